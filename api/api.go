@@ -2,16 +2,17 @@ package api
 
 import "time"
 
-//go:generate mockgen -package mock -destination ../mock/mock_api.go github.com/andig/evcc/api Charger,Meter,MeterEnergy,Vehicle,ChargeRater
+//go:generate mockgen -package mock -destination ../mock/mock_api.go github.com/andig/evcc/api BatteryController,Charger,ChargePhases,Identifier,Meter,MeterEnergy,Vehicle,ChargeRater,VehicleClimateController,VehicleStatus,VehicleStartCharge,VehicleStopCharge,VehicleChargeCurrent,VehicleChargeLimit
 
 // ChargeMode are charge modes modeled after OpenWB
 type ChargeMode string
 
 const (
-	ModeOff   ChargeMode = "off"
-	ModeNow   ChargeMode = "now"
-	ModeMinPV ChargeMode = "minpv"
-	ModePV    ChargeMode = "pv"
+	ModeOff      ChargeMode = "off"
+	ModeNow      ChargeMode = "now"
+	ModeMinPV    ChargeMode = "minpv"
+	ModePV       ChargeMode = "pv"
+	ModeCheapest ChargeMode = "cheapest"
 )
 
 // String implements Stringer
@@ -57,6 +58,11 @@ type Battery interface {
 	SoC() (float64, error)
 }
 
+// BatteryController is able to block or allow battery discharge
+type BatteryController interface {
+	LockDischarge(lock bool) error
+}
+
 // Charger is able to provide current charging status and to enable/disabler charging
 type Charger interface {
 	Status() (ChargeStatus, error)
@@ -70,11 +76,21 @@ type ChargerEx interface {
 	MaxCurrentMillis(current float64) error
 }
 
+// ChargePhases provides 1p/3p switching of the charger's phase contactors
+type ChargePhases interface {
+	Phases1p3p(phases int) error
+}
+
 // Diagnosis is a helper interface that allows to dump diagnostic data to console
 type Diagnosis interface {
 	Diagnose()
 }
 
+// Identifier provides the identification tag (e.g. RFID) used to authorize the current charging session
+type Identifier interface {
+	Identify() (string, error)
+}
+
 // ChargeTimer provides current charge cycle duration
 type ChargeTimer interface {
 	ChargingTime() (time.Duration, error)
@@ -89,9 +105,38 @@ type ChargeRater interface {
 type Vehicle interface {
 	Title() string
 	Capacity() int64
+	Identifiers() []string
+	Defaults() VehicleDefaults
 	SoC() (float64, error)
 }
 
+// ActionConfig contains charge mode and soc settings applied on an event. Zero values are not applied.
+type ActionConfig struct {
+	Mode      ChargeMode `mapstructure:"mode"`      // Charge mode
+	MinSoC    int        `mapstructure:"minSoC"`    // Minimum SoC
+	TargetSoC int        `mapstructure:"targetSoC"` // Target SoC
+}
+
+// VehicleDefaults are vehicle-specific settings taking precedence over the loadpoint configuration
+type VehicleDefaults struct {
+	ActionConfig `mapstructure:",squash"` // Settings applied when the vehicle becomes active
+	OnDisconnect ActionConfig             `mapstructure:"onDisconnect"` // Settings applied when the vehicle is disconnected
+}
+
+// ChargeCurvePoint is the maximum charge power the vehicle accepts at the given SoC
+type ChargeCurvePoint struct {
+	SoC   float64 `mapstructure:"soc" json:"soc"`     // SoC in %
+	Power float64 `mapstructure:"power" json:"power"` // Power in W
+}
+
+// ChargeCurve is the vehicle's charge power by SoC, interpolated linearly between points
+type ChargeCurve []ChargeCurvePoint
+
+// VehicleChargeCurve provides the vehicle's configured charge curve
+type VehicleChargeCurve interface {
+	ChargeCurve() ChargeCurve
+}
+
 // VehicleFinishTimer provides estimated charge cycle finish time
 type VehicleFinishTimer interface {
 	FinishTime() (time.Time, error)
@@ -112,7 +157,58 @@ type VehicleStartCharge interface {
 	StartCharge() error
 }
 
+// VehicleStopCharge stops the charging session on the vehicle side
+type VehicleStopCharge interface {
+	StopCharge() error
+}
+
+// VehicleChargeCurrent sets the charge current on the vehicle side
+type VehicleChargeCurrent interface {
+	SetChargeCurrent(current int64) error
+}
+
+// VehicleChargeLimit sets the SoC at which the vehicle stops charging
+type VehicleChargeLimit interface {
+	SetChargeLimit(soc int) error
+}
+
 // VehicleClimater provides climatisation data
 type VehicleClimater interface {
 	Climater() (active bool, outsideTemp float64, targetTemp float64, err error)
 }
+
+// VehicleClimateController starts and stops climatisation on the vehicle side
+type VehicleClimateController interface {
+	StartClimate(targetTemp float64) error // zero target temperature keeps the vehicle's setting
+	StopClimate() error
+}
+
+// Rate is a grid price valid within the given time slot
+type Rate struct {
+	Start time.Time `json:"start"`
+	End   time.Time `json:"end"`
+	Price float64   `json:"price"`
+}
+
+// Rates is a slice of tariff rates
+type Rates []Rate
+
+// Tariff provides the tariff's price slots
+type Tariff interface {
+	Rates() (Rates, error)
+}
+
+// ForecastSlot is the expected average pv power within the given time slot
+type ForecastSlot struct {
+	Start time.Time `json:"start"`
+	End   time.Time `json:"end"`
+	Power float64   `json:"power"` // W
+}
+
+// Forecast is a slice of forecast slots
+type Forecast []ForecastSlot
+
+// SolarForecast provides the expected pv production
+type SolarForecast interface {
+	Forecast() (Forecast, error)
+}
@@ -1,6 +1,10 @@
 package api
 
-import "strings"
+import (
+	"fmt"
+	"strings"
+	"time"
+)
 
 // ChargeModeString converts string to ChargeMode
 func ChargeModeString(mode string) ChargeMode {
@@ -11,9 +15,34 @@ func ChargeModeString(mode string) ChargeMode {
 		return ModeMinPV
 	case string(ModePV):
 		return ModePV
+	case string(ModeCheapest):
+		return ModeCheapest
 	case string(ModeOff):
 		return ModeOff
 	default:
 		return ""
 	}
 }
+
+// UnmarshalText implements the encoding.TextUnmarshaler interface.
+// The mode is normalized, empty text leaves the mode unset.
+func (c *ChargeMode) UnmarshalText(text []byte) error {
+	mode := ChargeModeString(string(text))
+	if mode == "" && len(text) > 0 {
+		return fmt.Errorf("invalid charge mode: %s", text)
+	}
+
+	*c = mode
+	return nil
+}
+
+// Current returns the rate active at the given time
+func (r Rates) Current(now time.Time) (Rate, error) {
+	for _, rate := range r {
+		if !rate.Start.After(now) && rate.End.After(now) {
+			return rate, nil
+		}
+	}
+
+	return Rate{}, ErrNotAvailable
+}
@@ -3,7 +3,8 @@ import Vue from "vue";
 function setProperty(obj, props, value) {
   const prop = props.shift();
   if (!obj[prop]) {
-    Vue.set(obj, prop, {});
+    // numeric keys like loadpoints.0 are array indexes
+    Vue.set(obj, prop, props.length && /^\d+$/.test(props[0]) ? [] : {});
   }
 
   if (!props.length) {
@@ -20,9 +20,14 @@ func init() {
 	registry.Add("default", NewConfigurableFromConfig)
 }
 
+//go:generate go run ../cmd/tools/decorate.go -p charger -f decorateCharger -o charger_decorators -b *Charger -r api.Charger -t "api.ChargePhases,Phases1p3p,func(phases int) error"
+
 // NewConfigurableFromConfig creates a new configurable charger
 func NewConfigurableFromConfig(other map[string]interface{}) (api.Charger, error) {
-	cc := struct{ Status, Enable, Enabled, MaxCurrent provider.Config }{}
+	cc := struct {
+		Status, Enable, Enabled, MaxCurrent provider.Config
+		Phases1p3p                          *provider.Config // optional
+	}{}
 	if err := util.DecodeOther(other, &cc); err != nil {
 		return nil, err
 	}
@@ -58,7 +63,25 @@ func NewConfigurableFromConfig(other map[string]interface{}) (api.Charger, error
 		return nil, fmt.Errorf("maxcurrent: %w", err)
 	}
 
-	return NewConfigurable(status, enabled, enable, maxcurrent)
+	c, err := NewConfigurable(status, enabled, enable, maxcurrent)
+	if err != nil {
+		return nil, err
+	}
+
+	// decorate Charger with ChargePhases
+	var phases1p3p func(int) error
+	if cc.Phases1p3p != nil {
+		phasesS, err := provider.NewIntSetterFromConfig("phases", *cc.Phases1p3p)
+		if err != nil {
+			return nil, fmt.Errorf("phases1p3p: %w", err)
+		}
+
+		phases1p3p = func(phases int) error {
+			return phasesS(int64(phases))
+		}
+	}
+
+	return decorateCharger(c, phases1p3p), nil
 }
 
 // NewConfigurable creates a new charger
@@ -67,7 +90,7 @@ func NewConfigurable(
 	enabledG func() (bool, error),
 	enableS func(bool) error,
 	maxCurrentS func(int64) error,
-) (api.Charger, error) {
+) (*Charger, error) {
 	c := &Charger{
 		statusG:     statusG,
 		enabledG:    enabledG,
@@ -5,6 +5,7 @@ import (
 	"errors"
 	"fmt"
 	"reflect"
+	"strings"
 	"time"
 
 	"github.com/andig/evcc/api"
@@ -283,6 +284,19 @@ func (c *Keba) Currents() (float64, float64, float64, error) {
 	return float64(kr.I1) / 1e3, float64(kr.I2) / 1e3, float64(kr.I3) / 1e3, err
 }
 
+// Identify implements the Identifier interface
+func (c *Keba) Identify() (string, error) {
+	var kr keba.Report100
+	err := c.roundtrip("report", 100, &kr)
+
+	// session ended or not authorized by rfid card
+	if kr.EndedS > 0 || strings.Trim(kr.RFIDTag, "0") == "" {
+		return "", err
+	}
+
+	return kr.RFIDTag, err
+}
+
 // Diagnose implements the Diagnosis interface
 func (c *Keba) Diagnose() {
 	var kr keba.Report100
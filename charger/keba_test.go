@@ -24,4 +24,8 @@ func TestKeba(t *testing.T) {
 	if _, ok := wb.(api.ChargeRater); !ok {
 		t.Error("missing ChargeRater interface")
 	}
+
+	if _, ok := wb.(api.Identifier); !ok {
+		t.Error("missing Identifier interface")
+	}
 }
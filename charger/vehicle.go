@@ -0,0 +1,109 @@
+package charger
+
+import (
+	"errors"
+	"sync"
+
+	"github.com/andig/evcc/api"
+)
+
+// Vehicle is an api.Charger implementation commanding the vehicle instead of the charger.
+// It allows controlling charging with mobile charging cables or chargers without remote control.
+// The vehicle must provide its charge status and start/stop charging, setting the charge current is optional.
+type Vehicle struct {
+	sync.Mutex
+	vehicle api.Vehicle
+	enabled bool
+	current int64
+	status  api.ChargeStatus
+}
+
+// NewVehicle creates a charger controlling the given vehicle
+func NewVehicle(vehicle api.Vehicle) (*Vehicle, error) {
+	if _, ok := vehicle.(api.VehicleStatus); !ok {
+		return nil, errors.New("vehicle does not provide charge status")
+	}
+
+	_, start := vehicle.(api.VehicleStartCharge)
+	_, stop := vehicle.(api.VehicleStopCharge)
+	if !start || !stop {
+		return nil, errors.New("vehicle does not support starting and stopping charging")
+	}
+
+	c := &Vehicle{
+		vehicle: vehicle,
+	}
+
+	// assume vehicle charging is enabled if currently charging
+	if status, err := c.Status(); err == nil {
+		c.enabled = status == api.StatusC
+	}
+
+	return c, nil
+}
+
+// Status implements the api.Charger interface. Vehicle apis report the status with a delay
+// of up to their cache duration, so connecting or disconnecting is detected late.
+// If the vehicle api fails, the last known status is assumed unchanged.
+func (c *Vehicle) Status() (api.ChargeStatus, error) {
+	c.Lock()
+	defer c.Unlock()
+
+	status, err := c.vehicle.(api.VehicleStatus).Status()
+	if err != nil {
+		if c.status != api.StatusNone {
+			return c.status, nil
+		}
+		return api.StatusNone, err
+	}
+
+	c.status = status
+
+	return status, nil
+}
+
+// Enabled implements the api.Charger interface. The vehicle's state may be outdated due to
+// caching by vehicle apis, therefore the last commanded state is returned.
+func (c *Vehicle) Enabled() (bool, error) {
+	c.Lock()
+	defer c.Unlock()
+	return c.enabled, nil
+}
+
+// Enable implements the api.Charger interface
+func (c *Vehicle) Enable(enable bool) error {
+	c.Lock()
+	defer c.Unlock()
+
+	var err error
+	if enable {
+		err = c.vehicle.(api.VehicleStartCharge).StartCharge()
+	} else {
+		err = c.vehicle.(api.VehicleStopCharge).StopCharge()
+	}
+
+	if err == nil {
+		c.enabled = enable
+	}
+
+	return err
+}
+
+// MaxCurrent implements the api.Charger interface. Vehicles without charge current control
+// charge at the current provided by the charging cable.
+func (c *Vehicle) MaxCurrent(current int64) error {
+	c.Lock()
+	defer c.Unlock()
+
+	vc, ok := c.vehicle.(api.VehicleChargeCurrent)
+	if !ok || current == c.current {
+		return nil
+	}
+
+	err := vc.SetChargeCurrent(current)
+	if err == nil {
+		c.current = current
+	}
+
+	return err
+}
@@ -0,0 +1,94 @@
+package charger
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/andig/evcc/api"
+	"github.com/andig/evcc/mock"
+	"github.com/golang/mock/gomock"
+)
+
+func TestVehicleMissingApi(t *testing.T) {
+	ctrl := gomock.NewController(t)
+
+	if _, err := NewVehicle(mock.NewMockVehicle(ctrl)); err == nil {
+		t.Error("expected error for vehicle without charge control")
+	}
+
+	v := &struct {
+		*mock.MockVehicle
+		*mock.MockVehicleStatus
+		*mock.MockVehicleStartCharge
+	}{
+		mock.NewMockVehicle(ctrl),
+		mock.NewMockVehicleStatus(ctrl),
+		mock.NewMockVehicleStartCharge(ctrl),
+	}
+
+	if _, err := NewVehicle(v); err == nil {
+		t.Error("expected error for vehicle without stop charge")
+	}
+}
+
+func TestVehicle(t *testing.T) {
+	ctrl := gomock.NewController(t)
+
+	v := &struct {
+		*mock.MockVehicle
+		*mock.MockVehicleStatus
+		*mock.MockVehicleStartCharge
+		*mock.MockVehicleStopCharge
+		*mock.MockVehicleChargeCurrent
+	}{
+		mock.NewMockVehicle(ctrl),
+		mock.NewMockVehicleStatus(ctrl),
+		mock.NewMockVehicleStartCharge(ctrl),
+		mock.NewMockVehicleStopCharge(ctrl),
+		mock.NewMockVehicleChargeCurrent(ctrl),
+	}
+
+	v.MockVehicleStatus.EXPECT().Status().Return(api.StatusC, nil)
+
+	c, err := NewVehicle(v)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if enabled, _ := c.Enabled(); !enabled {
+		t.Error("expected enabled while charging")
+	}
+
+	// vehicle api error keeps status
+	v.MockVehicleStatus.EXPECT().Status().Return(api.StatusNone, errors.New("foo"))
+	if status, err := c.Status(); err != nil || status != api.StatusC {
+		t.Errorf("expected unchanged status, got %s: %v", status, err)
+	}
+
+	// failed command keeps state
+	v.MockVehicleStopCharge.EXPECT().StopCharge().Return(errors.New("foo"))
+	if err := c.Enable(false); err == nil {
+		t.Error("expected error")
+	}
+
+	if enabled, _ := c.Enabled(); !enabled {
+		t.Error("expected enabled after failed stop")
+	}
+
+	v.MockVehicleStopCharge.EXPECT().StopCharge().Return(nil)
+	if err := c.Enable(false); err != nil {
+		t.Error(err)
+	}
+
+	if enabled, _ := c.Enabled(); enabled {
+		t.Error("expected disabled")
+	}
+
+	// current is only sent on change
+	v.MockVehicleChargeCurrent.EXPECT().SetChargeCurrent(int64(16)).Return(nil)
+	for i := 0; i < 2; i++ {
+		if err := c.MaxCurrent(16); err != nil {
+			t.Error(err)
+		}
+	}
+}
@@ -34,6 +34,16 @@ func runCharger(cmd *cobra.Command, args []string) {
 		configureMQTT(conf.Mqtt)
 	}
 
+	// vehicle chargers require vehicles
+	for _, cc := range conf.Chargers {
+		if _, isVehicle := vehicleChargerRef(cc); isVehicle {
+			if err := cp.configureVehicles(conf); err != nil {
+				log.FATAL.Fatal(err)
+			}
+			break
+		}
+	}
+
 	if err := cp.configureChargers(conf); err != nil {
 		log.FATAL.Fatal(err)
 	}
@@ -2,6 +2,8 @@ package cmd
 
 import (
 	"fmt"
+	"reflect"
+	"strings"
 	"time"
 
 	"github.com/andig/evcc/api"
@@ -20,15 +22,19 @@ type config struct {
 	Profile    bool
 	Levels     map[string]string
 	Interval   time.Duration
+	Database   string
 	Mqtt       mqttConfig
 	Javascript map[string]interface{}
 	Influx     server.InfluxConfig
 	HEMS       typedConfig
 	Messaging  messagingConfig
+	Tariffs    tariffConfig
+	Forecast   typedConfig
 	Meters     []qualifiedConfig
 	Chargers   []qualifiedConfig
 	Vehicles   []qualifiedConfig
 	Site       map[string]interface{}
+	Sites      []map[string]interface{}
 	LoadPoints []map[string]interface{}
 }
 
@@ -47,9 +53,16 @@ type typedConfig struct {
 	Other map[string]interface{} `mapstructure:",remain"`
 }
 
+type tariffConfig struct {
+	Currency string
+	Grid     typedConfig
+	FeedIn   typedConfig
+}
+
 type messagingConfig struct {
 	Events   map[string]push.EventTemplate
 	Services []typedConfig
+	Webhooks []map[string]interface{}
 }
 
 // ConfigProvider provides configuration items
@@ -57,6 +70,8 @@ type ConfigProvider struct {
 	meters   map[string]api.Meter
 	chargers map[string]api.Charger
 	vehicles map[string]api.Vehicle
+	configs  map[string]qualifiedConfig // device configurations by kind and name
+	prev     *ConfigProvider            // previous configuration for reusing unchanged devices
 }
 
 // Meter provides meters by name
@@ -86,13 +101,48 @@ func (cp *ConfigProvider) Vehicle(name string) api.Vehicle {
 	return nil
 }
 
+// deviceKey identifies a device by kind and name
+func deviceKey(kind, name string) string {
+	return kind + "." + name
+}
+
+// reusable returns true if the previous configuration contains an identical device
+func (cp *ConfigProvider) reusable(kind string, cc qualifiedConfig) bool {
+	if cp.prev == nil {
+		return false
+	}
+
+	prev, ok := cp.prev.configs[deviceKey(kind, cc.Name)]
+	return ok && reflect.DeepEqual(prev, cc)
+}
+
+// changed returns true if the device has been created by the last configuration
+func (cp *ConfigProvider) changed(kind, name string) bool {
+	cc, ok := cp.configs[deviceKey(kind, name)]
+	return !ok || !cp.reusable(kind, cc)
+}
+
+// exists returns true if the device is configured
+func (cp *ConfigProvider) exists(kind, name string) bool {
+	_, ok := cp.configs[deviceKey(kind, name)]
+	return ok
+}
+
+// track records the device configuration
+func (cp *ConfigProvider) track(kind string, cc qualifiedConfig) {
+	if cp.configs == nil {
+		cp.configs = make(map[string]qualifiedConfig)
+	}
+	cp.configs[deviceKey(kind, cc.Name)] = cc
+}
+
 func (cp *ConfigProvider) configure(conf config) error {
 	err := cp.configureMeters(conf)
 	if err == nil {
-		err = cp.configureChargers(conf)
+		err = cp.configureVehicles(conf)
 	}
 	if err == nil {
-		err = cp.configureVehicles(conf)
+		err = cp.configureChargers(conf)
 	}
 	return err
 }
@@ -100,17 +150,25 @@ func (cp *ConfigProvider) configure(conf config) error {
 func (cp *ConfigProvider) configureMeters(conf config) error {
 	cp.meters = make(map[string]api.Meter)
 	for _, cc := range conf.Meters {
+		if _, exists := cp.meters[cc.Name]; exists {
+			return fmt.Errorf("duplicate meter name: %s already defined and must be unique", cc.Name)
+		}
+
+		// reuse unchanged meter on reload
+		if cp.reusable("meter", cc) {
+			cp.meters[cc.Name] = cp.prev.meters[cc.Name]
+			cp.track("meter", cc)
+			continue
+		}
+
 		m, err := meter.NewFromConfig(cc.Type, cc.Other)
 		if err != nil {
 			err = fmt.Errorf("cannot create meter '%s': %w", cc.Name, err)
 			return err
 		}
 
-		if _, exists := cp.meters[cc.Name]; exists {
-			return fmt.Errorf("duplicate meter name: %s already defined and must be unique", cc.Name)
-		}
-
 		cp.meters[cc.Name] = m
+		cp.track("meter", cc)
 	}
 
 	return nil
@@ -119,36 +177,80 @@ func (cp *ConfigProvider) configureMeters(conf config) error {
 func (cp *ConfigProvider) configureChargers(conf config) error {
 	cp.chargers = make(map[string]api.Charger)
 	for _, cc := range conf.Chargers {
-		c, err := charger.NewFromConfig(cc.Type, cc.Other)
+		if _, exists := cp.chargers[cc.Name]; exists {
+			return fmt.Errorf("duplicate charger name: %s already defined and must be unique", cc.Name)
+		}
+
+		ref, isVehicle := vehicleChargerRef(cc)
+
+		// reuse unchanged charger on reload, vehicle chargers also require an unchanged vehicle
+		if cp.reusable("charger", cc) && (!isVehicle || !cp.changed("vehicle", ref)) {
+			cp.chargers[cc.Name] = cp.prev.chargers[cc.Name]
+			cp.track("charger", cc)
+			continue
+		}
+
+		var c api.Charger
+		var err error
+		if isVehicle {
+			c, err = cp.vehicleCharger(ref)
+		} else {
+			c, err = charger.NewFromConfig(cc.Type, cc.Other)
+		}
 		if err != nil {
 			err = fmt.Errorf("cannot create charger '%s': %w", cc.Name, err)
 			return err
 		}
 
-		if _, exists := cp.chargers[cc.Name]; exists {
-			return fmt.Errorf("duplicate charger name: %s already defined and must be unique", cc.Name)
-		}
-
 		cp.chargers[cc.Name] = c
+		cp.track("charger", cc)
 	}
 
 	return nil
 }
 
+// vehicleChargerRef returns the vehicle reference if the charger is controlled by the vehicle
+func vehicleChargerRef(cc qualifiedConfig) (string, bool) {
+	if !strings.EqualFold(cc.Type, "vehicle") {
+		return "", false
+	}
+
+	ref, _ := cc.Other["vehicle"].(string)
+	return ref, true
+}
+
+// vehicleCharger creates a charger controlling the referenced vehicle
+func (cp *ConfigProvider) vehicleCharger(ref string) (api.Charger, error) {
+	v, ok := cp.vehicles[ref]
+	if !ok {
+		return nil, fmt.Errorf("invalid vehicle: %s", ref)
+	}
+
+	return charger.NewVehicle(v)
+}
+
 func (cp *ConfigProvider) configureVehicles(conf config) error {
 	cp.vehicles = make(map[string]api.Vehicle)
 	for _, cc := range conf.Vehicles {
+		if _, exists := cp.vehicles[cc.Name]; exists {
+			return fmt.Errorf("duplicate vehicle name: %s already defined and must be unique", cc.Name)
+		}
+
+		// reuse unchanged vehicle on reload
+		if cp.reusable("vehicle", cc) {
+			cp.vehicles[cc.Name] = cp.prev.vehicles[cc.Name]
+			cp.track("vehicle", cc)
+			continue
+		}
+
 		v, err := vehicle.NewFromConfig(cc.Type, cc.Other)
 		if err != nil {
 			err = fmt.Errorf("cannot create vehicle '%s': %w", cc.Name, err)
 			return err
 		}
 
-		if _, exists := cp.vehicles[cc.Name]; exists {
-			return fmt.Errorf("duplicate vehicle name: %s already defined and must be unique", cc.Name)
-		}
-
 		cp.vehicles[cc.Name] = v
+		cp.track("vehicle", cc)
 	}
 
 	return nil
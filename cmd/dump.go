@@ -36,42 +36,49 @@ func runDump(cmd *cobra.Command, args []string) {
 		configureMQTT(conf.Mqtt)
 	}
 
-	site, err := loadConfig(conf)
+	sites, err := loadConfig(conf)
 	if err != nil {
 		log.FATAL.Fatal(err)
 	}
 
 	d := dumper{len: 2}
 
-	d.Header("config", "=")
-	fmt.Println("")
-
-	if name := site.Meters.GridMeterRef; name != "" {
-		d.DumpWithHeader(fmt.Sprintf("grid: %s", name), cp.Meter(name))
-	}
-	if name := site.Meters.PVMeterRef; name != "" {
-		d.DumpWithHeader(fmt.Sprintf("pv: %s", name), cp.Meter(name))
-	}
-	if name := site.Meters.BatteryMeterRef; name != "" {
-		d.DumpWithHeader(fmt.Sprintf("battery: %s", name), cp.Meter(name))
-	}
-
-	for id, lpI := range site.LoadPoints() {
-		lp := lpI.(*core.LoadPoint)
+	for _, site := range sites {
+		title := "config"
+		if site.ID != "" {
+			title = fmt.Sprintf("site %s", site.ID)
+		}
 
-		d.Header(fmt.Sprintf("loadpoint %d", id+1), "=")
+		d.Header(title, "=")
 		fmt.Println("")
 
-		if name := lp.Meters.ChargeMeterRef; name != "" {
-			d.DumpWithHeader(fmt.Sprintf("charge: %s", name), cp.Meter(name))
+		if name := site.Meters.GridMeterRef; name != "" {
+			d.DumpWithHeader(fmt.Sprintf("grid: %s", name), cp.Meter(name))
 		}
-
-		if name := lp.ChargerRef; name != "" {
-			d.DumpWithHeader(fmt.Sprintf("charger: %s", name), cp.Charger(name))
+		if name := site.Meters.PVMeterRef; name != "" {
+			d.DumpWithHeader(fmt.Sprintf("pv: %s", name), cp.Meter(name))
+		}
+		if name := site.Meters.BatteryMeterRef; name != "" {
+			d.DumpWithHeader(fmt.Sprintf("battery: %s", name), cp.Meter(name))
 		}
 
-		for id, v := range lp.VehiclesRef {
-			d.DumpWithHeader(fmt.Sprintf("vehicle %d", id), cp.Vehicle(v))
+		for id, lpI := range site.LoadPoints() {
+			lp := lpI.(*core.LoadPoint)
+
+			d.Header(fmt.Sprintf("loadpoint %d", id+1), "=")
+			fmt.Println("")
+
+			if name := lp.Meters.ChargeMeterRef; name != "" {
+				d.DumpWithHeader(fmt.Sprintf("charge: %s", name), cp.Meter(name))
+			}
+
+			if name := lp.ChargerRef; name != "" {
+				d.DumpWithHeader(fmt.Sprintf("charger: %s", name), cp.Charger(name))
+			}
+
+			for id, v := range lp.VehiclesRef {
+				d.DumpWithHeader(fmt.Sprintf("vehicle %d", id), cp.Vehicle(v))
+			}
 		}
 	}
 }
@@ -0,0 +1,227 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"sync"
+	"time"
+
+	"github.com/andig/evcc/core"
+	"github.com/mitchellh/mapstructure"
+	"github.com/spf13/viper"
+)
+
+// replaceTimeout is the maximum time for all sites' control loops accepting loadpoint replacements
+const replaceTimeout = time.Minute
+
+// reloader re-reads the config file and replaces changed loadpoints at runtime
+type reloader struct {
+	mu        sync.Mutex
+	sites     []*core.Site
+	conf      config
+	siteConfs []siteConfig
+	stale     []bool // site's loadpoints were not replaced by the last reload
+}
+
+func newReloader(sites []*core.Site, conf config) (*reloader, error) {
+	siteConfs, err := siteConfigs(conf)
+	if err != nil {
+		return nil, err
+	}
+
+	r := &reloader{
+		sites:     sites,
+		conf:      conf,
+		siteConfs: siteConfs,
+		stale:     make([]bool, len(sites)),
+	}
+
+	return r, nil
+}
+
+// loadpointDevices contains a loadpoint's device references
+type loadpointDevices struct {
+	Charger  string
+	Vehicle  string
+	Vehicles []string
+	Meters   struct {
+		Charge string
+	}
+}
+
+func decodeLoadpointDevices(lpc map[string]interface{}) (loadpointDevices, error) {
+	var res loadpointDevices
+	err := mapstructure.Decode(lpc, &res)
+	return res, err
+}
+
+// check verifies that all referenced devices exist and returns true if any of them has changed
+func (d loadpointDevices) check(cp *ConfigProvider) (bool, error) {
+	refs := map[string][]string{
+		"charger": {d.Charger},
+		"vehicle": append([]string{d.Vehicle}, d.Vehicles...),
+		"meter":   {d.Meters.Charge},
+	}
+
+	var changed bool
+	for kind, names := range refs {
+		for _, name := range names {
+			if name == "" {
+				continue
+			}
+
+			if !cp.exists(kind, name) {
+				return false, fmt.Errorf("invalid %s: %s", kind, name)
+			}
+
+			changed = changed || cp.changed(kind, name)
+		}
+	}
+
+	return changed, nil
+}
+
+// checkSite verifies that the site and its meters are unchanged
+func checkSite(prev, curr siteConfig, cp *ConfigProvider) error {
+	if curr.ID != prev.ID || !reflect.DeepEqual(curr.Other, prev.Other) {
+		return errors.New("changing the site requires a restart")
+	}
+
+	if len(curr.LoadPoints) != len(prev.LoadPoints) {
+		return errors.New("changing the number of loadpoints requires a restart")
+	}
+
+	var siteMeters struct {
+		Meters struct {
+			Grid, PV, Battery string
+		}
+	}
+	if err := mapstructure.Decode(curr.Other, &siteMeters); err != nil {
+		return err
+	}
+
+	for _, name := range []string{siteMeters.Meters.Grid, siteMeters.Meters.PV, siteMeters.Meters.Battery} {
+		if name != "" && (!cp.exists("meter", name) || cp.changed("meter", name)) {
+			return fmt.Errorf("changing site meter %s requires a restart", name)
+		}
+	}
+
+	return nil
+}
+
+// changedLoadPoints creates the site's loadpoints whose configuration or devices have changed,
+// or all loadpoints if all is set. Unchanged loadpoints are returned as nil.
+func changedLoadPoints(prev, curr siteConfig, cp *ConfigProvider, all bool) ([]*core.LoadPoint, error) {
+	loadPoints := make([]*core.LoadPoint, len(curr.LoadPoints))
+
+	for id, lpc := range curr.LoadPoints {
+		devices, err := decodeLoadpointDevices(lpc)
+		if err != nil {
+			return nil, fmt.Errorf("failed decoding loadpoint configuration: %w", err)
+		}
+
+		changed, err := devices.check(cp)
+		if err != nil {
+			return nil, fmt.Errorf("failed configuring loadpoint: %w", err)
+		}
+
+		if !all && !changed && reflect.DeepEqual(lpc, prev.LoadPoints[id]) {
+			continue
+		}
+
+		if loadPoints[id], err = configureLoadPoint(curr.ID, id, lpc, cp); err != nil {
+			return nil, err
+		}
+	}
+
+	return loadPoints, nil
+}
+
+// Reload re-reads the config file and replaces loadpoints whose configuration or
+// devices have changed. Unchanged devices are reused. Changes to the sites, their meters or
+// the number of loadpoints require a restart.
+func (r *reloader) Reload() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if cfgFile == "" {
+		return errors.New("missing evcc config")
+	}
+
+	log.INFO.Println("reloading config file", cfgFile)
+
+	if err := viper.ReadInConfig(); err != nil {
+		return fmt.Errorf("failed reading config file %s: %w", cfgFile, err)
+	}
+
+	var conf config
+	if err := viper.UnmarshalExact(&conf); err != nil {
+		return fmt.Errorf("failed parsing config file %s: %w", cfgFile, err)
+	}
+
+	siteConfs, err := siteConfigs(conf)
+	if err != nil {
+		return err
+	}
+
+	if len(siteConfs) != len(r.siteConfs) {
+		return errors.New("changing the number of sites requires a restart")
+	}
+
+	next := &ConfigProvider{prev: cp}
+	if err := next.configure(conf); err != nil {
+		return err
+	}
+
+	for i, sc := range siteConfs {
+		if err := checkSite(r.siteConfs[i], sc, next); err != nil {
+			return err
+		}
+	}
+
+	// sections other than devices and loadpoints are only applied on startup
+	prev, curr := r.conf, conf
+	prev.Meters, prev.Chargers, prev.Vehicles, prev.LoadPoints, prev.Sites = nil, nil, nil, nil, nil
+	curr.Meters, curr.Chargers, curr.Vehicles, curr.LoadPoints, curr.Sites = nil, nil, nil, nil, nil
+	if !reflect.DeepEqual(prev, curr) {
+		log.WARN.Println("reload: only devices and loadpoints are updated, other changes require a restart")
+	}
+
+	// create all loadpoints before replacing any
+	loadPoints := make([][]*core.LoadPoint, len(siteConfs))
+	for i, sc := range siteConfs {
+		if loadPoints[i], err = changedLoadPoints(r.siteConfs[i], sc, next, r.stale[i]); err != nil {
+			return err
+		}
+	}
+
+	for i, site := range r.sites {
+		if len(loadPoints[i]) != len(site.LoadPoints()) {
+			return fmt.Errorf("site %d: number of loadpoints must not change", i+1)
+		}
+	}
+
+	// sites failing to accept their loadpoints keep the previous devices and
+	// have all their loadpoints replaced by the next reload
+	var failed error
+	deadline := time.Now().Add(replaceTimeout)
+	for i, site := range r.sites {
+		if err := site.ReplaceLoadPoints(loadPoints[i], deadline); err != nil {
+			r.stale[i] = true
+			failed = fmt.Errorf("site %d: %w", i+1, err)
+			continue
+		}
+
+		r.stale[i] = false
+	}
+
+	// replaced loadpoints use the new devices, release previous devices
+	next.prev = nil
+	cp = next
+
+	r.conf = conf
+	r.siteConfs = siteConfs
+
+	return failed
+}
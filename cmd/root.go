@@ -6,10 +6,13 @@ import (
 	_ "net/http/pprof" // pprof handler
 	"os"
 	"os/signal"
+	"sync"
 	"syscall"
 	"time"
 
+	"github.com/andig/evcc/core"
 	"github.com/andig/evcc/server"
+	"github.com/andig/evcc/server/db"
 	"github.com/andig/evcc/server/updater"
 	"github.com/andig/evcc/util"
 	"github.com/andig/evcc/util/pipe"
@@ -152,6 +155,9 @@ func run(cmd *cobra.Command, args []string) {
 	uri := viper.GetString("uri")
 	log.INFO.Println("listening at", uri)
 
+	// setup session and settings storage
+	configureStorage(conf.Database)
+
 	// setup mqtt client listener
 	if conf.Mqtt.Broker != "" {
 		configureMQTT(conf.Mqtt)
@@ -167,26 +173,31 @@ func run(cmd *cobra.Command, args []string) {
 	cache := util.NewCache()
 	go cache.Run(pipe.NewDropper(ignoreErrors...).Pipe(tee.Attach()))
 
-	// setup loadpoints
-	site, err := loadConfig(conf)
+	// setup sites and loadpoints
+	sites, err := loadConfig(conf)
 	if err != nil {
 		log.FATAL.Fatal(err)
 	}
 
+	siteAPIs := make([]core.SiteAPI, 0, len(sites))
+	for _, site := range sites {
+		siteAPIs = append(siteAPIs, site)
+	}
+
 	// setup database
 	if conf.Influx.URL != "" {
-		configureDatabase(conf.Influx, site.LoadPoints(), tee.Attach())
+		configureDatabase(conf.Influx, siteAPIs, tee.Attach())
 	}
 
 	// setup mqtt publisher
 	if conf.Mqtt.Broker != "" {
 		publisher := server.NewMQTT(conf.Mqtt.Topic)
-		go publisher.Run(site, pipe.NewDropper(ignoreMqtt...).Pipe(tee.Attach()))
+		go publisher.Run(siteAPIs, pipe.NewDropper(ignoreMqtt...).Pipe(tee.Attach()))
 	}
 
 	// create webserver
 	socketHub := server.NewSocketHub()
-	httpd := server.NewHTTPd(uri, site, socketHub, cache)
+	httpd := server.NewHTTPd(uri, siteAPIs, socketHub, cache)
 
 	// metrics
 	if viper.GetBool("metrics") {
@@ -198,9 +209,21 @@ func run(cmd *cobra.Command, args []string) {
 		httpd.Router().PathPrefix("/debug/").Handler(http.DefaultServeMux)
 	}
 
+	// config reload
+	reloader, err := newReloader(sites, conf)
+	if err != nil {
+		log.FATAL.Fatal(err)
+	}
+
+	httpd.Router().Methods("POST", "OPTIONS").Path("/api/config/reload").Handler(server.ReloadHandler(reloader.Reload))
+
 	// start HEMS server
 	if conf.HEMS.Type != "" {
-		hems := configureHEMS(conf.HEMS, site, cache, httpd)
+		if len(sites) > 1 || sites[0].ID != "" {
+			log.FATAL.Fatal("hems requires a single site without id")
+		}
+
+		hems := configureHEMS(conf.HEMS, sites[0], cache, httpd)
 		go hems.Run()
 	}
 
@@ -221,19 +244,43 @@ func run(cmd *cobra.Command, args []string) {
 	pushChan := configureMessengers(conf.Messaging, cache)
 
 	// set channels
-	site.Prepare(valueChan, pushChan)
-	site.DumpConfig()
+	for _, site := range sites {
+		site.Prepare(valueChan, pushChan)
+		site.DumpConfig()
+	}
 
 	stopC := make(chan struct{})
 	exitC := make(chan struct{})
 
+	// run each site's control loop
+	var wg sync.WaitGroup
+	for _, site := range sites {
+		wg.Add(1)
+		go func(site *core.Site) {
+			site.Run(stopC, conf.Interval)
+			wg.Done()
+		}(site)
+	}
+
 	go func() {
-		site.Run(stopC, conf.Interval)
+		wg.Wait()
 		close(exitC)
 	}()
 
 	// uds health check listener
-	go server.HealthListener(site)
+	go server.HealthListener(siteAPIs)
+
+	// reload config on SIGHUP
+	go func() {
+		hupC := make(chan os.Signal, 1)
+		signal.Notify(hupC, syscall.SIGHUP)
+
+		for range hupC {
+			if err := reloader.Reload(); err != nil {
+				log.ERROR.Printf("failed reloading config: %v", err)
+			}
+		}
+	}()
 
 	// catch signals
 	go func() {
@@ -244,6 +291,11 @@ func run(cmd *cobra.Command, args []string) {
 		close(stopC) // signal loop to end
 		<-exitC      // wait for loop to end
 
+		// flush database
+		if db.Instance != nil {
+			_ = db.Instance.Close()
+		}
+
 		os.Exit(1)
 	}()
 
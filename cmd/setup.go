@@ -4,15 +4,22 @@ import (
 	"errors"
 	"fmt"
 	"math/rand"
+	"os"
+	"path/filepath"
+	"regexp"
 	"strconv"
 	"time"
 
+	"github.com/andig/evcc/api"
 	"github.com/andig/evcc/core"
+	"github.com/andig/evcc/forecast"
 	"github.com/andig/evcc/hems"
 	"github.com/andig/evcc/provider/javascript"
 	"github.com/andig/evcc/provider/mqtt"
 	"github.com/andig/evcc/push"
 	"github.com/andig/evcc/server"
+	"github.com/andig/evcc/server/db"
+	"github.com/andig/evcc/tariff"
 	"github.com/andig/evcc/util"
 	"github.com/andig/evcc/util/pipe"
 	"github.com/spf13/viper"
@@ -25,7 +32,7 @@ func init() {
 var cp = &ConfigProvider{}
 
 // setup influx databases
-func configureDatabase(conf server.InfluxConfig, loadPoints []core.LoadPointAPI, in <-chan util.Param) {
+func configureDatabase(conf server.InfluxConfig, sites []core.SiteAPI, in <-chan util.Param) {
 	influx := server.NewInfluxClient(
 		conf.URL,
 		conf.Token,
@@ -43,7 +50,36 @@ func configureDatabase(conf server.InfluxConfig, loadPoints []core.LoadPointAPI,
 	limiter := pipe.NewLimiter(5 * time.Second)
 	in = limiter.Pipe(in)
 
-	go influx.Run(loadPoints, in)
+	go influx.Run(sites, in)
+}
+
+// setup embedded database for sessions and settings. Without configured database the
+// default location is used if accessible, otherwise sessions and settings are not persisted.
+func configureStorage(file string) {
+	if file != "" {
+		log.INFO.Println("using database", file)
+
+		var err error
+		if db.Instance, err = db.New(file); err != nil {
+			log.FATAL.Fatalf("failed configuring database: %v", err)
+		}
+
+		return
+	}
+
+	home, err := os.UserHomeDir()
+	if err == nil {
+		file = filepath.Join(home, ".evcc", "evcc.db")
+
+		var store *db.DB
+		if store, err = db.New(file); err == nil {
+			log.INFO.Println("using database", file)
+			db.Instance = store
+			return
+		}
+	}
+
+	log.ERROR.Printf("database not available, sessions and settings are not persisted: %v", err)
 }
 
 // setup mqtt
@@ -88,49 +124,213 @@ func configureMessengers(conf messagingConfig, cache *util.Cache) chan push.Even
 		notificationHub.Add(impl)
 	}
 
+	// persist undelivered webhook events if database is available
+	var store push.Store
+	if db.Instance != nil {
+		store = db.Instance
+	}
+
+	for _, other := range conf.Webhooks {
+		webhook, err := push.NewWebhookFromConfig(other, store)
+		if err != nil {
+			log.FATAL.Fatalf("failed configuring webhook: %v", err)
+		}
+		notificationHub.AddWebhook(webhook)
+	}
+
 	go notificationHub.Run(notificationChan)
 
 	return notificationChan
 }
 
-func loadConfig(conf config) (site *core.Site, err error) {
-	if err = cp.configure(conf); err == nil {
-		var loadPoints []*core.LoadPoint
-		loadPoints, err = configureLoadPoints(conf, cp)
+func loadConfig(conf config) ([]*core.Site, error) {
+	if err := cp.configure(conf); err != nil {
+		return nil, err
+	}
+
+	siteConfs, err := siteConfigs(conf)
+	if err != nil {
+		return nil, err
+	}
+
+	tariffs, err := configureTariffs(conf.Tariffs)
+	if err != nil {
+		return nil, err
+	}
+
+	solarForecast, err := configureForecast(conf.Forecast)
+	if err != nil {
+		return nil, err
+	}
+
+	var sites []*core.Site
+	for _, sc := range siteConfs {
+		loadPoints, err := configureLoadPoints(sc, cp)
+		if err != nil {
+			return nil, err
+		}
+
+		site, err := configureSite(sc, cp, loadPoints, tariffs, solarForecast)
+		if err != nil {
+			return nil, err
+		}
+
+		sites = append(sites, site)
+	}
+
+	return sites, nil
+}
+
+func configureTariffs(conf tariffConfig) (tariff.Tariffs, error) {
+	tariffs := tariff.Tariffs{Currency: conf.Currency}
 
-		if err == nil {
-			site, err = configureSite(conf.Site, cp, loadPoints)
+	if conf.Grid.Type != "" {
+		t, err := tariff.NewFromConfig(conf.Grid.Type, conf.Grid.Other)
+		if err != nil {
+			return tariffs, fmt.Errorf("failed configuring grid tariff: %w", err)
 		}
+		tariffs.Grid = t
 	}
 
-	return site, err
+	if conf.FeedIn.Type != "" {
+		t, err := tariff.NewFromConfig(conf.FeedIn.Type, conf.FeedIn.Other)
+		if err != nil {
+			return tariffs, fmt.Errorf("failed configuring feed-in tariff: %w", err)
+		}
+		tariffs.FeedIn = t
+	}
+
+	return tariffs, nil
 }
 
-func configureSite(conf map[string]interface{}, cp *ConfigProvider, loadPoints []*core.LoadPoint) (*core.Site, error) {
-	site, err := core.NewSiteFromConfig(log, cp, conf, loadPoints)
+func configureForecast(conf typedConfig) (api.SolarForecast, error) {
+	if conf.Type == "" {
+		return nil, nil
+	}
+
+	f, err := forecast.NewFromConfig(conf.Type, conf.Other)
+	if err != nil {
+		return nil, fmt.Errorf("failed configuring forecast: %w", err)
+	}
+
+	return f, nil
+}
+
+func configureSite(sc siteConfig, cp *ConfigProvider, loadPoints []*core.LoadPoint, tariffs tariff.Tariffs, solarForecast api.SolarForecast) (*core.Site, error) {
+	site, err := core.NewSiteFromConfig(log, cp, sc.Other, loadPoints, tariffs, solarForecast)
 	if err != nil {
+		if sc.ID != "" {
+			return nil, fmt.Errorf("failed configuring site %s: %w", sc.ID, err)
+		}
 		return nil, fmt.Errorf("failed configuring site: %w", err)
 	}
 
 	return site, nil
 }
 
-func configureLoadPoints(conf config, cp *ConfigProvider) (loadPoints []*core.LoadPoint, err error) {
+// loadpointConfigs returns the raw loadpoint configurations. Loadpoints are read from viper
+// since they are not part of the config struct.
+func loadpointConfigs() ([]map[string]interface{}, error) {
 	lpInterfaces, ok := viper.AllSettings()["loadpoints"].([]interface{})
 	if !ok || len(lpInterfaces) == 0 {
 		return nil, errors.New("missing loadpoints")
 	}
 
-	for id, lpcI := range lpInterfaces {
+	var res []map[string]interface{}
+	for _, lpcI := range lpInterfaces {
 		var lpc map[string]interface{}
 		if err := util.DecodeOther(lpcI, &lpc); err != nil {
 			return nil, fmt.Errorf("failed decoding loadpoint configuration: %w", err)
 		}
 
-		log := util.NewLogger("lp-" + strconv.Itoa(id+1))
-		lp, err := core.NewLoadPointFromConfig(log, cp, lpc)
+		res = append(res, lpc)
+	}
+
+	return res, nil
+}
+
+// siteConfig is a site's raw configuration including its loadpoints
+type siteConfig struct {
+	ID         string
+	Other      map[string]interface{}   // site configuration
+	LoadPoints []map[string]interface{} // loadpoint configurations
+}
+
+var siteIDRegex = regexp.MustCompile(`^[a-z0-9_-]+$`)
+
+// siteConfigs returns the raw site configurations. A single site is configured using the
+// site and loadpoints sections, multiple sites using the sites section. Sites are read from
+// viper like loadpoints.
+func siteConfigs(conf config) ([]siteConfig, error) {
+	sitesI, ok := viper.AllSettings()["sites"].([]interface{})
+	if !ok || len(sitesI) == 0 {
+		lpConfigs, err := loadpointConfigs()
+		if err != nil {
+			return nil, err
+		}
+
+		return []siteConfig{{Other: conf.Site, LoadPoints: lpConfigs}}, nil
+	}
+
+	if len(conf.Site) > 0 || len(conf.LoadPoints) > 0 {
+		return nil, errors.New("sites cannot be combined with site and loadpoints")
+	}
+
+	var res []siteConfig
+	ids := make(map[string]bool)
+
+	for _, scI := range sitesI {
+		var other map[string]interface{}
+		if err := util.DecodeOther(scI, &other); err != nil {
+			return nil, fmt.Errorf("failed decoding site configuration: %w", err)
+		}
+
+		sc := siteConfig{Other: other}
+		sc.ID, _ = other["id"].(string)
+
+		if !siteIDRegex.MatchString(sc.ID) {
+			return nil, fmt.Errorf("invalid site id: '%s' (lower case letters, digits, - and _)", sc.ID)
+		}
+		if ids[sc.ID] {
+			return nil, fmt.Errorf("duplicate site id: %s already defined and must be unique", sc.ID)
+		}
+		ids[sc.ID] = true
+
+		if err := util.DecodeOther(other["loadpoints"], &sc.LoadPoints); err != nil {
+			return nil, fmt.Errorf("failed decoding site %s loadpoint configuration: %w", sc.ID, err)
+		}
+		delete(other, "loadpoints")
+
+		if len(sc.LoadPoints) == 0 {
+			return nil, fmt.Errorf("site %s: missing loadpoints", sc.ID)
+		}
+
+		res = append(res, sc)
+	}
+
+	return res, nil
+}
+
+func configureLoadPoint(site string, id int, lpc map[string]interface{}, cp *ConfigProvider) (*core.LoadPoint, error) {
+	name := "lp-" + strconv.Itoa(id+1)
+	if site != "" {
+		name = site + "-" + name
+	}
+
+	log := util.NewLogger(name)
+	lp, err := core.NewLoadPointFromConfig(log, cp, lpc)
+	if err != nil {
+		return nil, fmt.Errorf("failed configuring loadpoint: %w", err)
+	}
+
+	return lp, nil
+}
+
+func configureLoadPoints(sc siteConfig, cp *ConfigProvider) (loadPoints []*core.LoadPoint, err error) {
+	for id, lpc := range sc.LoadPoints {
+		lp, err := configureLoadPoint(sc.ID, id, lpc, cp)
 		if err != nil {
-			return nil, fmt.Errorf("failed configuring loadpoint: %w", err)
+			return nil, err
 		}
 
 		loadPoints = append(loadPoints, lp)
@@ -0,0 +1,151 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/andig/evcc/api"
+	"github.com/andig/evcc/server"
+	"github.com/andig/evcc/simulator"
+	"github.com/andig/evcc/util"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+// simulateCmd represents the simulate command
+var simulateCmd = &cobra.Command{
+	Use:   "simulate [csv file]",
+	Short: "Replay recorded site data against simulated charger and vehicle",
+	Args:  cobra.ExactArgs(1),
+	Run:   runSimulate,
+}
+
+func init() {
+	rootCmd.AddCommand(simulateCmd)
+
+	simulateCmd.Flags().Float64("capacity", 50, "Vehicle capacity in kWh")
+	simulateCmd.Flags().Float64("soc", 20, "Initial vehicle SoC in %")
+	simulateCmd.Flags().Int64("phases", 0, "Charger phases (default loadpoint configuration)")
+	simulateCmd.Flags().StringSlice("mode", nil, "Charge modes to compare (default loadpoint configuration)")
+	simulateCmd.Flags().Float64Slice("enable", nil, "PV mode enable thresholds in W to compare")
+	simulateCmd.Flags().Float64Slice("disable", nil, "PV mode disable thresholds in W to compare")
+	simulateCmd.Flags().DurationSlice("guard", nil, "Guard durations to compare")
+	simulateCmd.Flags().Float64Slice("residual", nil, "Residual powers in W to compare")
+}
+
+// simulationConfig returns the first site's first loadpoint and control settings from the config file
+func simulationConfig() (simulator.Config, error) {
+	var conf simulator.Config
+
+	if cfgFile == "" {
+		return conf, nil
+	}
+
+	fileConf, err := loadConfigFile(cfgFile)
+	if err != nil {
+		return conf, err
+	}
+
+	siteConfs, err := siteConfigs(fileConf)
+	if err != nil {
+		return conf, err
+	}
+	conf.LoadPoint = siteConfs[0].LoadPoints[0]
+
+	// site meters and load management are provided by the simulation
+	conf.Site = make(map[string]interface{})
+	for k, v := range siteConfs[0].Other {
+		switch strings.ToLower(k) {
+		case "voltage", "residualpower", "prioritysoc", "filter":
+			conf.Site[k] = v
+		}
+	}
+
+	return conf, nil
+}
+
+// simulationSettings returns all combinations of the compared settings
+func simulationSettings(cmd *cobra.Command, base simulator.Setting) []simulator.Setting {
+	modes, _ := cmd.Flags().GetStringSlice("mode")
+	enables, _ := cmd.Flags().GetFloat64Slice("enable")
+	disables, _ := cmd.Flags().GetFloat64Slice("disable")
+	guards, _ := cmd.Flags().GetDurationSlice("guard")
+	residuals, _ := cmd.Flags().GetFloat64Slice("residual")
+
+	res := []simulator.Setting{base}
+
+	vary := func(n int, set func(*simulator.Setting, int)) {
+		if n == 0 {
+			return
+		}
+
+		var next []simulator.Setting
+		for _, s := range res {
+			for i := 0; i < n; i++ {
+				set(&s, i)
+				next = append(next, s)
+			}
+		}
+		res = next
+	}
+
+	vary(len(modes), func(s *simulator.Setting, i int) { s.Mode = api.ChargeModeString(modes[i]) })
+	vary(len(enables), func(s *simulator.Setting, i int) { s.Enable = enables[i] })
+	vary(len(disables), func(s *simulator.Setting, i int) { s.Disable = disables[i] })
+	vary(len(guards), func(s *simulator.Setting, i int) { s.GuardDuration = guards[i] })
+	vary(len(residuals), func(s *simulator.Setting, i int) { s.ResidualPower = residuals[i] })
+
+	return res
+}
+
+func runSimulate(cmd *cobra.Command, args []string) {
+	util.LogLevel(viper.GetString("log"), viper.GetStringMapString("levels"))
+	log.INFO.Printf("evcc %s (%s)", server.Version, server.Commit)
+
+	f, err := os.Open(args[0])
+	if err != nil {
+		log.FATAL.Fatal(err)
+	}
+
+	samples, err := simulator.ReadCSV(f)
+	f.Close()
+	if err != nil {
+		log.FATAL.Fatalf("failed reading %s: %v", args[0], err)
+	}
+
+	conf, err := simulationConfig()
+	if err != nil {
+		log.FATAL.Fatal(err)
+	}
+
+	conf.Interval = viper.GetDuration("interval")
+	conf.Capacity, _ = cmd.Flags().GetFloat64("capacity")
+	conf.SoC, _ = cmd.Flags().GetFloat64("soc")
+	conf.Phases, _ = cmd.Flags().GetInt64("phases")
+
+	base, err := simulator.DefaultSetting(conf)
+	if err != nil {
+		log.FATAL.Fatal(err)
+	}
+
+	fmt.Printf("simulating %d samples from %s to %s at %v interval\n\n", len(samples),
+		samples[0].Time.Format("2006-01-02 15:04"), samples[len(samples)-1].Time.Format("2006-01-02 15:04"), conf.Interval)
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', tabwriter.AlignRight)
+	fmt.Fprintln(w, "Mode\tEnable (W)\tDisable (W)\tGuard\tResidual (W)\tCharged (kWh)\tGrid import (kWh)\tSwitches\tSoC (%)\t")
+
+	for _, setting := range simulationSettings(cmd, base) {
+		res, err := simulator.Run(samples, conf, setting)
+		if err != nil {
+			log.FATAL.Fatal(err)
+		}
+
+		fmt.Fprintf(w, "%s\t%.0f\t%.0f\t%v\t%.0f\t%.2f\t%.2f\t%d\t%.0f\t\n",
+			res.Mode, res.Enable, res.Disable, res.GuardDuration, res.ResidualPower,
+			res.ChargedEnergy, res.GridImport, res.Switches, res.SoC)
+	}
+
+	w.Flush()
+}
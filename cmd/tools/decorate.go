@@ -50,7 +50,7 @@ import (
 		}
 {{- end -}}
 
-func {{.Function}}(base {{.BaseType}}{{range ordered}}, {{.VarName}} func() {{slice .Signature 7}}{{end}}) {{.ReturnType}} {
+func {{.Function}}(base {{.BaseType}}{{range ordered}}, {{.VarName}} {{.Signature}}{{end}}) {{.ReturnType}} {
 {{- $basetype := .BaseType}}
 {{- $shortbase := .ShortBase}}
 {{- $prefix := .Function}}
@@ -73,8 +73,8 @@ type {{$prefix}}{{.ShortType}}Impl struct {
 	{{.VarName}} {{.Signature}}
 }
 
-func (impl *{{$prefix}}{{.ShortType}}Impl) {{.Function}}{{slice .Signature 4}} {
-	return impl.{{.VarName}}()
+func (impl *{{$prefix}}{{.ShortType}}Impl) {{.Function}}({{.Params}}) {{.Returns}} {
+	return impl.{{.VarName}}({{.Args}})
 }
 
 {{end}}
@@ -86,6 +86,36 @@ type dynamicType struct {
 
 type typeStruct struct {
 	Type, ShortType, Signature, Function, VarName string
+	Params, Args, Returns                         string
+}
+
+// parseSignature splits a function signature like `func(phases int) error` into
+// parameters, argument names and return types. Parameters must be named.
+func parseSignature(signature string) (params, args, returns string, err error) {
+	signature = strings.TrimPrefix(signature, "func")
+	end := strings.Index(signature, ")")
+	if !strings.HasPrefix(signature, "(") || end < 0 {
+		return "", "", "", fmt.Errorf("invalid signature: %s", signature)
+	}
+
+	params = signature[1:end]
+	returns = strings.TrimSpace(signature[end+1:])
+
+	var names []string
+	for _, param := range strings.Split(params, ",") {
+		if param = strings.TrimSpace(param); param == "" {
+			continue
+		}
+
+		fields := strings.Fields(param)
+		if len(fields) < 2 {
+			return "", "", "", fmt.Errorf("unnamed parameter: %s", param)
+		}
+
+		names = append(names, fields[0])
+	}
+
+	return params, strings.Join(names, ", "), returns, nil
 }
 
 func generate(out io.Writer, packageName, functionName, baseType string, dynamicTypes ...dynamicType) error {
@@ -135,12 +165,20 @@ func generate(out io.Writer, packageName, functionName, baseType string, dynamic
 	for _, dt := range dynamicTypes {
 		parts := strings.SplitN(dt.typ, ".", 2)
 
+		params, args, returns, err := parseSignature(dt.signature)
+		if err != nil {
+			return err
+		}
+
 		types[dt.typ] = typeStruct{
 			Type:      dt.typ,
 			ShortType: parts[1],
 			VarName:   strings.ToLower(parts[1][:1]) + parts[1][1:],
 			Signature: dt.signature,
 			Function:  dt.function,
+			Params:    params,
+			Args:      args,
+			Returns:   returns,
 		}
 
 		combos = append(combos, dt.typ)
@@ -0,0 +1,316 @@
+package core
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/andig/evcc/api"
+	"github.com/andig/evcc/provider"
+	"github.com/andig/evcc/util"
+	"github.com/benbjohnson/clock"
+)
+
+// ConsumerAPI is the external consumer API
+type ConsumerAPI interface {
+	Name() string
+}
+
+// ConsumerConfig configures a flexible consumer like a heat pump, heating rod or pool pump
+type ConsumerConfig struct {
+	Title      string           `mapstructure:"title"`      // UI title
+	Priority   int              `mapstructure:"priority"`   // Priority for pv allocation, shared with loadpoints
+	Power      []float64        `mapstructure:"power"`      // Nominal power per level in W, single level for on/off
+	Enable     *provider.Config `mapstructure:"enable"`     // Bool setter switching an on/off consumer
+	Level      *provider.Config `mapstructure:"level"`      // Int setter selecting the level, 0 is off
+	Meter      string           `mapstructure:"meter"`      // Optional meter reference
+	MinRuntime time.Duration    `mapstructure:"minRuntime"` // Minimum time before reducing the level
+}
+
+// Consumer is a flexible consumer switched by available pv power
+type Consumer struct {
+	ConsumerConfig
+
+	log   *util.Logger
+	clock clock.Clock // mockable time
+
+	uiChan chan<- util.Param // client push messages
+	site   string            // site id
+	id     int               // consumer id
+
+	meter    api.Meter       // optional consumption meter
+	setLevel func(int) error // level setter
+
+	level   int       // current level, 0 is off
+	updated time.Time // level changed timestamp
+	power   float64   // current power
+
+	failures   int       // consecutive failed control cycles
+	faultSince time.Time // first failed control cycle
+}
+
+// NewConsumerFromConfig creates a consumer from configuration
+func NewConsumerFromConfig(log *util.Logger, cp configProvider, cc ConsumerConfig) (*Consumer, error) {
+	c := &Consumer{
+		ConsumerConfig: cc,
+		log:            log,
+		clock:          clock.New(),
+	}
+
+	if len(cc.Power) == 0 {
+		return nil, errors.New("missing power")
+	}
+
+	for i, p := range cc.Power {
+		if p <= 0 || i > 0 && p <= cc.Power[i-1] {
+			return nil, errors.New("power must be positive and increasing")
+		}
+	}
+
+	switch {
+	case cc.Enable != nil && cc.Level != nil:
+		return nil, errors.New("either enable or level required")
+
+	case cc.Enable != nil:
+		if len(cc.Power) > 1 {
+			return nil, errors.New("enable requires single power level")
+		}
+
+		enableS, err := provider.NewBoolSetterFromConfig("enable", *cc.Enable)
+		if err != nil {
+			return nil, fmt.Errorf("enable: %w", err)
+		}
+
+		c.setLevel = func(level int) error {
+			return enableS(level > 0)
+		}
+
+	case cc.Level != nil:
+		levelS, err := provider.NewIntSetterFromConfig("level", *cc.Level)
+		if err != nil {
+			return nil, fmt.Errorf("level: %w", err)
+		}
+
+		c.setLevel = func(level int) error {
+			return levelS(int64(level))
+		}
+
+	default:
+		return nil, errors.New("missing enable or level")
+	}
+
+	if cc.Meter != "" {
+		c.meter = cp.Meter(cc.Meter)
+	}
+
+	return c, nil
+}
+
+// Name returns the consumer's title
+func (c *Consumer) Name() string {
+	return c.Title
+}
+
+// publish sends values to UI and databases
+func (c *Consumer) publish(key string, val interface{}) {
+	if c.uiChan != nil {
+		c.uiChan <- util.Param{
+			Site:     c.site,
+			Consumer: &c.id,
+			Key:      key,
+			Val:      val,
+		}
+	}
+}
+
+// Prepare attaches the ui channel and switches the consumer off to start from a defined state.
+// The level before startup is unknown, hence minimum runtime does not apply.
+func (c *Consumer) Prepare(uiChan chan<- util.Param, site string, id int) {
+	c.uiChan = uiChan
+	c.site = site
+	c.id = id
+
+	c.publish("title", c.Title)
+	c.publish("level", c.level)
+
+	if err := c.setLevel(0); err != nil {
+		c.log.ERROR.Printf("consumer level 0: %v", err)
+	}
+}
+
+// levelPower returns the nominal power of the level
+func (c *Consumer) levelPower(level int) float64 {
+	if level == 0 {
+		return 0
+	}
+	return c.Power[level-1]
+}
+
+// levelFor returns the highest level not exceeding the power budget
+func (c *Consumer) levelFor(budget float64) int {
+	var level int
+	for i, p := range c.Power {
+		if p <= budget {
+			level = i + 1
+		}
+	}
+	return level
+}
+
+// held returns true if the level must not be reduced due to minimum runtime
+func (c *Consumer) held() bool {
+	return c.level > 0 && c.clock.Since(c.updated) < c.MinRuntime
+}
+
+// powerDemand returns the consumer's power requirements for pv allocation
+func (c *Consumer) powerDemand() powerDemand {
+	d := powerDemand{
+		priority: c.Priority,
+		min:      c.Power[0],
+		max:      c.Power[len(c.Power)-1],
+	}
+
+	if c.held() {
+		d.min = c.levelPower(c.level)
+		d.mandatory = true
+	}
+
+	return d
+}
+
+// updatePower updates the consumer's current power from its meter or nominal level power
+func (c *Consumer) updatePower() {
+	c.power = c.levelPower(c.level)
+
+	if c.meter == nil {
+		return
+	}
+
+	power, err := c.meter.CurrentPower()
+	if err != nil {
+		c.log.ERROR.Printf("consumer meter: %v", err)
+		return
+	}
+
+	c.power = power
+	c.log.DEBUG.Printf("consumer power: %.0fW", c.power)
+	c.publish("power", c.power)
+
+	if m, ok := c.meter.(api.MeterEnergy); ok {
+		if energy, err := m.TotalEnergy(); err == nil {
+			c.publish("energy", energy)
+		} else {
+			c.log.ERROR.Printf("consumer meter energy: %v", err)
+		}
+	}
+}
+
+// Update selects the consumer's level from its pv budgets. The level is increased if the
+// enable budget allows and reduced if the disable budget requires after minimum runtime.
+func (c *Consumer) Update(enableBudget, disableBudget float64) {
+	level := c.level
+
+	if up := c.levelFor(enableBudget); up > c.level {
+		level = up
+	} else if down := c.levelFor(disableBudget); down < c.level {
+		if c.held() {
+			c.log.DEBUG.Printf("consumer level %d - runtime remaining %v", c.level, (c.MinRuntime - c.clock.Since(c.updated)).Truncate(time.Second))
+			return
+		}
+		level = down
+	}
+
+	if level == c.level {
+		return
+	}
+
+	if err := c.setLevel(level); err != nil {
+		c.log.ERROR.Printf("consumer level %d: %v", level, err)
+		return
+	}
+
+	c.log.INFO.Printf("consumer level: %d (%.0fW)", level, c.levelPower(level))
+
+	c.level = level
+	c.updated = c.clock.Now()
+
+	c.publish("level", c.level)
+	if c.meter == nil {
+		c.publish("power", c.levelPower(c.level))
+	}
+}
+
+// fault records a failed control cycle and switches the consumer off once the site's fail-safe is triggered.
+// Consumers have no minimum level, so both min and disable actions switch off regardless of minimum runtime.
+func (c *Consumer) fault(failSafe FailSafeConfig) {
+	now := c.clock.Now()
+	if c.failures == 0 {
+		c.faultSince = now
+	}
+	c.failures++
+
+	if failSafe.Action == failSafeKeep || !failSafe.triggered(c.failures, now.Sub(c.faultSince)) {
+		return
+	}
+
+	if c.level == 0 {
+		return
+	}
+
+	if err := c.setLevel(0); err != nil {
+		c.log.ERROR.Printf("fail-safe: %v", err)
+		return
+	}
+
+	c.log.WARN.Printf("fail-safe: level 0 after %d failed cycles", c.failures)
+
+	c.level = 0
+	c.updated = now
+
+	c.publish("level", c.level)
+	if c.meter == nil {
+		c.publish("power", c.levelPower(c.level))
+	}
+}
+
+// clearFault resets the failure count
+func (c *Consumer) clearFault() {
+	c.failures = 0
+}
+
+// configureConsumers creates the site's consumers
+func (site *Site) configureConsumers(cp configProvider) error {
+	for i, cc := range site.ConsumerConfigs {
+		name := "consumer-" + strconv.Itoa(i+1)
+		if site.ID != "" {
+			name = site.ID + "-" + name
+		}
+
+		c, err := NewConsumerFromConfig(util.NewLogger(name), cp, cc)
+		if err != nil {
+			return fmt.Errorf("consumer %d: %w", i+1, err)
+		}
+
+		c.clock = site.clock
+		site.consumers = append(site.consumers, c)
+	}
+
+	return nil
+}
+
+// Consumers returns the array of associated consumers
+func (site *Site) Consumers() []ConsumerAPI {
+	res := make([]ConsumerAPI, len(site.consumers))
+	for id, c := range site.consumers {
+		res[id] = c
+	}
+	return res
+}
+
+// updateConsumerPower updates the consumers' current power
+func (site *Site) updateConsumerPower() {
+	for _, c := range site.consumers {
+		c.updatePower()
+	}
+}
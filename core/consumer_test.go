@@ -0,0 +1,136 @@
+package core
+
+import (
+	"testing"
+	"time"
+
+	"github.com/andig/evcc/util"
+	"github.com/benbjohnson/clock"
+)
+
+func newTestConsumer(clck clock.Clock, priority int, power ...float64) (*Consumer, *int) {
+	set := -1
+
+	c := &Consumer{
+		ConsumerConfig: ConsumerConfig{
+			Priority:   priority,
+			Power:      power,
+			MinRuntime: 10 * time.Minute,
+		},
+		log:   util.NewLogger("foo"),
+		clock: clck,
+		setLevel: func(level int) error {
+			set = level
+			return nil
+		},
+	}
+
+	return c, &set
+}
+
+func TestConsumerUpdate(t *testing.T) {
+	tc := []struct {
+		level                       int
+		elapsed                     time.Duration
+		enableBudget, disableBudget float64
+		expect                      int // expected level, -1 if not set
+	}{
+		{0, 0, 500, 500, -1},                 // insufficient power
+		{0, 0, 2500, 2500, 2},                // switch on
+		{0, 0, 5000, 5000, 3},                // max level
+		{2, 0, 2500, 1500, -1},               // reduction within runtime
+		{2, 10 * time.Minute, 2500, 1500, 1}, // reduction after runtime
+		{2, 10 * time.Minute, 2500, 0, 0},    // switch off after runtime
+		{2, 0, 3000, 0, 3},                   // increase within runtime
+	}
+
+	for _, tc := range tc {
+		t.Logf("%+v", tc)
+
+		clck := clock.NewMock()
+		c, set := newTestConsumer(clck, 0, 1000, 2000, 3000)
+		c.level = tc.level
+		c.updated = clck.Now()
+		clck.Add(tc.elapsed)
+
+		c.Update(tc.enableBudget, tc.disableBudget)
+
+		if *set != tc.expect {
+			t.Errorf("expected level %d, got %d", tc.expect, *set)
+		}
+	}
+}
+
+func TestAllocateConsumers(t *testing.T) {
+	clck := clock.NewMock()
+
+	heatpump, _ := newTestConsumer(clck, 1, 2000)
+	heater, _ := newTestConsumer(clck, 0, 1000, 2000, 3000)
+
+	site := &Site{
+		log:       util.NewLogger("foo"),
+		consumers: []*Consumer{heatpump, heater},
+	}
+
+	tc := []struct {
+		sitePower        float64
+		heaterLevel      int
+		heaterHeld       bool
+		heatpump, heater float64
+	}{
+		{-1000, 0, false, 0, 1000},    // insufficient power for heat pump
+		{-4000, 0, false, 2000, 2000}, // remaining power for heater
+		{-6000, 0, false, 3000, 3000}, // excess power to highest priority
+		{0, 2, false, 2000, 0},        // heater power is available
+		{1500, 2, true, -1500, 2000},  // heater held at current level
+	}
+
+	for _, tc := range tc {
+		t.Logf("%+v", tc)
+
+		heater.level = tc.heaterLevel
+		heater.power = heater.levelPower(heater.level)
+		heater.updated = clck.Now()
+		if !tc.heaterHeld {
+			heater.updated = clck.Now().Add(-heater.MinRuntime)
+		}
+
+		_, budgets := site.allocate(tc.sitePower)
+
+		if budgets[0] != tc.heatpump || budgets[1] != tc.heater {
+			t.Errorf("expected budgets %.0fW/%.0fW, got %.0fW/%.0fW", tc.heatpump, tc.heater, budgets[0], budgets[1])
+		}
+	}
+}
+
+func TestConsumerFailSafe(t *testing.T) {
+	tc := []struct {
+		action   string
+		failures int
+		expect   int // expected level, -1 if not set
+	}{
+		{"", 3, -1},
+		{failSafeKeep, 3, -1},
+		{failSafeDisable, 1, -1},
+		{failSafeDisable, 2, 0},
+		{failSafeMin, 2, 0},
+	}
+
+	for _, tc := range tc {
+		t.Logf("%+v", tc)
+
+		clck := clock.NewMock()
+		c, set := newTestConsumer(clck, 0, 1000)
+		c.level = 1
+		c.updated = clck.Now()
+
+		failSafe := FailSafeConfig{Action: tc.action, Cycles: 2}
+		for i := 0; i < tc.failures; i++ {
+			c.fault(failSafe)
+		}
+
+		if *set != tc.expect {
+			t.Errorf("expected level %d, got %d", tc.expect, *set)
+		}
+	}
+}
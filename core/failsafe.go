@@ -0,0 +1,96 @@
+package core
+
+import (
+	"fmt"
+	"time"
+)
+
+// Fail-safe actions
+const (
+	failSafeKeep    = "keep"    // keep current charger state
+	failSafeMin     = "min"     // reduce to min current if charging
+	failSafeDisable = "disable" // disable charger
+)
+
+// FailSafeConfig defines the action applied when meters or chargers stop responding.
+// The action applies after the given number of failed cycles or failure duration, immediately if neither is set.
+type FailSafeConfig struct {
+	Action  string        `mapstructure:"action"`  // keep, min or disable
+	Cycles  int           `mapstructure:"cycles"`  // failed control cycles
+	Timeout time.Duration `mapstructure:"timeout"` // duration of failures
+}
+
+// validate checks the fail-safe action
+func (c FailSafeConfig) validate() error {
+	switch c.Action {
+	case "", failSafeKeep, failSafeMin, failSafeDisable:
+		return nil
+	default:
+		return fmt.Errorf("invalid fail-safe action: %s", c.Action)
+	}
+}
+
+// triggered returns true if the fail-safe action applies after failures lasting for duration
+func (c FailSafeConfig) triggered(failures int, duration time.Duration) bool {
+	if c.Action == "" {
+		return false
+	}
+
+	if c.Cycles == 0 && c.Timeout == 0 {
+		return true
+	}
+
+	return c.Cycles > 0 && failures >= c.Cycles || c.Timeout > 0 && duration >= c.Timeout
+}
+
+// fault records a failed control cycle and applies the fail-safe action once triggered
+func (lp *LoadPoint) fault(err error) {
+	now := lp.clock.Now()
+	if lp.failures == 0 {
+		lp.faultSince = now
+	}
+	lp.failures++
+
+	lp.publish("fault", err.Error())
+
+	if !lp.FailSafe.triggered(lp.failures, now.Sub(lp.faultSince)) {
+		return
+	}
+
+	if !lp.failSafe {
+		lp.log.WARN.Printf("fail-safe: %s after %d failed cycles: %v", lp.FailSafe.Action, lp.failures, err)
+		lp.failSafe = true
+		lp.publish("failSafe", lp.failSafe)
+		lp.triggerEvent(evFault)
+	}
+
+	var limitErr error
+	switch lp.FailSafe.Action {
+	case failSafeMin:
+		if lp.enabled {
+			limitErr = lp.setLimit(float64(lp.MinCurrent), true)
+		}
+	case failSafeDisable:
+		limitErr = lp.setLimit(0, true)
+	}
+
+	if limitErr != nil {
+		lp.log.ERROR.Printf("fail-safe: %v", limitErr)
+	}
+}
+
+// clearFault resets the failure count and releases the fail-safe action
+func (lp *LoadPoint) clearFault() {
+	if lp.failures == 0 {
+		return
+	}
+
+	lp.failures = 0
+	lp.publish("fault", "")
+
+	if lp.failSafe {
+		lp.log.INFO.Println("fail-safe: released")
+		lp.failSafe = false
+		lp.publish("failSafe", lp.failSafe)
+	}
+}
@@ -1,6 +1,8 @@
 package core
 
 import (
+	"math"
+
 	"github.com/avast/retry-go"
 )
 
@@ -11,13 +13,16 @@ var (
 	// retryOptions ist the default options set for retryable operations
 	retryOptions = []retry.Option{retry.Attempts(3), retry.LastErrorOnly(true)}
 
-	// Voltage global value
-	Voltage float64
+	// Voltage is the default operating voltage of loadpoints not attached to a site
+	Voltage = 230.0 // V
+
+	// noCurrentLimit is used if the site does not restrict loadpoint currents
+	noCurrentLimit = math.Inf(1)
 )
 
 // powerToCurrent is a helper function to convert power to per-phase current
-func powerToCurrent(power float64, phases int64) float64 {
-	return power / (float64(phases) * Voltage)
+func powerToCurrent(power, voltage float64, phases int64) float64 {
+	return power / (float64(phases) * voltage)
 }
 
 // consumedPower estimates how much power the charger might have consumed given it was the only load
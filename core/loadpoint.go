@@ -13,6 +13,7 @@ import (
 	"github.com/andig/evcc/core/soc"
 	"github.com/andig/evcc/core/wrapper"
 	"github.com/andig/evcc/push"
+	"github.com/andig/evcc/server/db"
 	"github.com/andig/evcc/util"
 
 	evbus "github.com/asaskevich/EventBus"
@@ -27,6 +28,7 @@ const (
 	evChargePower       = "power"      // update chargeRater
 	evVehicleConnect    = "connect"    // vehicle connected
 	evVehicleDisconnect = "disconnect" // vehicle disconnected
+	evFault             = "fault"      // fail-safe action applied
 
 	minActiveCurrent = 1.0 // minimum current at which a phase is treated as active
 )
@@ -67,6 +69,7 @@ type ThresholdConfig struct {
 type LoadPoint struct {
 	clock    clock.Clock       // mockable time
 	bus      evbus.Bus         // event bus
+	handlers []subscription    // event bus handlers
 	pushChan chan<- push.Event // notifications
 	uiChan   chan<- util.Param // client push messages
 	lpChan   chan<- *LoadPoint // update requests
@@ -77,6 +80,7 @@ type LoadPoint struct {
 	Mode       api.ChargeMode `mapstructure:"mode"` // Charge mode, guarded by mutex
 
 	Title       string   `mapstructure:"title"`    // UI title
+	ID          string   `mapstructure:"id"`       // Unique identifier of persisted runtime settings, defaults to loadpoint number
 	Phases      int64    `mapstructure:"phases"`   // Phases- required for converting power and current
 	ChargerRef  string   `mapstructure:"charger"`  // Charger reference
 	VehicleRef  string   `mapstructure:"vehicle"`  // Vehicle reference
@@ -84,42 +88,82 @@ type LoadPoint struct {
 	Meters      struct {
 		ChargeMeterRef string `mapstructure:"charge"` // Charge meter reference
 	}
-	SoC          SoCConfig
-	OnDisconnect struct {
-		Mode      api.ChargeMode `mapstructure:"mode"`      // Charge mode to apply when car disconnected
-		TargetSoC int            `mapstructure:"targetSoC"` // Target SoC to apply when car disconnected
-	}
+	SoC             SoCConfig
+	OnDisconnect    api.ActionConfig // Charge mode and soc to apply when car disconnected
 	Enable, Disable ThresholdConfig
+	Priority        int                `mapstructure:"priority"`     // Priority for sharing pv power between loadpoints, higher first
+	FailSafe        FailSafeConfig     `mapstructure:"failSafe"`     // Action when meters or charger stop responding, defaults to site
+	Precondition    PreconditionConfig `mapstructure:"precondition"` // Vehicle climatisation before departure
+	Switch          SwitchConfig       `mapstructure:"switch"`       // Charger switch cycle limits
 
 	MinCurrent    int64         // PV mode: start current	Min+PV mode: min current
 	MaxCurrent    int64         // Max allowed current. Physically ensured by the charger
 	GuardDuration time.Duration // charger enable/disable minimum holding time
 
-	enabled       bool      // Charger enabled state
-	chargeCurrent float64   // Charger current limit
-	guardUpdated  time.Time // Charger enabled/disabled timestamp
-	socUpdated    time.Time // SoC updated timestamp (poll: connected)
+	enabled           bool        // Charger enabled state
+	chargeCurrent     float64     // Charger current limit
+	currentLimit      float64     // Site load management current limit
+	sitePowerLimit    float64     // Share of the site's remote power limit, zero if not limited
+	siteID            string      // Id of the loadpoint's site, namespaces runtime settings and sessions
+	siteVoltage       float64     // Operating voltage of the site, zero if not attached to a site
+	enablePowerOffset float64     // Difference of site power filtered for enable decisions to site power
+	currentOffset     float64     // Learned difference between commanded and measured charge current
+	vehicleMaxCurrent float64     // Learned maximum current drawn by the vehicle, zero if unknown
+	chargerPhases     int64       // Charger phases if switchable
+	maxPhases         int64       // Configured phases, restored when not charging in pv modes
+	guardUpdated      time.Time   // Charger enabled/disabled timestamp
+	switchStarts      []time.Time // Charger enabled timestamps within the last day
+	currentUpdated    time.Time   // Charge current or enabled state changed timestamp
+	socUpdated        time.Time   // SoC updated timestamp (poll: connected)
+
+	chargeCurveChanged bool      // Learned charge curve changed since last persisted
+	chargeLimit        int       // Target soc applied as the vehicle's charge limit, zero if not applied
+	chargeLimitFailed  time.Time // Failed charge limit request timestamp, retried after soc poll interval
 
 	charger     api.Charger
 	chargeTimer api.ChargeTimer
 	chargeRater api.ChargeRater
 
-	chargeMeter  api.Meter     // Charger usage meter
-	vehicle      api.Vehicle   // Currently active vehicle
-	vehicles     []api.Vehicle // Assigned vehicles
+	chargeMeter  api.Meter        // Charger usage meter
+	vehicle      api.Vehicle      // Currently active vehicle
+	vehicles     []api.Vehicle    // Assigned vehicles
+	vehicleRefs  []string         // Configuration names of assigned vehicles
+	vehicleID    string           // Charger-reported vehicle identification, e.g. RFID tag
+	defaults     api.ActionConfig // Configured mode and soc, applied to vehicles without own defaults
 	socEstimator *soc.Estimator
 	socTimer     *soc.Timer
+	planner      *soc.Planner
+	settings     *db.Settings
+	restored     map[string]bool // Runtime settings restored on startup
 
 	// cached state
-	status        api.ChargeStatus // Charger status
-	remoteDemand  RemoteDemand     // External status demand
-	chargePower   float64          // Charging power
-	connectedTime time.Time        // Time when vehicle was connected
-	pvTimer       time.Time        // PV enabled/disable timer
+	status           api.ChargeStatus // Charger status
+	remoteDemand     RemoteDemand     // External status demand
+	remotePowerLimit RemotePowerLimit // External power limit
+	chargePower      float64          // Charging power
+	connectedTime    time.Time        // Time when vehicle was connected
+	pvTimer          time.Time        // PV enabled/disable timer
+	phaseTimer       time.Time        // 1p/3p switch timer
+	targetCharging   bool             // Charging for target time
+	departureTime    time.Time        // Planned departure for preconditioning
+	preconditioning  bool             // Vehicle climatisation for departure active
+	failures         int              // Consecutive failed control cycles
+	faultSince       time.Time        // Time of first failed control cycle
+	failSafe         bool             // Fail-safe action applied
 
 	socCharge      float64       // Vehicle SoC
 	chargedEnergy  float64       // Charged energy while connected in Wh
 	chargeDuration time.Duration // Charge duration
+
+	chargedSolarEnergy float64 // Charged energy covered by pv surplus while connected in Wh
+	sessionEnergy      float64 // Charged energy attributed to pv surplus or grid while connected in Wh
+	accountedEnergy    float64 // Charged energy at last attribution in Wh
+	sessionCost        float64 // Cost of charged energy while connected
+
+	session           *db.Session // Current charging session
+	sessionSoCStarted bool        // Session start soc has been recorded
+	sessionOffset     float64     // Session energy not reported by the charge rater in Wh
+	sessionResumed    bool        // Session restored, charge rater reading not yet known
 }
 
 // NewLoadPointFromConfig creates a new loadpoint
@@ -129,10 +173,6 @@ func NewLoadPointFromConfig(log *util.Logger, cp configProvider, other map[strin
 		return nil, err
 	}
 
-	// set sane defaults
-	lp.Mode = api.ChargeModeString(string(lp.Mode))
-	lp.OnDisconnect.Mode = api.ChargeModeString(string(lp.OnDisconnect.Mode))
-
 	sort.Ints(lp.SoC.Levels)
 
 	// set vehicle polling mode
@@ -179,12 +219,22 @@ func NewLoadPointFromConfig(log *util.Logger, cp configProvider, other map[strin
 	for _, ref := range lp.VehiclesRef {
 		vehicle := cp.Vehicle(ref)
 		lp.vehicles = append(lp.vehicles, vehicle)
+		lp.vehicleRefs = append(lp.vehicleRefs, ref)
 	}
 
 	// single vehicle
 	if lp.VehicleRef != "" {
 		vehicle := cp.Vehicle(lp.VehicleRef)
 		lp.vehicles = append(lp.vehicles, vehicle)
+		lp.vehicleRefs = append(lp.vehicleRefs, lp.VehicleRef)
+	}
+
+	if lp.Precondition.Duration == 0 {
+		lp.Precondition.Duration = preconditionDuration
+	}
+
+	if err := lp.FailSafe.validate(); err != nil {
+		return nil, err
 	}
 
 	if lp.ChargerRef == "" {
@@ -195,6 +245,14 @@ func NewLoadPointFromConfig(log *util.Logger, cp configProvider, other map[strin
 
 	// allow target charge handler to access loadpoint
 	lp.socTimer = soc.NewTimer(lp.log, lp.adapter(), lp.MaxCurrent)
+
+	// configured defaults before runtime settings are restored by the site
+	lp.defaults = api.ActionConfig{
+		Mode:      lp.Mode,
+		MinSoC:    lp.SoC.Min,
+		TargetSoC: lp.SoC.Target,
+	}
+
 	if lp.Enable.Threshold > lp.Disable.Threshold {
 		log.WARN.Printf("PV mode enable threshold (%.0fW) is larger than disable threshold (%.0fW)", lp.Enable.Threshold, lp.Disable.Threshold)
 	}
@@ -238,8 +296,8 @@ func (lp *LoadPoint) configureChargerType(charger api.Charger) {
 			lp.chargeMeter = mt
 		} else {
 			mt := &wrapper.ChargeMeter{}
-			_ = lp.bus.Subscribe(evChargeCurrent, lp.evChargeCurrentWrappedMeterHandler)
-			_ = lp.bus.Subscribe(evChargeStop, func() { mt.SetPower(0) })
+			lp.subscribe(evChargeCurrent, lp.evChargeCurrentWrappedMeterHandler)
+			lp.subscribe(evChargeStop, func() { mt.SetPower(0) })
 			lp.chargeMeter = mt
 		}
 	}
@@ -249,10 +307,10 @@ func (lp *LoadPoint) configureChargerType(charger api.Charger) {
 		lp.chargeRater = rt
 	} else {
 		rt := wrapper.NewChargeRater(lp.log, lp.chargeMeter)
-		_ = lp.bus.Subscribe(evChargePower, rt.SetChargePower)
-		_ = lp.bus.Subscribe(evVehicleConnect, func() { rt.StartCharge(false) })
-		_ = lp.bus.Subscribe(evChargeStart, func() { rt.StartCharge(true) })
-		_ = lp.bus.Subscribe(evChargeStop, rt.StopCharge)
+		lp.subscribe(evChargePower, rt.SetChargePower)
+		lp.subscribe(evVehicleConnect, func() { rt.StartCharge(false) })
+		lp.subscribe(evChargeStart, func() { rt.StartCharge(true) })
+		lp.subscribe(evChargeStop, rt.StopCharge)
 		lp.chargeRater = rt
 	}
 
@@ -261,16 +319,16 @@ func (lp *LoadPoint) configureChargerType(charger api.Charger) {
 		lp.chargeTimer = ct
 	} else {
 		ct := wrapper.NewChargeTimer()
-		_ = lp.bus.Subscribe(evVehicleConnect, func() { ct.StartCharge(false) })
-		_ = lp.bus.Subscribe(evChargeStart, func() { ct.StartCharge(true) })
-		_ = lp.bus.Subscribe(evChargeStop, ct.StopCharge)
+		lp.subscribe(evVehicleConnect, func() { ct.StartCharge(false) })
+		lp.subscribe(evChargeStart, func() { ct.StartCharge(true) })
+		lp.subscribe(evChargeStop, ct.StopCharge)
 		lp.chargeTimer = ct
 	}
 }
 
 // triggerEvent sends push messages to clients
 func (lp *LoadPoint) triggerEvent(event string) {
-	lp.pushChan <- push.Event{Event: event}
+	lp.pushChan <- push.Event{Event: event, Time: lp.clock.Now()}
 }
 
 // publish sends values to UI and databases
@@ -294,6 +352,9 @@ func (lp *LoadPoint) evChargeStopHandler() {
 	lp.log.INFO.Println("stop charging <-")
 	lp.triggerEvent(evChargeStop)
 
+	// persist energy charged so far
+	lp.persistSession()
+
 	// soc update reset
 	lp.socUpdated = time.Time{}
 }
@@ -306,6 +367,13 @@ func (lp *LoadPoint) evVehicleConnectHandler() {
 	lp.chargedEnergy = 0
 	lp.publish("chargedEnergy", lp.chargedEnergy)
 
+	// solar share and cost
+	lp.chargedSolarEnergy = 0
+	lp.sessionEnergy = 0
+	lp.accountedEnergy = 0
+	lp.sessionCost = 0
+	lp.publishSessionEnergy()
+
 	// duration
 	lp.connectedTime = lp.clock.Now()
 	lp.publish("connectedDuration", time.Duration(0))
@@ -313,11 +381,16 @@ func (lp *LoadPoint) evVehicleConnectHandler() {
 	// soc update reset
 	lp.socUpdated = time.Time{}
 
+	// next vehicle requires its charge limit
+	lp.chargeLimit = 0
+
 	// soc update reset on car change
 	if lp.socEstimator != nil {
 		lp.socEstimator.Reset()
 	}
 
+	lp.startSession()
+
 	lp.triggerEvent(evVehicleConnect)
 }
 
@@ -329,18 +402,34 @@ func (lp *LoadPoint) evVehicleDisconnectHandler() {
 	lp.publish("chargedEnergy", lp.chargedEnergy)
 	lp.publish("connectedDuration", lp.clock.Since(lp.connectedTime))
 
+	// persist session before applying disconnect defaults
+	lp.finishSession()
+
 	lp.triggerEvent(evVehicleDisconnect)
 
 	// set default mode on disconnect
-	if lp.OnDisconnect.Mode != "" && lp.GetMode() != api.ModeOff {
-		lp.SetMode(lp.OnDisconnect.Mode)
+	onDisconnect := lp.vehicleDefaults().OnDisconnect
+	if onDisconnect.Mode != "" && lp.GetMode() != api.ModeOff {
+		lp.SetMode(onDisconnect.Mode)
+	}
+	if onDisconnect.MinSoC != 0 {
+		_ = lp.SetMinSoC(onDisconnect.MinSoC)
 	}
-	if lp.OnDisconnect.TargetSoC != 0 {
-		_ = lp.SetTargetSoC(lp.OnDisconnect.TargetSoC)
+	if onDisconnect.TargetSoC != 0 {
+		_ = lp.SetTargetSoC(onDisconnect.TargetSoC)
 	}
 
+	// identification is only valid while connected
+	lp.vehicleID = ""
+
 	// soc update reset
 	lp.socUpdated = time.Time{}
+
+	// next vehicle requires its charge limit
+	lp.chargeLimit = 0
+
+	// next vehicle may draw different currents
+	lp.resetCurrentLearning()
 }
 
 // evChargeCurrentHandler publishes the charge current
@@ -357,7 +446,7 @@ func (lp *LoadPoint) evChargeCurrentHandler(current float64) {
 // If physical charge meter is present this handler is not used.
 // The actual value is published by the evChargeCurrentHandler
 func (lp *LoadPoint) evChargeCurrentWrappedMeterHandler(current float64) {
-	power := current * float64(lp.Phases) * Voltage
+	power := current * float64(lp.Phases) * lp.voltage()
 
 	if !lp.enabled || lp.status != api.StatusC {
 		// if disabled we cannot be charging
@@ -387,18 +476,26 @@ func (lp *LoadPoint) Prepare(uiChan chan<- util.Param, pushChan chan<- push.Even
 	lp.pushChan = pushChan
 	lp.lpChan = lpChan
 
+	// site load management applies after first update
+	lp.currentLimit = noCurrentLimit
+
+	// assume charger uses configured phases until switched
+	lp.chargerPhases = lp.Phases
+	lp.maxPhases = lp.Phases
+
 	// event handlers
-	_ = lp.bus.Subscribe(evChargeStart, lp.evChargeStartHandler)
-	_ = lp.bus.Subscribe(evChargeStop, lp.evChargeStopHandler)
-	_ = lp.bus.Subscribe(evVehicleConnect, lp.evVehicleConnectHandler)
-	_ = lp.bus.Subscribe(evVehicleDisconnect, lp.evVehicleDisconnectHandler)
-	_ = lp.bus.Subscribe(evChargeCurrent, lp.evChargeCurrentHandler)
+	lp.subscribe(evChargeStart, lp.evChargeStartHandler)
+	lp.subscribe(evChargeStop, lp.evChargeStopHandler)
+	lp.subscribe(evVehicleConnect, lp.evVehicleConnectHandler)
+	lp.subscribe(evVehicleDisconnect, lp.evVehicleDisconnectHandler)
+	lp.subscribe(evChargeCurrent, lp.evChargeCurrentHandler)
 
 	// publish initial values
 	lp.publish("title", lp.Title)
 	lp.publish("minCurrent", lp.MinCurrent)
 	lp.publish("maxCurrent", lp.MaxCurrent)
 	lp.publish("phases", lp.Phases)
+	lp.publish("priority", lp.Priority)
 	lp.publish("activePhases", lp.Phases)
 	lp.publish("hasVehicle", len(lp.vehicles) > 0)
 
@@ -407,6 +504,7 @@ func (lp *LoadPoint) Prepare(uiChan chan<- util.Param, pushChan chan<- push.Even
 	lp.publish("targetSoC", lp.SoC.Target)
 	lp.publish("minSoC", lp.SoC.Min)
 	lp.publish("socLevels", lp.SoC.Levels)
+	lp.publish("departureTime", lp.departureTime)
 	lp.Unlock()
 
 	// use first vehicle for estimator
@@ -440,6 +538,45 @@ func (lp *LoadPoint) syncCharger() {
 }
 
 func (lp *LoadPoint) setLimit(chargeCurrent float64, force bool) (err error) {
+	// maximum current allowed by charger, site load management and remote power limit
+	maxCurrent := math.Min(float64(lp.MaxCurrent), math.Max(lp.currentLimit, 0))
+
+	// honour site load management
+	if chargeCurrent > lp.currentLimit {
+		lp.log.DEBUG.Printf("site current limit: %.2gA", lp.currentLimit)
+		chargeCurrent = math.Max(lp.currentLimit, 0)
+
+		// protecting the main fuse has priority over the contactor
+		if chargeCurrent < float64(lp.MinCurrent) {
+			force = true
+		}
+	}
+
+	// honour remote power limit
+	if limit := lp.powerLimit(); limit > 0 {
+		limitCurrent := powerToCurrent(limit, lp.voltage(), lp.Phases)
+		maxCurrent = math.Min(maxCurrent, limitCurrent)
+
+		if chargeCurrent > limitCurrent {
+			lp.log.DEBUG.Printf("remote power limit: %.0fW", limit)
+			chargeCurrent = limitCurrent
+
+			// limit cannot be met without disabling charger
+			if chargeCurrent < float64(lp.MinCurrent) {
+				force = true
+			}
+		}
+	}
+
+	// switch budget exhausted, keep charging at minimum current instead of disabling
+	if !force && lp.enabled && lp.connected() && chargeCurrent < float64(lp.MinCurrent) && lp.switchBudgetExhausted() {
+		lp.log.DEBUG.Println("switch budget exhausted - keep minimum current")
+		chargeCurrent = float64(lp.MinCurrent)
+	}
+
+	// compensate vehicle drawing less than commanded within the limits
+	chargeCurrent = lp.commandedCurrent(chargeCurrent, maxCurrent)
+
 	// set current
 	if chargeCurrent != lp.chargeCurrent && chargeCurrent >= float64(lp.MinCurrent) {
 		if charger, ok := lp.charger.(api.ChargerEx); ok {
@@ -452,6 +589,7 @@ func (lp *LoadPoint) setLimit(chargeCurrent float64, force bool) (err error) {
 
 		if err == nil {
 			lp.chargeCurrent = chargeCurrent
+			lp.currentUpdated = lp.clock.Now()
 			lp.bus.Publish(evChargeCurrent, chargeCurrent)
 		} else {
 			lp.log.ERROR.Printf("max charge current %.2g: %v", chargeCurrent, err)
@@ -460,15 +598,25 @@ func (lp *LoadPoint) setLimit(chargeCurrent float64, force bool) (err error) {
 
 	// set enabled
 	if enabled := chargeCurrent >= float64(lp.MinCurrent); enabled != lp.enabled && err == nil {
-		if remaining := (lp.GuardDuration - lp.clock.Since(lp.guardUpdated)).Truncate(time.Second); remaining > 0 && !force {
+		if remaining := lp.switchHold(enabled); remaining > 0 && !force {
 			lp.log.DEBUG.Printf("charger %s - contactor delay %v", status[enabled], remaining)
 			return nil
 		}
 
+		if enabled && !force && lp.switchBudgetExhausted() {
+			lp.log.DEBUG.Printf("charger %s - switch budget exhausted", status[enabled])
+			return nil
+		}
+
 		lp.log.DEBUG.Printf("charger %s", status[enabled])
 		if err = lp.charger.Enable(enabled); err == nil {
 			lp.enabled = enabled
 			lp.guardUpdated = lp.clock.Now()
+			lp.currentUpdated = lp.guardUpdated
+
+			if enabled {
+				lp.addSwitchStart(lp.guardUpdated)
+			}
 
 			lp.bus.Publish(evChargeCurrent, chargeCurrent)
 			lp.log.DEBUG.Printf("charger %s", status[enabled])
@@ -487,6 +635,21 @@ func (lp *LoadPoint) setLimit(chargeCurrent float64, force bool) (err error) {
 	return err
 }
 
+// checkCheapest warns if cheapest mode cannot charge due to missing tariff or vehicle
+func (lp *LoadPoint) checkCheapest(mode api.ChargeMode) {
+	if mode != api.ModeCheapest {
+		return
+	}
+
+	if !lp.planner.Configured() {
+		lp.log.WARN.Println("cheapest mode requires a grid tariff, vehicle will not charge")
+	}
+
+	if len(lp.vehicles) == 0 {
+		lp.log.WARN.Println("cheapest mode requires a vehicle, vehicle will not charge")
+	}
+}
+
 // connected returns the EVs connection state
 func (lp *LoadPoint) connected() bool {
 	return lp.status == api.StatusB || lp.status == api.StatusC
@@ -497,6 +660,14 @@ func (lp *LoadPoint) charging() bool {
 	return lp.status == api.StatusC
 }
 
+// voltage returns the operating voltage of the loadpoint's site
+func (lp *LoadPoint) voltage() float64 {
+	if lp.siteVoltage > 0 {
+		return lp.siteVoltage
+	}
+	return Voltage
+}
+
 // targetSocReached checks if target is configured and reached.
 // If vehicle is not configured this will always return false
 func (lp *LoadPoint) targetSocReached() bool {
@@ -543,6 +714,26 @@ func (lp *LoadPoint) climateActive() bool {
 	return false
 }
 
+// powerLimit returns the effective remote power limit of loadpoint and site, zero if not limited
+func (lp *LoadPoint) powerLimit() float64 {
+	lp.Lock()
+	defer lp.Unlock()
+
+	limit := lp.remotePowerLimit
+	if limit.Power > 0 && !limit.active(lp.clock.Now()) {
+		lp.log.INFO.Printf("remote power limit expired (%s)", limit.Source)
+		lp.remotePowerLimit = RemotePowerLimit{}
+		lp.publishRemotePowerLimit()
+	}
+
+	res := lp.remotePowerLimit.Power
+	if lp.sitePowerLimit > 0 && (res == 0 || lp.sitePowerLimit < res) {
+		res = lp.sitePowerLimit
+	}
+
+	return res
+}
+
 // remoteControlled returns true if remote control status is active
 func (lp *LoadPoint) remoteControlled(demand RemoteDemand) bool {
 	lp.Lock()
@@ -553,15 +744,107 @@ func (lp *LoadPoint) remoteControlled(demand RemoteDemand) bool {
 
 // setActiveVehicle assigns currently active vehicle and configures soc estimator
 func (lp *LoadPoint) setActiveVehicle(vehicle api.Vehicle) {
-	if lp.vehicle != nil {
+	changed := lp.vehicle != nil
+	if changed {
 		lp.log.INFO.Printf("vehicle updated: %s -> %s", lp.vehicle.Title(), vehicle.Title())
 	}
 
+	// persist charge curve samples of the previous vehicle
+	if lp.chargeCurveChanged && lp.socEstimator != nil {
+		lp.saveChargeCurve()
+	}
+
 	lp.vehicle = vehicle
+	lp.chargeLimit = 0
 	lp.socEstimator = soc.NewEstimator(lp.log, vehicle, lp.SoC.Estimate)
+	if vc, ok := vehicle.(api.VehicleChargeCurve); ok {
+		lp.socEstimator.SetChargeCurve(vc.ChargeCurve())
+	}
 
 	lp.publish("socTitle", lp.vehicle.Title())
 	lp.publish("socCapacity", lp.vehicle.Capacity())
+
+	// keep mode and restored soc settings on startup
+	lp.applyVehicleDefaults(changed)
+	lp.restoreVehicleSettings()
+}
+
+// updateChargeLimit applies the target soc as charge limit of connected vehicles supporting it
+func (lp *LoadPoint) updateChargeLimit() {
+	vcl, ok := lp.vehicle.(api.VehicleChargeLimit)
+	if !ok || !lp.connected() || lp.chargeLimit == lp.SoC.Target {
+		return
+	}
+
+	// don't hammer the vehicle api after failure
+	if lp.clock.Since(lp.chargeLimitFailed) < lp.SoC.Poll.Interval {
+		return
+	}
+
+	lp.log.INFO.Printf("vehicle charge limit: %d%%", lp.SoC.Target)
+
+	if err := vcl.SetChargeLimit(lp.SoC.Target); err != nil {
+		lp.log.ERROR.Printf("vehicle charge limit: %v", err)
+		lp.chargeLimitFailed = lp.clock.Now()
+		return
+	}
+
+	lp.chargeLimit = lp.SoC.Target
+	lp.chargeLimitFailed = time.Time{}
+}
+
+// findVehicleByID returns the vehicle with matching identifier
+func findVehicleByID(vehicles []api.Vehicle, id string) api.Vehicle {
+	for _, vehicle := range vehicles {
+		for _, vid := range vehicle.Identifiers() {
+			if strings.EqualFold(vid, id) {
+				return vehicle
+			}
+		}
+	}
+	return nil
+}
+
+// identifyVehicle activates the vehicle matching the charger-reported identification.
+// Returns true if the vehicle has been identified.
+func (lp *LoadPoint) identifyVehicle() bool {
+	identifier, ok := lp.charger.(api.Identifier)
+	if !ok || !lp.connected() {
+		return false
+	}
+
+	id, err := identifier.Identify()
+	if err != nil {
+		lp.log.ERROR.Printf("charger identification: %v", err)
+		return false
+	}
+
+	changed := id != lp.vehicleID
+	if changed {
+		lp.vehicleID = id
+		lp.publish("vehicleIdentity", id)
+	}
+
+	if id == "" {
+		return false
+	}
+
+	vehicle := findVehicleByID(lp.vehicles, id)
+	if vehicle == nil {
+		if changed {
+			lp.log.WARN.Printf("unknown vehicle identification: %s", id)
+		}
+		return false
+	}
+
+	if vehicle != lp.vehicle {
+		lp.setActiveVehicle(vehicle)
+
+		// update soc from identified vehicle
+		lp.socUpdated = time.Time{}
+	}
+
+	return true
 }
 
 // findActiveVehicle validates if the active vehicle is still connected to the loadpoint
@@ -570,6 +853,11 @@ func (lp *LoadPoint) findActiveVehicle() {
 		return
 	}
 
+	// charger-reported identification takes precedence over vehicle status
+	if lp.identifyVehicle() {
+		return
+	}
+
 	if vs, ok := lp.vehicle.(api.VehicleStatus); ok {
 		status, err := vs.Status()
 
@@ -619,7 +907,20 @@ func (lp *LoadPoint) updateChargerStatus() error {
 		// changed from empty (initial startup) - set connected without sending message
 		if prevStatus == api.StatusNone {
 			lp.connectedTime = lp.clock.Now()
-			lp.publish("connectedDuration", time.Duration(0))
+
+			switch {
+			case lp.connected() && lp.session == nil:
+				// vehicle connected before startup
+				lp.startSession()
+			case lp.connected():
+				// continue session restored after restart
+				lp.connectedTime = lp.session.Created
+			default:
+				// vehicle disconnected while not running, finish restored session
+				lp.finishSession()
+			}
+
+			lp.publish("connectedDuration", lp.clock.Since(lp.connectedTime))
 		}
 
 		// changed from A - connected
@@ -664,6 +965,8 @@ func (lp *LoadPoint) detectPhases() {
 	lp.publish("chargeCurrents", currents)
 
 	if lp.charging() {
+		lp.learnCurrent(currents)
+
 		var phases int64
 		for _, i := range currents {
 			if i >= minActiveCurrent {
@@ -681,24 +984,42 @@ func (lp *LoadPoint) detectPhases() {
 }
 
 // effectiveCurrent returns the currently effective charging current
-// it does not take measured currents into account
+// corrected by the learned current offset and vehicle max current
 func (lp *LoadPoint) effectiveCurrent() float64 {
 	if lp.status != api.StatusC {
 		return 0
 	}
-	return lp.chargeCurrent
+	return math.Min(lp.chargeCurrent-lp.currentOffset, lp.maxVehicleCurrent())
 }
 
 // pvDisableTimer puts the pv enable/disable timer into elapsed state
 func (lp *LoadPoint) pvDisableTimer() {
-	lp.pvTimer = time.Now().Add(-lp.Disable.Delay)
+	lp.pvTimer = lp.clock.Now().Add(-lp.Disable.Delay)
 }
 
 // pvMaxCurrent calculates the maximum target current for PV mode
 func (lp *LoadPoint) pvMaxCurrent(mode api.ChargeMode, sitePower float64) float64 {
+	// switch phases before calculating target current
+	if lp.pvScalePhases(sitePower) {
+		// measured power does not yet reflect the new phases, keep minimum current for this cycle
+		if lp.enabled || mode == api.ModeMinPV {
+			return float64(lp.MinCurrent)
+		}
+		return 0
+	}
+
+	// site power is filtered for disable decisions, enable power for enable decisions
+	enablePower := sitePower + lp.enablePowerOffset
+
 	// calculate target charge current from delta power and actual current
 	effectiveCurrent := lp.effectiveCurrent()
-	deltaCurrent := powerToCurrent(-sitePower, lp.Phases)
+	deltaCurrent := powerToCurrent(-sitePower, lp.voltage(), lp.Phases)
+
+	// increasing current requires both enable and disable filtered power
+	if enableDelta := powerToCurrent(-enablePower, lp.voltage(), lp.Phases); deltaCurrent > 0 {
+		deltaCurrent = math.Max(0, math.Min(deltaCurrent, enableDelta))
+	}
+
 	targetCurrent := math.Max(math.Min(effectiveCurrent+deltaCurrent, float64(lp.MaxCurrent)), 0)
 
 	lp.log.DEBUG.Printf("max charge current: %.2gA = %.2gA + %.2gA (%.0fW @ %dp)", targetCurrent, effectiveCurrent, deltaCurrent, sitePower, lp.Phases)
@@ -737,8 +1058,8 @@ func (lp *LoadPoint) pvMaxCurrent(mode api.ChargeMode, sitePower float64) float6
 	if mode == api.ModePV && !lp.enabled {
 		// kick off enable sequence
 		if targetCurrent >= float64(lp.MinCurrent) ||
-			(lp.Enable.Threshold != 0 && sitePower <= lp.Enable.Threshold) {
-			lp.log.DEBUG.Printf("site power %.0fW < enable threshold %.0fW", sitePower, lp.Enable.Threshold)
+			(lp.Enable.Threshold != 0 && enablePower <= lp.Enable.Threshold) {
+			lp.log.DEBUG.Printf("site power %.0fW < enable threshold %.0fW", enablePower, lp.Enable.Threshold)
 
 			if lp.pvTimer.IsZero() {
 				lp.log.DEBUG.Printf("start pv enable timer: %v", lp.Enable.Delay)
@@ -792,6 +1113,7 @@ func (lp *LoadPoint) updateChargeMeter() {
 func (lp *LoadPoint) publishChargeProgress() {
 	if f, err := lp.chargeRater.ChargedEnergy(); err == nil {
 		lp.chargedEnergy = 1e3 * f // convert to Wh
+		lp.resumeSession()
 	} else {
 		lp.log.ERROR.Printf("charge rater error: %v", err)
 	}
@@ -825,6 +1147,22 @@ func (lp *LoadPoint) socPollAllowed() bool {
 	return res
 }
 
+// fullPowerOffered returns true if charging at maximum current on all phases without site limits,
+// so that charge power is only limited by the vehicle
+func (lp *LoadPoint) fullPowerOffered() bool {
+	maxCurrent := float64(lp.MaxCurrent)
+
+	return lp.charging() && lp.chargeCurrent >= maxCurrent &&
+		lp.chargerPhases >= lp.maxPhases && lp.currentLimit >= maxCurrent &&
+		(lp.sitePowerLimit == 0 || powerToCurrent(lp.sitePowerLimit, lp.voltage(), lp.Phases) >= maxCurrent)
+}
+
+// saveChargeCurve persists the learned charge curve for the active vehicle
+func (lp *LoadPoint) saveChargeCurve() {
+	lp.chargeCurveChanged = false
+	lp.saveVehicleCharacteristic("chargeCurve", lp.socEstimator.LearnedChargeCurve())
+}
+
 // publish state of charge, remaining charge duration and range
 func (lp *LoadPoint) publishSoCAndRange() {
 	if lp.socEstimator == nil {
@@ -832,12 +1170,31 @@ func (lp *LoadPoint) publishSoCAndRange() {
 	}
 
 	if lp.socPollAllowed() {
+		learned := lp.socEstimator.EnergyPerSocStep()
+
 		f, err := lp.socEstimator.SoC(lp.chargedEnergy)
 		if err == nil {
 			lp.socCharge = math.Trunc(f)
 			lp.log.DEBUG.Printf("vehicle soc: %.0f%%", lp.socCharge)
 			lp.publish("socCharge", lp.socCharge)
 
+			// persist learned vehicle charge characteristics
+			if energy := lp.socEstimator.EnergyPerSocStep(); energy != learned {
+				lp.saveVehicleCharacteristic("energyPerSocStep", energy)
+			}
+
+			// vehicle accepts less than offered power at full current
+			if lp.fullPowerOffered() {
+				if lp.socEstimator.LearnChargePower(lp.chargePower) {
+					lp.saveChargeCurve()
+				} else {
+					lp.chargeCurveChanged = true
+				}
+			}
+
+			// track session soc
+			lp.updateSessionSoC(lp.socCharge)
+
 			chargeEstimate := time.Duration(-1)
 			if lp.charging() {
 				chargeEstimate = lp.socEstimator.RemainingChargeDuration(lp.chargePower, lp.SoC.Target)
@@ -874,8 +1231,9 @@ func (lp *LoadPoint) publishSoCAndRange() {
 	}
 }
 
-// Update is the main control function. It reevaluates meters and charger state
-func (lp *LoadPoint) Update(sitePower float64) {
+// Update is the main control function. It reevaluates meters and charger state.
+// The available current is the per-phase current the loadpoint may additionally draw from the site.
+func (lp *LoadPoint) Update(sitePower float64, availableCurrent float64) {
 	mode := lp.GetMode()
 	lp.publish("mode", mode)
 
@@ -892,12 +1250,16 @@ func (lp *LoadPoint) Update(sitePower float64) {
 	// read and publish status
 	if err := lp.updateChargerStatus(); err != nil {
 		lp.log.ERROR.Printf("charger error: %v", err)
+		lp.fault(err)
 		return
 	}
 
+	lp.clearFault()
+
 	lp.publish("connected", lp.connected())
 	lp.publish("charging", lp.charging())
 	lp.publish("enabled", lp.enabled)
+	lp.publishSwitchCycles()
 
 	// update active vehicle and publish soc
 	// must be run after updating charger status to make sure
@@ -911,12 +1273,24 @@ func (lp *LoadPoint) Update(sitePower float64) {
 	// phase detection
 	lp.detectPhases()
 
+	// departure climatisation
+	lp.updatePrecondition()
+
+	// vehicle-side charge limit
+	lp.updateChargeLimit()
+
+	// site load management
+	lp.currentLimit = lp.effectiveCurrent() + availableCurrent
+
 	// check if car connected and ready for charging
 	var err error
 
 	// track if remote disabled is actually active
 	remoteDisabled := RemoteEnable
 
+	// track if charging for target time
+	lp.targetCharging = false
+
 	// execute loading strategy
 	switch {
 	case !lp.connected():
@@ -927,7 +1301,7 @@ func (lp *LoadPoint) Update(sitePower float64) {
 	case lp.targetSocReached():
 		lp.log.DEBUG.Printf("targetSoC reached: %.1f > %d", lp.socCharge, lp.SoC.Target)
 		var targetCurrent float64 // zero disables
-		if lp.climateActive() {
+		if lp.climateRequired() {
 			lp.log.DEBUG.Println("climater active")
 			targetCurrent = float64(lp.MinCurrent)
 		}
@@ -943,14 +1317,35 @@ func (lp *LoadPoint) Update(sitePower float64) {
 		err = lp.setLimit(0, true)
 
 	case lp.minSocNotReached():
+		lp.restorePhases()
 		err = lp.setLimit(float64(lp.MaxCurrent), true)
 		lp.pvDisableTimer() // let PV mode disable immediately afterwards
 
 	case mode == api.ModeNow:
+		lp.restorePhases()
 		err = lp.setLimit(float64(lp.MaxCurrent), true)
 
+	// cheapest tariff slots, honouring target charge request if set
+	case mode == api.ModeCheapest:
+		targetSoC, targetTime := lp.SoC.Target, lp.socTimer.Time
+		if !targetTime.IsZero() {
+			targetSoC = lp.socTimer.SoC
+		}
+
+		lp.restorePhases()
+
+		var targetCurrent float64 // zero disables
+		if lp.planner.Active(targetSoC, targetTime) {
+			targetCurrent = float64(lp.MaxCurrent)
+		} else if lp.preconditioning {
+			targetCurrent = float64(lp.MinCurrent)
+		}
+		err = lp.setLimit(targetCurrent, true)
+
 	// target charging
 	case lp.socTimer.StartRequired():
+		lp.targetCharging = true
+		lp.restorePhases()
 		targetCurrent := lp.socTimer.Handle()
 		err = lp.setLimit(targetCurrent, false)
 
@@ -959,7 +1354,7 @@ func (lp *LoadPoint) Update(sitePower float64) {
 		lp.log.DEBUG.Printf("pv max charge current: %.2gA", targetCurrent)
 
 		var required bool // false
-		if targetCurrent == 0 && lp.climateActive() {
+		if targetCurrent == 0 && lp.climateRequired() {
 			targetCurrent = float64(lp.MinCurrent)
 			required = true
 		}
@@ -1,6 +1,9 @@
 package core
 
-import "github.com/andig/evcc/core/soc"
+import (
+	"github.com/andig/evcc/core/soc"
+	"github.com/benbjohnson/clock"
+)
 
 type adapter struct {
 	lp *LoadPoint
@@ -23,5 +26,9 @@ func (a *adapter) ActivePhases() int64 {
 }
 
 func (a *adapter) Voltage() float64 {
-	return Voltage
+	return a.lp.voltage()
+}
+
+func (a *adapter) Clock() clock.Clock {
+	return a.lp.clock
 }
@@ -20,7 +20,9 @@ type LoadPointAPI interface {
 	GetMinSoC() int
 	SetMinSoC(int) error
 	SetTargetCharge(time.Time, int)
+	SetDepartureTime(time.Time)
 	RemoteControl(string, RemoteDemand)
+	SetRemotePowerLimit(string, float64, time.Duration)
 
 	// energy
 	GetMinCurrent() int64
@@ -42,11 +44,13 @@ func (lp *LoadPoint) SetMode(mode api.ChargeMode) {
 	defer lp.Unlock()
 
 	lp.log.INFO.Printf("set charge mode: %s", string(mode))
+	lp.checkCheapest(mode)
 
 	// apply immediately
 	if lp.Mode != mode {
 		lp.Mode = mode
 		lp.publish("mode", mode)
+		lp.saveSetting("mode", mode)
 		lp.requestUpdate()
 	}
 }
@@ -73,6 +77,7 @@ func (lp *LoadPoint) SetTargetSoC(soc int) error {
 	if lp.SoC.Target != soc {
 		lp.SoC.Target = soc
 		lp.publish("targetSoC", soc)
+		lp.saveVehicleSetting("targetSoC", soc)
 		lp.requestUpdate()
 	}
 
@@ -101,6 +106,7 @@ func (lp *LoadPoint) SetMinSoC(soc int) error {
 	if lp.SoC.Min != soc {
 		lp.SoC.Min = soc
 		lp.publish("minSoC", soc)
+		lp.saveVehicleSetting("minSoC", soc)
 		lp.requestUpdate()
 	}
 
@@ -114,6 +120,10 @@ func (lp *LoadPoint) SetTargetCharge(finishAt time.Time, targetSoC int) {
 
 	lp.log.INFO.Printf("set target charge: %d @ %v", targetSoC, finishAt)
 
+	lp.socTimer.Time = finishAt
+	lp.socTimer.SoC = targetSoC
+	lp.saveSetting("targetCharge", targetCharge{Time: finishAt, SoC: targetSoC})
+
 	// apply immediately
 	// TODO check reset of targetSoC
 	lp.publish("targetTime", finishAt)
@@ -140,6 +150,25 @@ func (lp *LoadPoint) RemoteControl(source string, demand RemoteDemand) {
 	}
 }
 
+// SetRemotePowerLimit limits the charging power. Zero power removes the limit, zero duration does not expire.
+func (lp *LoadPoint) SetRemotePowerLimit(source string, power float64, duration time.Duration) {
+	lp.Lock()
+	defer lp.Unlock()
+
+	lp.log.INFO.Printf("remote power limit: %.0fW (%s)", power, source)
+
+	lp.remotePowerLimit = newRemotePowerLimit(source, power, duration, lp.clock.Now())
+	lp.publishRemotePowerLimit()
+
+	lp.requestUpdate()
+}
+
+// publishRemotePowerLimit publishes the remote power limit
+func (lp *LoadPoint) publishRemotePowerLimit() {
+	lp.publish("remotePowerLimit", lp.remotePowerLimit.Power)
+	lp.publish("remotePowerLimitSource", lp.remotePowerLimit.Source)
+}
+
 // HasChargeMeter determines if a physical charge meter is attached
 func (lp *LoadPoint) HasChargeMeter() bool {
 	_, isWrapped := lp.chargeMeter.(*wrapper.ChargeMeter)
@@ -158,10 +187,10 @@ func (lp *LoadPoint) GetMaxCurrent() int64 {
 
 // GetMinPower returns the minimal loadpoint power for a single phase
 func (lp *LoadPoint) GetMinPower() int64 {
-	return int64(Voltage) * lp.MinCurrent
+	return int64(lp.voltage()) * lp.MinCurrent
 }
 
 // GetMaxPower returns the minimal loadpoint power taking active phases into account
 func (lp *LoadPoint) GetMaxPower() int64 {
-	return int64(Voltage) * lp.Phases * lp.MaxCurrent
+	return int64(lp.voltage()) * lp.Phases * lp.MaxCurrent
 }
@@ -0,0 +1,88 @@
+package core
+
+import (
+	"time"
+
+	"github.com/andig/evcc/api"
+)
+
+const preconditionDuration = 15 * time.Minute // default climatisation start before departure
+
+// PreconditionConfig configures vehicle climatisation before departure
+type PreconditionConfig struct {
+	Duration    time.Duration `mapstructure:"duration"`    // Climatisation start before departure
+	Temperature float64       `mapstructure:"temperature"` // Cabin target temperature, zero keeps the vehicle's setting
+}
+
+// climateRequired returns true if the vehicle requires grid power for climatisation
+func (lp *LoadPoint) climateRequired() bool {
+	return lp.preconditioning || lp.climateActive()
+}
+
+// updatePrecondition starts climatisation shortly before the departure time while the vehicle is connected.
+// Climatisation is stopped if the departure is cancelled but continues when the vehicle is disconnected.
+func (lp *LoadPoint) updatePrecondition() {
+	now := lp.clock.Now()
+
+	// departure time reached, vehicle keeps climatisation running
+	if !lp.departureTime.IsZero() && !now.Before(lp.departureTime) {
+		lp.log.DEBUG.Println("precondition: departure time reached")
+		lp.setDepartureTime(time.Time{})
+
+		if lp.preconditioning {
+			lp.preconditioning = false
+			lp.publish("preconditioning", lp.preconditioning)
+		}
+	}
+
+	due := !lp.departureTime.IsZero() && lp.connected() &&
+		!now.Before(lp.departureTime.Add(-lp.Precondition.Duration))
+
+	if due == lp.preconditioning {
+		return
+	}
+
+	// stop only if departure has been cancelled or postponed
+	stop := !due && lp.connected()
+
+	if cc, ok := lp.vehicle.(api.VehicleClimateController); ok && (due || stop) {
+		var err error
+		if due {
+			lp.log.INFO.Printf("precondition: start climate for departure at %v", lp.departureTime.Round(time.Minute))
+			err = cc.StartClimate(lp.Precondition.Temperature)
+		} else {
+			lp.log.INFO.Println("precondition: stop climate")
+			err = cc.StopClimate()
+		}
+
+		// retry next cycle
+		if err != nil {
+			lp.log.ERROR.Printf("precondition: %v", err)
+			return
+		}
+	}
+
+	lp.preconditioning = due
+	lp.publish("preconditioning", lp.preconditioning)
+}
+
+// setDepartureTime sets and persists the departure time, zero time removes it
+func (lp *LoadPoint) setDepartureTime(departure time.Time) {
+	lp.departureTime = departure
+	lp.publish("departureTime", departure)
+	lp.saveSetting("departureTime", departure)
+}
+
+// SetDepartureTime sets the departure time for vehicle preconditioning, zero time removes it
+func (lp *LoadPoint) SetDepartureTime(departure time.Time) {
+	lp.Lock()
+	defer lp.Unlock()
+
+	lp.log.INFO.Printf("set departure time: %v", departure)
+
+	// apply immediately
+	if !lp.departureTime.Equal(departure) {
+		lp.setDepartureTime(departure)
+		lp.requestUpdate()
+	}
+}
@@ -0,0 +1,79 @@
+package core
+
+import (
+	"math"
+	"time"
+)
+
+const (
+	currentSettleTime   = 30 * time.Second // time for the vehicle to follow a changed charge current
+	maxCurrentOffset    = 2.0              // A, larger deficits are treated as vehicle current limit
+	currentOffsetWeight = 0.2              // weight of each measurement when learning the offset
+)
+
+// learnCurrent learns the offset between commanded and measured charge current and the
+// vehicle's effective max current. Currents are only evaluated once the vehicle has settled.
+func (lp *LoadPoint) learnCurrent(currents []float64) {
+	if !lp.enabled || lp.chargeCurrent == 0 || lp.clock.Since(lp.currentUpdated) < currentSettleTime {
+		return
+	}
+
+	var measured float64
+	for _, i := range currents {
+		measured = math.Max(measured, i)
+	}
+
+	if measured < minActiveCurrent {
+		return
+	}
+
+	deficit := lp.chargeCurrent - measured
+
+	switch {
+	// vehicle draws less than commanded, e.g. due to an internal current limit
+	case deficit > maxCurrentOffset:
+		if lp.vehicleMaxCurrent == 0 || math.Abs(measured-lp.vehicleMaxCurrent) >= 0.5 {
+			lp.log.DEBUG.Printf("vehicle max current: %.1fA (commanded %.1fA)", measured, lp.chargeCurrent)
+			lp.vehicleMaxCurrent = measured
+			lp.publish("vehicleMaxCurrent", lp.vehicleMaxCurrent)
+		}
+
+	default:
+		// vehicle follows commanded current, limit no longer applies
+		if lp.vehicleMaxCurrent > 0 && measured > lp.vehicleMaxCurrent+0.5 {
+			lp.log.DEBUG.Printf("vehicle max current: released at %.1fA", measured)
+			lp.vehicleMaxCurrent = 0
+			lp.publish("vehicleMaxCurrent", lp.vehicleMaxCurrent)
+		}
+
+		offset := lp.currentOffset + currentOffsetWeight*(deficit-lp.currentOffset)
+		lp.currentOffset = math.Max(0, math.Min(offset, maxCurrentOffset))
+		lp.log.DEBUG.Printf("charge current offset: %.2fA (commanded %.1fA, measured %.1fA)", lp.currentOffset, lp.chargeCurrent, measured)
+		lp.publish("chargeCurrentOffset", lp.currentOffset)
+	}
+}
+
+// resetCurrentLearning forgets the learned offset and max current, e.g. when the vehicle changes
+func (lp *LoadPoint) resetCurrentLearning() {
+	lp.currentOffset = 0
+	lp.vehicleMaxCurrent = 0
+	lp.publish("chargeCurrentOffset", lp.currentOffset)
+	lp.publish("vehicleMaxCurrent", lp.vehicleMaxCurrent)
+}
+
+// commandedCurrent returns the current to command for the vehicle to draw the target current.
+// The compensated current does not exceed the maximum current.
+func (lp *LoadPoint) commandedCurrent(target, maxCurrent float64) float64 {
+	if lp.currentOffset == 0 || target < float64(lp.MinCurrent) {
+		return target
+	}
+	return math.Max(target, math.Min(target+lp.currentOffset, maxCurrent))
+}
+
+// maxVehicleCurrent returns the maximum current the vehicle draws
+func (lp *LoadPoint) maxVehicleCurrent() float64 {
+	if lp.vehicleMaxCurrent > 0 {
+		return math.Min(lp.vehicleMaxCurrent, float64(lp.MaxCurrent))
+	}
+	return float64(lp.MaxCurrent)
+}
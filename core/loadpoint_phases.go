@@ -0,0 +1,106 @@
+package core
+
+import (
+	"time"
+
+	"github.com/andig/evcc/api"
+)
+
+// setPhases switches the charger's phases and updates the loadpoint accordingly.
+// An enabled charger is disabled while switching to protect the contactors.
+func (lp *LoadPoint) setPhases(phases int64) error {
+	phaser, ok := lp.charger.(api.ChargePhases)
+	if !ok {
+		return api.ErrNotAvailable
+	}
+
+	if lp.enabled {
+		lp.log.DEBUG.Println("switch phases: disable charger")
+		if err := lp.charger.Enable(false); err != nil {
+			return err
+		}
+	}
+
+	lp.log.DEBUG.Printf("switch phases: %dp", phases)
+	err := phaser.Phases1p3p(int(phases))
+	if err == nil {
+		lp.chargerPhases = phases
+		lp.Phases = phases
+		lp.publish("activePhases", lp.Phases)
+	}
+
+	if lp.enabled {
+		lp.log.DEBUG.Println("switch phases: enable charger")
+		if enableErr := lp.charger.Enable(true); enableErr != nil {
+			lp.enabled = false
+			lp.publish("enabled", lp.enabled)
+			if err == nil {
+				err = enableErr
+			}
+		}
+		lp.guardUpdated = lp.clock.Now()
+	}
+
+	return err
+}
+
+// restorePhases switches the charger back to the configured phases if not charging in pv modes
+func (lp *LoadPoint) restorePhases() {
+	if _, ok := lp.charger.(api.ChargePhases); !ok || lp.maxPhases == 0 || lp.chargerPhases == lp.maxPhases {
+		return
+	}
+
+	lp.phaseTimer = time.Time{}
+
+	if err := lp.setPhases(lp.maxPhases); err != nil {
+		lp.log.ERROR.Printf("switch phases: %v", err)
+	}
+}
+
+// pvScalePhases switches between 1p and 3p depending on available pv power.
+// Switching is delayed by the enable/disable delays using a separate timer.
+// Returns true if phases have been switched. Loadpoints configured for 1p are not scaled.
+func (lp *LoadPoint) pvScalePhases(sitePower float64) bool {
+	if _, ok := lp.charger.(api.ChargePhases); !ok || lp.maxPhases != 3 {
+		return false
+	}
+
+	availablePower := lp.chargePower - sitePower
+	minPower3p := float64(lp.MinCurrent) * 3 * lp.voltage()
+
+	var targetPhases int64
+	var delay time.Duration
+
+	switch {
+	case lp.chargerPhases != 1 && availablePower < minPower3p:
+		targetPhases, delay = 1, lp.Disable.Delay
+	case lp.chargerPhases == 1 && availablePower >= minPower3p:
+		targetPhases, delay = 3, lp.Enable.Delay
+	default:
+		// reset timer
+		lp.phaseTimer = time.Time{}
+		return false
+	}
+
+	lp.log.DEBUG.Printf("available power %.0fW, 3p minimum %.0fW", availablePower, minPower3p)
+
+	if lp.phaseTimer.IsZero() {
+		lp.log.DEBUG.Printf("start phase %dp timer: %v", targetPhases, delay)
+		lp.phaseTimer = lp.clock.Now()
+	}
+
+	elapsed := lp.clock.Since(lp.phaseTimer)
+	if elapsed < delay {
+		lp.log.DEBUG.Printf("phase %dp timer remaining: %v", targetPhases, (delay - elapsed).Round(time.Second))
+		return false
+	}
+
+	lp.phaseTimer = time.Time{}
+
+	if err := lp.setPhases(targetPhases); err != nil {
+		lp.log.ERROR.Printf("switch phases: %v", err)
+		return false
+	}
+
+	return true
+}
@@ -0,0 +1,71 @@
+package core
+
+import (
+	"github.com/andig/evcc/core/wrapper"
+)
+
+// subscription is an event handler attached to the loadpoint's event bus
+type subscription struct {
+	topic string
+	fn    interface{}
+}
+
+// subscribe attaches an event handler to the loadpoint's event bus
+func (lp *LoadPoint) subscribe(topic string, fn interface{}) {
+	_ = lp.bus.Subscribe(topic, fn)
+	lp.handlers = append(lp.handlers, subscription{topic: topic, fn: fn})
+}
+
+// close detaches all event handlers of a replaced loadpoint
+func (lp *LoadPoint) close() {
+	for _, h := range lp.handlers {
+		_ = lp.bus.Unsubscribe(h.topic, h.fn)
+	}
+	lp.handlers = nil
+}
+
+// takeOver continues the runtime state of the replaced loadpoint. Must be called before Prepare.
+func (lp *LoadPoint) takeOver(old *LoadPoint) {
+	old.Lock()
+	defer old.Unlock()
+
+	// vehicle status and timers
+	lp.status = old.status
+	lp.connectedTime = old.connectedTime
+	lp.pvTimer = old.pvTimer
+	lp.phaseTimer = old.phaseTimer
+	lp.guardUpdated = old.guardUpdated
+	lp.switchStarts = old.switchStarts
+	lp.socUpdated = old.socUpdated
+	lp.socCharge = old.socCharge
+
+	// external control
+	lp.remoteDemand = old.remoteDemand
+	lp.remotePowerLimit = old.remotePowerLimit
+
+	// open session, energy is continued from the new charge rater's first reading
+	lp.session = old.session
+	lp.sessionSoCStarted = old.sessionSoCStarted
+	lp.chargedSolarEnergy = old.chargedSolarEnergy
+	lp.sessionEnergy = old.sessionEnergy
+	lp.sessionCost = old.sessionCost
+	lp.chargedEnergy = old.chargedEnergy
+	lp.sessionOffset = 0
+	lp.sessionResumed = false
+
+	if lp.session != nil {
+		lp.sessionOffset = old.sessionChargedEnergy()
+		lp.sessionResumed = true
+		lp.persistSession()
+	}
+
+	// wrapped rater and timer only start on charge events
+	if lp.charging() {
+		if rt, ok := lp.chargeRater.(*wrapper.ChargeRater); ok {
+			rt.StartCharge(false)
+		}
+		if ct, ok := lp.chargeTimer.(*wrapper.ChargeTimer); ok {
+			ct.StartCharge(false)
+		}
+	}
+}
@@ -0,0 +1,178 @@
+package core
+
+import (
+	"github.com/andig/evcc/server/db"
+)
+
+// title returns the loadpoint name used for identifying sessions
+func (lp *LoadPoint) title() string {
+	if lp.Title != "" {
+		return lp.Title
+	}
+	return lp.log.Name()
+}
+
+// openSession is the persisted state of the current charging session
+type openSession struct {
+	Session       db.Session
+	SoCStarted    bool    // Session start soc has been recorded
+	ChargedEnergy float64 // Wh
+	SolarEnergy   float64 // Wh
+	SessionEnergy float64 // Wh
+	Cost          float64
+}
+
+// startSession creates a new charging session when a vehicle connects
+func (lp *LoadPoint) startSession() {
+	lp.session = &db.Session{
+		Created:   lp.clock.Now(),
+		Site:      lp.siteID,
+		LoadPoint: lp.title(),
+	}
+	lp.sessionSoCStarted = false
+	lp.sessionOffset = 0
+	lp.sessionResumed = false
+
+	lp.persistSession()
+}
+
+// sessionChargedEnergy returns the session's charged energy including energy charged before restart in Wh
+func (lp *LoadPoint) sessionChargedEnergy() float64 {
+	return lp.sessionOffset + lp.chargedEnergy
+}
+
+// persistSession stores the open session to survive restarts
+func (lp *LoadPoint) persistSession() {
+	if lp.session == nil {
+		return
+	}
+
+	lp.saveSetting("session", openSession{
+		Session:       *lp.session,
+		SoCStarted:    lp.sessionSoCStarted,
+		ChargedEnergy: lp.sessionChargedEnergy(),
+		SolarEnergy:   lp.chargedSolarEnergy,
+		SessionEnergy: lp.sessionEnergy,
+		Cost:          lp.sessionCost,
+	})
+}
+
+// restoreSession restores the session open before restart. The charged energy is
+// continued from the charge rater's first reading.
+func (lp *LoadPoint) restoreSession() {
+	var saved openSession
+	if !loadSetting(lp.log, lp.settings, "session", &saved) {
+		return
+	}
+
+	lp.session = &saved.Session
+	lp.sessionSoCStarted = saved.SoCStarted
+	lp.sessionOffset = saved.ChargedEnergy
+	lp.sessionResumed = true
+	lp.chargedSolarEnergy = saved.SolarEnergy
+	lp.sessionEnergy = saved.SessionEnergy
+	lp.sessionCost = saved.Cost
+}
+
+// resumeSession continues a restored session's energy from the charge rater's current reading
+func (lp *LoadPoint) resumeSession() {
+	if !lp.sessionResumed {
+		return
+	}
+
+	lp.sessionResumed = false
+	lp.sessionOffset -= lp.chargedEnergy
+	lp.accountedEnergy = lp.chargedEnergy
+}
+
+// updateSessionSoC records the session's start and end soc
+func (lp *LoadPoint) updateSessionSoC(soc float64) {
+	if lp.session == nil {
+		return
+	}
+
+	// persist on change only
+	if lp.sessionSoCStarted && lp.session.SoCEnd == soc {
+		return
+	}
+
+	if !lp.sessionSoCStarted {
+		lp.session.SoCStart = soc
+		lp.sessionSoCStarted = true
+	}
+	lp.session.SoCEnd = soc
+
+	lp.persistSession()
+}
+
+// finishSession completes the current charging session and persists it
+func (lp *LoadPoint) finishSession() {
+	if lp.session == nil {
+		return
+	}
+
+	s := lp.session
+	lp.session = nil
+
+	// persist charge curve samples of the last bucket
+	if lp.chargeCurveChanged && lp.socEstimator != nil {
+		lp.saveChargeCurve()
+	}
+
+	s.Finished = lp.clock.Now()
+	s.ChargedEnergy = lp.sessionChargedEnergy() / 1e3
+	s.SolarPercentage = lp.solarPercentage()
+	s.Cost = lp.sessionCost
+	s.Mode = lp.GetMode()
+	if lp.vehicle != nil {
+		s.Vehicle = lp.vehicle.Title()
+	}
+
+	lp.sessionOffset = 0
+	lp.sessionResumed = false
+
+	if err := lp.settings.Delete("session"); err != nil {
+		lp.log.ERROR.Printf("remove session: %v", err)
+	}
+
+	if db.Instance == nil {
+		return
+	}
+
+	if err := db.Instance.PersistSession(s); err != nil {
+		lp.log.ERROR.Printf("persist session: %v", err)
+	}
+}
+
+// solarPercentage returns the percentage of charged energy covered by pv surplus
+func (lp *LoadPoint) solarPercentage() float64 {
+	if lp.sessionEnergy <= 0 {
+		return 0
+	}
+	return 100 * lp.chargedSolarEnergy / lp.sessionEnergy
+}
+
+// updateSessionEnergy attributes the energy charged since the last update to pv surplus and grid
+// using the site's solar share. Solar energy is valued at the feed-in price.
+func (lp *LoadPoint) updateSessionEnergy(solarShare, gridPrice, feedInPrice float64) {
+	energy := lp.chargedEnergy - lp.accountedEnergy
+	if energy < 0 {
+		energy = lp.chargedEnergy // charge rater has been reset
+	}
+
+	solar := energy * solarShare
+	lp.chargedSolarEnergy += solar
+	lp.sessionEnergy += energy
+	lp.sessionCost += (solar*feedInPrice + (energy-solar)*gridPrice) / 1e3
+
+	lp.accountedEnergy = lp.chargedEnergy
+
+	lp.publishSessionEnergy()
+}
+
+// publishSessionEnergy publishes solar share and cost of the current session
+func (lp *LoadPoint) publishSessionEnergy() {
+	lp.publish("chargedSolarEnergy", lp.chargedSolarEnergy)
+	lp.publish("solarPercentage", lp.solarPercentage())
+	lp.publish("sessionCost", lp.sessionCost)
+}
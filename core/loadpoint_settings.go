@@ -0,0 +1,213 @@
+package core
+
+import (
+	"errors"
+	"time"
+
+	"github.com/andig/evcc/api"
+	"github.com/andig/evcc/server/db"
+	"github.com/andig/evcc/util"
+)
+
+// targetCharge is the persisted target charging request
+type targetCharge struct {
+	Time time.Time `json:"time"`
+	SoC  int       `json:"soc"`
+}
+
+// loadSetting restores a persisted setting and returns true if found
+func loadSetting(log *util.Logger, settings *db.Settings, key string, val interface{}) bool {
+	err := settings.Load(key, val)
+	if err != nil && !errors.Is(err, db.ErrNotFound) {
+		log.ERROR.Printf("restore %s: %v", key, err)
+	}
+	return err == nil
+}
+
+// restoreSettings overrides configured defaults with persisted runtime settings
+func (lp *LoadPoint) restoreSettings() {
+	if db.Instance == nil {
+		return
+	}
+
+	lp.settings = db.Instance.Settings(lp.settingsScope())
+	lp.restored = make(map[string]bool)
+
+	var mode api.ChargeMode
+	if loadSetting(lp.log, lp.settings, "mode", &mode) && api.ChargeModeString(string(mode)) != "" {
+		lp.Mode = api.ChargeModeString(string(mode))
+	}
+
+	var soc int
+	if loadSetting(lp.log, lp.settings, "targetSoC", &soc) {
+		lp.SoC.Target = soc
+		lp.restored["targetSoC"] = true
+	}
+	if loadSetting(lp.log, lp.settings, "minSoC", &soc) {
+		lp.SoC.Min = soc
+		lp.restored["minSoC"] = true
+	}
+
+	var tc targetCharge
+	if loadSetting(lp.log, lp.settings, "targetCharge", &tc) && tc.Time.After(lp.clock.Now()) {
+		lp.socTimer.Time = tc.Time
+		lp.socTimer.SoC = tc.SoC
+	}
+
+	var departure time.Time
+	if loadSetting(lp.log, lp.settings, "departureTime", &departure) && departure.After(lp.clock.Now()) {
+		lp.departureTime = departure
+	}
+
+	var starts []time.Time
+	if loadSetting(lp.log, lp.settings, "switchStarts", &starts) {
+		lp.switchStarts = starts
+		lp.switchCycles() // drop expired cycles
+	}
+
+	lp.restoreSession()
+}
+
+// settingsScope returns the scope of the loadpoint's runtime settings, namespaced by site id
+func (lp *LoadPoint) settingsScope() string {
+	id := lp.ID
+	if id == "" {
+		id = lp.log.Name()
+	}
+
+	if lp.siteID != "" {
+		return "site." + lp.siteID + ".loadpoint." + id
+	}
+	return "loadpoint." + id
+}
+
+// restoreVehicleSettings restores the active vehicle's persisted soc settings
+func (lp *LoadPoint) restoreVehicleSettings() {
+	settings := lp.vehicleSettings()
+	if settings == nil {
+		return
+	}
+
+	lp.Lock()
+	defer lp.Unlock()
+
+	var soc int
+	if loadSetting(lp.log, settings, "targetSoC", &soc) {
+		lp.SoC.Target = soc
+		lp.publish("targetSoC", soc)
+	}
+	if loadSetting(lp.log, settings, "minSoC", &soc) {
+		lp.SoC.Min = soc
+		lp.publish("minSoC", soc)
+	}
+
+	var energy float64
+	if loadSetting(lp.log, settings, "energyPerSocStep", &energy) && energy > 0 && lp.socEstimator != nil {
+		lp.log.DEBUG.Printf("vehicle energy per soc: %.0fWh", energy)
+		lp.socEstimator.SetEnergyPerSocStep(energy)
+	}
+
+	var curve api.ChargeCurve
+	if loadSetting(lp.log, settings, "chargeCurve", &curve) && lp.socEstimator != nil {
+		lp.socEstimator.SetLearnedChargeCurve(curve)
+	}
+}
+
+// vehicleSettings returns the active vehicle's settings, keyed by the vehicle's configuration name
+func (lp *LoadPoint) vehicleSettings() *db.Settings {
+	if db.Instance == nil || lp.vehicle == nil {
+		return nil
+	}
+	return db.Instance.Settings("vehicle." + lp.vehicleRef())
+}
+
+// vehicleRef returns the active vehicle's configuration name, falling back to its title if unknown
+func (lp *LoadPoint) vehicleRef() string {
+	for i, vehicle := range lp.vehicles {
+		if vehicle == lp.vehicle && i < len(lp.vehicleRefs) {
+			return lp.vehicleRefs[i]
+		}
+	}
+	return lp.vehicle.Title()
+}
+
+// saveSetting persists a runtime setting
+func (lp *LoadPoint) saveSetting(key string, val interface{}) {
+	if err := lp.settings.Save(key, val); err != nil {
+		lp.log.ERROR.Printf("persist %s: %v", key, err)
+	}
+}
+
+// saveVehicleCharacteristic persists a learned charge characteristic of the active vehicle
+func (lp *LoadPoint) saveVehicleCharacteristic(key string, val interface{}) {
+	if err := lp.vehicleSettings().Save(key, val); err != nil {
+		lp.log.ERROR.Printf("persist %s: %v", key, err)
+	}
+}
+
+// saveVehicleSetting persists a runtime setting for both loadpoint and active vehicle
+func (lp *LoadPoint) saveVehicleSetting(key string, val interface{}) {
+	lp.saveSetting(key, val)
+
+	if err := lp.vehicleSettings().Save(key, val); err != nil {
+		lp.log.ERROR.Printf("persist %s: %v", key, err)
+	}
+}
+
+// mergeAction returns the action with unset values taken from fallback
+func mergeAction(action, fallback api.ActionConfig) api.ActionConfig {
+	if action.Mode == "" {
+		action.Mode = fallback.Mode
+	}
+	if action.MinSoC == 0 {
+		action.MinSoC = fallback.MinSoC
+	}
+	if action.TargetSoC == 0 {
+		action.TargetSoC = fallback.TargetSoC
+	}
+	return action
+}
+
+// vehicleDefaults returns the active vehicle's defaults falling back to the loadpoint configuration
+func (lp *LoadPoint) vehicleDefaults() api.VehicleDefaults {
+	var res api.VehicleDefaults
+	if lp.vehicle != nil {
+		res = lp.vehicle.Defaults()
+	}
+
+	res.ActionConfig = mergeAction(res.ActionConfig, lp.defaults)
+	res.OnDisconnect = mergeAction(res.OnDisconnect, lp.OnDisconnect)
+
+	return res
+}
+
+// applyVehicleDefaults applies the active vehicle's mode and soc defaults.
+// On startup, the mode and soc settings restored from the loadpoint's runtime settings are kept.
+// Persisted vehicle settings are restored afterwards and take precedence.
+func (lp *LoadPoint) applyVehicleDefaults(changed bool) {
+	defaults := lp.vehicleDefaults()
+
+	lp.Lock()
+	defer lp.Unlock()
+
+	if changed && defaults.Mode != "" && lp.Mode != defaults.Mode {
+		lp.log.DEBUG.Printf("vehicle default mode: %s", defaults.Mode)
+		lp.checkCheapest(defaults.Mode)
+		lp.Mode = defaults.Mode
+		lp.publish("mode", lp.Mode)
+		lp.saveSetting("mode", lp.Mode)
+	}
+
+	// min soc of zero is valid
+	if (changed || !lp.restored["minSoC"]) && lp.SoC.Min != defaults.MinSoC {
+		lp.SoC.Min = defaults.MinSoC
+		lp.publish("minSoC", lp.SoC.Min)
+		lp.saveSetting("minSoC", lp.SoC.Min)
+	}
+
+	if (changed || !lp.restored["targetSoC"]) && defaults.TargetSoC != 0 && lp.SoC.Target != defaults.TargetSoC {
+		lp.SoC.Target = defaults.TargetSoC
+		lp.publish("targetSoC", lp.SoC.Target)
+		lp.saveSetting("targetSoC", lp.SoC.Target)
+	}
+}
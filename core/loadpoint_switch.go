@@ -0,0 +1,69 @@
+package core
+
+import (
+	"time"
+)
+
+// SwitchConfig limits how often the charger is enabled and disabled
+type SwitchConfig struct {
+	MaxPerHour int           `mapstructure:"maxPerHour"` // Maximum switch cycles within the last hour, zero is unlimited
+	MaxPerDay  int           `mapstructure:"maxPerDay"`  // Maximum switch cycles within the last 24 hours, zero is unlimited
+	MinCharge  time.Duration `mapstructure:"minCharge"`  // Minimum duration charging once enabled, defaults to guard duration
+	MinPause   time.Duration `mapstructure:"minPause"`   // Minimum duration paused once disabled, defaults to guard duration
+}
+
+// switchHold returns the remaining time before the charger may be switched to the given state
+func (lp *LoadPoint) switchHold(enable bool) time.Duration {
+	hold := lp.Switch.MinCharge
+	if enable {
+		hold = lp.Switch.MinPause
+	}
+
+	if hold == 0 {
+		hold = lp.GuardDuration
+	}
+
+	return (hold - lp.clock.Since(lp.guardUpdated)).Truncate(time.Second)
+}
+
+// switchCycles returns the number of switch cycles within the last hour and day.
+// A switch cycle starts when the charger is enabled.
+func (lp *LoadPoint) switchCycles() (hour, day int) {
+	now := lp.clock.Now()
+
+	// drop cycles older than a day
+	var i int
+	for i < len(lp.switchStarts) && now.Sub(lp.switchStarts[i]) >= 24*time.Hour {
+		i++
+	}
+	lp.switchStarts = lp.switchStarts[i:]
+
+	for _, ts := range lp.switchStarts {
+		if now.Sub(ts) < time.Hour {
+			hour++
+		}
+	}
+
+	return hour, len(lp.switchStarts)
+}
+
+// addSwitchStart records and persists the start of a switch cycle so that limits survive restarts
+func (lp *LoadPoint) addSwitchStart(ts time.Time) {
+	lp.switchCycles() // drop expired cycles before persisting
+	lp.switchStarts = append(lp.switchStarts, ts)
+	lp.saveSetting("switchStarts", lp.switchStarts)
+}
+
+// switchBudgetExhausted returns true if enabling the charger would exceed the switch cycle limits
+func (lp *LoadPoint) switchBudgetExhausted() bool {
+	hour, day := lp.switchCycles()
+	return lp.Switch.MaxPerHour > 0 && hour >= lp.Switch.MaxPerHour ||
+		lp.Switch.MaxPerDay > 0 && day >= lp.Switch.MaxPerDay
+}
+
+// publishSwitchCycles publishes the number of switch cycles within the last hour and day
+func (lp *LoadPoint) publishSwitchCycles() {
+	hour, day := lp.switchCycles()
+	lp.publish("switchCyclesHour", hour)
+	lp.publish("switchCyclesDay", day)
+}
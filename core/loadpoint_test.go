@@ -1,6 +1,9 @@
 package core
 
 import (
+	"errors"
+	"math"
+	"path/filepath"
 	"testing"
 	"time"
 
@@ -8,6 +11,7 @@ import (
 	"github.com/andig/evcc/core/soc"
 	"github.com/andig/evcc/mock"
 	"github.com/andig/evcc/push"
+	"github.com/andig/evcc/server/db"
 	"github.com/andig/evcc/util"
 	evbus "github.com/asaskevich/EventBus"
 	"github.com/benbjohnson/clock"
@@ -168,7 +172,7 @@ func TestUpdatePowerZero(t *testing.T) {
 		}
 
 		lp.Mode = tc.mode
-		lp.Update(0) // sitePower 0
+		lp.Update(0, noCurrentLimit) // sitePower 0
 
 		ctrl.Finish()
 	}
@@ -399,7 +403,7 @@ func TestDisableAndEnableAtTargetSoC(t *testing.T) {
 	charger.EXPECT().Status().Return(api.StatusC, nil)
 	charger.EXPECT().Enabled().Return(lp.enabled, nil)
 	charger.EXPECT().MaxCurrent(maxA).Return(nil)
-	lp.Update(500)
+	lp.Update(500, noCurrentLimit)
 
 	t.Log("charging above target - soc deactivates charger")
 	clock.Add(5 * time.Minute)
@@ -407,20 +411,20 @@ func TestDisableAndEnableAtTargetSoC(t *testing.T) {
 	charger.EXPECT().Status().Return(api.StatusC, nil)
 	charger.EXPECT().Enabled().Return(lp.enabled, nil)
 	charger.EXPECT().Enable(false).Return(nil)
-	lp.Update(500)
+	lp.Update(500, noCurrentLimit)
 
 	t.Log("deactivated charger changes status to B")
 	clock.Add(5 * time.Minute)
 	vehicle.EXPECT().SoC().Return(95.0, nil)
 	charger.EXPECT().Status().Return(api.StatusB, nil)
 	charger.EXPECT().Enabled().Return(lp.enabled, nil)
-	lp.Update(-5000)
+	lp.Update(-5000, noCurrentLimit)
 
 	t.Log("soc has fallen below target - soc update prevented by timer")
 	clock.Add(5 * time.Minute)
 	charger.EXPECT().Status().Return(api.StatusB, nil)
 	charger.EXPECT().Enabled().Return(lp.enabled, nil)
-	lp.Update(-5000)
+	lp.Update(-5000, noCurrentLimit)
 
 	t.Log("soc has fallen below target - soc update timer expired")
 	clock.Add(pollInterval)
@@ -428,7 +432,7 @@ func TestDisableAndEnableAtTargetSoC(t *testing.T) {
 	charger.EXPECT().Status().Return(api.StatusB, nil)
 	charger.EXPECT().Enabled().Return(lp.enabled, nil)
 	charger.EXPECT().Enable(true).Return(nil)
-	lp.Update(-5000)
+	lp.Update(-5000, noCurrentLimit)
 
 	ctrl.Finish()
 }
@@ -449,10 +453,7 @@ func TestSetModeAndSocAtDisconnect(t *testing.T) {
 		MinCurrent:  minA,
 		MaxCurrent:  maxA,
 		status:      api.StatusC,
-		OnDisconnect: struct {
-			Mode      api.ChargeMode `mapstructure:"mode"`      // Charge mode to apply when car disconnected
-			TargetSoC int            `mapstructure:"targetSoC"` // Target SoC to apply when car disconnected
-		}{
+		OnDisconnect: api.ActionConfig{
 			Mode:      api.ModeOff,
 			TargetSoC: 70,
 		},
@@ -468,14 +469,14 @@ func TestSetModeAndSocAtDisconnect(t *testing.T) {
 	charger.EXPECT().Enabled().Return(lp.enabled, nil)
 	charger.EXPECT().Status().Return(api.StatusC, nil)
 	charger.EXPECT().MaxCurrent(maxA).Return(nil)
-	lp.Update(500)
+	lp.Update(500, noCurrentLimit)
 
 	t.Log("switch off when disconnected")
 	clock.Add(5 * time.Minute)
 	charger.EXPECT().Enabled().Return(lp.enabled, nil)
 	charger.EXPECT().Status().Return(api.StatusA, nil)
 	charger.EXPECT().Enable(false).Return(nil)
-	lp.Update(-3000)
+	lp.Update(-3000, noCurrentLimit)
 
 	if lp.Mode != api.ModeOff {
 		t.Error("unexpected mode", lp.Mode)
@@ -536,14 +537,14 @@ func TestChargedEnergyAtDisconnect(t *testing.T) {
 	rater.EXPECT().ChargedEnergy().Return(0.0, nil)
 	charger.EXPECT().Enabled().Return(lp.enabled, nil)
 	charger.EXPECT().Status().Return(api.StatusC, nil)
-	lp.Update(-1)
+	lp.Update(-1, noCurrentLimit)
 
 	t.Log("at 1:00h charging at 5 kWh")
 	clock.Add(time.Hour)
 	rater.EXPECT().ChargedEnergy().Return(5.0, nil)
 	charger.EXPECT().Enabled().Return(lp.enabled, nil)
 	charger.EXPECT().Status().Return(api.StatusC, nil)
-	lp.Update(-1)
+	lp.Update(-1, noCurrentLimit)
 	expectCache("chargedEnergy", 5000.0)
 
 	t.Log("at 1:00h stop charging at 5 kWh")
@@ -551,7 +552,7 @@ func TestChargedEnergyAtDisconnect(t *testing.T) {
 	rater.EXPECT().ChargedEnergy().Return(5.0, nil)
 	charger.EXPECT().Enabled().Return(lp.enabled, nil)
 	charger.EXPECT().Status().Return(api.StatusB, nil)
-	lp.Update(-1)
+	lp.Update(-1, noCurrentLimit)
 	expectCache("chargedEnergy", 5000.0)
 
 	t.Log("at 1:00h restart charging at 5 kWh")
@@ -559,7 +560,7 @@ func TestChargedEnergyAtDisconnect(t *testing.T) {
 	rater.EXPECT().ChargedEnergy().Return(5.0, nil)
 	charger.EXPECT().Enabled().Return(lp.enabled, nil)
 	charger.EXPECT().Status().Return(api.StatusC, nil)
-	lp.Update(-1)
+	lp.Update(-1, noCurrentLimit)
 	expectCache("chargedEnergy", 5000.0)
 
 	t.Log("at 1:30h continue charging at 7.5 kWh")
@@ -567,7 +568,7 @@ func TestChargedEnergyAtDisconnect(t *testing.T) {
 	rater.EXPECT().ChargedEnergy().Return(7.5, nil)
 	charger.EXPECT().Enabled().Return(lp.enabled, nil)
 	charger.EXPECT().Status().Return(api.StatusC, nil)
-	lp.Update(-1)
+	lp.Update(-1, noCurrentLimit)
 	expectCache("chargedEnergy", 7500.0)
 
 	t.Log("at 2:00h stop charging at 10 kWh")
@@ -575,7 +576,7 @@ func TestChargedEnergyAtDisconnect(t *testing.T) {
 	rater.EXPECT().ChargedEnergy().Return(10.0, nil)
 	charger.EXPECT().Enabled().Return(lp.enabled, nil)
 	charger.EXPECT().Status().Return(api.StatusB, nil)
-	lp.Update(-1)
+	lp.Update(-1, noCurrentLimit)
 	expectCache("chargedEnergy", 10000.0)
 
 	ctrl.Finish()
@@ -736,3 +737,1039 @@ func TestMinSoC(t *testing.T) {
 		}
 	}
 }
+
+func TestSiteCurrentLimit(t *testing.T) {
+	tc := []struct {
+		available float64
+		expect    func(h *mock.MockCharger)
+	}{
+		{noCurrentLimit, func(h *mock.MockCharger) {
+			h.EXPECT().MaxCurrent(maxA)
+		}},
+		{20, func(h *mock.MockCharger) {
+			h.EXPECT().MaxCurrent(maxA)
+		}},
+		{4, func(h *mock.MockCharger) {
+			h.EXPECT().MaxCurrent(int64(10)) // minA + 4A
+		}},
+		{0, func(h *mock.MockCharger) {
+			// MaxCurrent omitted since identical value
+		}},
+		{-2, func(h *mock.MockCharger) {
+			h.EXPECT().Enable(false) // below minA, ignore contactor delay
+		}},
+	}
+
+	for _, tc := range tc {
+		t.Log(tc)
+
+		clck := clock.NewMock()
+		ctrl := gomock.NewController(t)
+		charger := mock.NewMockCharger(ctrl)
+
+		lp := &LoadPoint{
+			log:           util.NewLogger("foo"),
+			bus:           evbus.New(),
+			clock:         clck,
+			charger:       charger,
+			chargeMeter:   &Null{}, // silence nil panics
+			chargeRater:   &Null{}, // silence nil panics
+			chargeTimer:   &Null{}, // silence nil panics
+			MinCurrent:    minA,
+			MaxCurrent:    maxA,
+			Phases:        1,
+			GuardDuration: time.Hour,
+			status:        api.StatusC, // no status change
+			Mode:          api.ModeNow,
+		}
+
+		attachListeners(t, lp)
+
+		charger.EXPECT().Status().Return(api.StatusC, nil)
+		charger.EXPECT().Enabled().Return(true, nil)
+		tc.expect(charger)
+
+		lp.Update(0, tc.available)
+
+		ctrl.Finish()
+	}
+}
+
+func TestPVScalePhases(t *testing.T) {
+	dt := time.Minute
+
+	tc := []struct {
+		phases, maxPhases int64
+		chargePower       float64
+		sitePower         float64
+		switched          int64 // expected phases after delay, zero if not switched
+	}{
+		{3, 3, 0, -1000, 1},    // 3p: below 3p minimum
+		{3, 3, 4140, 0, 0},     // 3p: at 3p minimum
+		{3, 3, 4140, 1000, 1},  // 3p: grid import
+		{1, 3, 1380, -2000, 0}, // 1p: below 3p minimum
+		{1, 3, 1380, -3000, 3}, // 1p: above 3p minimum
+		{1, 3, 3680, -460, 3},  // 1p: above 3p minimum at higher current
+		{1, 3, 3680, 1000, 0},  // 1p: grid import
+		{3, 3, 0, -5000, 0},    // 3p: above 3p minimum
+		{1, 1, 1380, -3000, 0}, // configured 1p: above 3p minimum
+	}
+
+	for _, tc := range tc {
+		t.Logf("%+v", tc)
+
+		clck := clock.NewMock()
+		ctrl := gomock.NewController(t)
+
+		charger := &struct {
+			*mock.MockCharger
+			*mock.MockChargePhases
+		}{
+			mock.NewMockCharger(ctrl),
+			mock.NewMockChargePhases(ctrl),
+		}
+
+		Voltage = 230 // V
+
+		lp := &LoadPoint{
+			log:           util.NewLogger("foo"),
+			clock:         clck,
+			charger:       charger,
+			MinCurrent:    minA,
+			MaxCurrent:    maxA,
+			Phases:        tc.phases,
+			chargerPhases: tc.phases,
+			maxPhases:     tc.maxPhases,
+			chargePower:   tc.chargePower,
+			Enable:        ThresholdConfig{Delay: dt},
+			Disable:       ThresholdConfig{Delay: dt},
+		}
+
+		// timer started
+		if lp.pvScalePhases(tc.sitePower) {
+			t.Error("unexpected switch before delay")
+		}
+
+		clck.Add(dt)
+
+		if tc.switched > 0 {
+			charger.MockChargePhases.EXPECT().Phases1p3p(int(tc.switched)).Return(nil)
+		}
+
+		if res := lp.pvScalePhases(tc.sitePower); res != (tc.switched > 0) {
+			t.Errorf("expected switched %v, got %v", tc.switched > 0, res)
+		}
+
+		if tc.switched > 0 && lp.Phases != tc.switched {
+			t.Errorf("expected %dp, got %dp", tc.switched, lp.Phases)
+		}
+
+		ctrl.Finish()
+	}
+}
+
+func TestPVScalePhasesCharging(t *testing.T) {
+	clck := clock.NewMock()
+	ctrl := gomock.NewController(t)
+
+	charger := &struct {
+		*mock.MockCharger
+		*mock.MockChargePhases
+	}{
+		mock.NewMockCharger(ctrl),
+		mock.NewMockChargePhases(ctrl),
+	}
+
+	Voltage = 230 // V
+
+	lp := &LoadPoint{
+		log:           util.NewLogger("foo"),
+		clock:         clck,
+		charger:       charger,
+		MinCurrent:    minA,
+		MaxCurrent:    maxA,
+		Phases:        3,
+		chargerPhases: 3,
+		maxPhases:     3,
+		enabled:       true,
+		status:        api.StatusC,
+		chargePower:   3000,
+	}
+
+	// contactors are switched without load
+	gomock.InOrder(
+		charger.MockCharger.EXPECT().Enable(false).Return(nil),
+		charger.MockChargePhases.EXPECT().Phases1p3p(1).Return(nil),
+		charger.MockCharger.EXPECT().Enable(true).Return(nil),
+	)
+
+	if !lp.pvScalePhases(0) {
+		t.Error("expected phase switch")
+	}
+
+	if !lp.enabled || lp.Phases != 1 {
+		t.Errorf("expected enabled at 1p, got %v at %dp", lp.enabled, lp.Phases)
+	}
+
+	// configured phases restored outside pv modes
+	gomock.InOrder(
+		charger.MockCharger.EXPECT().Enable(false).Return(nil),
+		charger.MockChargePhases.EXPECT().Phases1p3p(3).Return(nil),
+		charger.MockCharger.EXPECT().Enable(true).Return(nil),
+	)
+
+	lp.restorePhases()
+	if lp.Phases != 3 {
+		t.Errorf("expected 3p, got %dp", lp.Phases)
+	}
+
+	// nothing to restore
+	lp.restorePhases()
+
+	ctrl.Finish()
+}
+
+func TestRestoreSettings(t *testing.T) {
+	store, err := db.New(filepath.Join(t.TempDir(), "evcc.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	db.Instance = store
+	defer func() {
+		db.Instance = nil
+		store.Close()
+	}()
+
+	ctrl := gomock.NewController(t)
+	vhc := mock.NewMockVehicle(ctrl)
+	vhc.EXPECT().Title().Return("Zoe").AnyTimes()
+	vhc.EXPECT().Defaults().Return(api.VehicleDefaults{
+		ActionConfig: api.ActionConfig{TargetSoC: 60},
+	}).AnyTimes()
+
+	newLoadPoint := func() *LoadPoint {
+		lp := NewLoadPoint(util.NewLogger("lp-1"))
+		lp.SoC.Target = 100
+		lp.socTimer = soc.NewTimer(lp.log, lp.adapter(), lp.MaxCurrent)
+		lp.restoreSettings()
+		return lp
+	}
+
+	// change settings at runtime
+	lp := newLoadPoint()
+	lp.vehicle = vhc
+
+	finishAt := time.Now().Add(time.Hour).Round(time.Second)
+	lp.SetMode(api.ModePV)
+	_ = lp.SetTargetSoC(80)
+	lp.SetTargetCharge(finishAt, 90)
+	lp.addSwitchStart(lp.clock.Now())
+
+	// restart
+	lp = newLoadPoint()
+	if lp.Mode != api.ModePV {
+		t.Errorf("expected mode %s, got %s", api.ModePV, lp.Mode)
+	}
+	if lp.SoC.Target != 80 {
+		t.Errorf("expected target soc 80, got %d", lp.SoC.Target)
+	}
+	if !lp.socTimer.Time.Equal(finishAt) || lp.socTimer.SoC != 90 {
+		t.Errorf("expected target charge 90 @ %v, got %d @ %v", finishAt, lp.socTimer.SoC, lp.socTimer.Time)
+	}
+	if _, day := lp.switchCycles(); day != 1 {
+		t.Errorf("expected 1 switch cycle, got %d", day)
+	}
+
+	// vehicle defaults keep restored settings on startup
+	lp.vehicle = vhc
+	lp.applyVehicleDefaults(false)
+	if lp.SoC.Target != 80 {
+		t.Errorf("expected restored target soc 80, got %d", lp.SoC.Target)
+	}
+
+	// settings are scoped by loadpoint id
+	other := NewLoadPoint(util.NewLogger("lp-1"))
+	other.ID = "garage"
+	other.restoreSettings()
+	if other.Mode == api.ModePV {
+		t.Errorf("unexpected mode %s restored from other loadpoint", other.Mode)
+	}
+
+	// settings are scoped by site
+	other = NewLoadPoint(util.NewLogger("lp-1"))
+	other.siteID = "north"
+	other.restoreSettings()
+	if other.Mode == api.ModePV {
+		t.Errorf("unexpected mode %s restored from other site", other.Mode)
+	}
+
+	// vehicle settings
+	lp.SoC.Target = 50
+	lp.vehicle = vhc
+	lp.restoreVehicleSettings()
+	if lp.SoC.Target != 80 {
+		t.Errorf("expected vehicle target soc 80, got %d", lp.SoC.Target)
+	}
+
+	// vehicle settings are keyed by configuration name, not title
+	same := mock.NewMockVehicle(ctrl)
+	lp.vehicles = []api.Vehicle{vhc, same}
+	lp.vehicleRefs = []string{"zoe", "zoe2"}
+
+	lp.vehicle = vhc
+	_ = lp.SetTargetSoC(70)
+
+	lp.SoC.Target = 50
+	lp.vehicle = same
+	lp.restoreVehicleSettings()
+	if lp.SoC.Target != 50 {
+		t.Errorf("unexpected vehicle target soc %d restored from other vehicle", lp.SoC.Target)
+	}
+
+	ctrl.Finish()
+}
+
+func TestIdentifyVehicle(t *testing.T) {
+	tc := []struct {
+		id       string
+		active   int // expected active vehicle
+		identify bool
+	}{
+		{"", 0, false},        // not authorized
+		{"unknown", 0, false}, // unknown tag
+		{"tag-a", 0, true},    // first vehicle
+		{"TAG-B", 1, true},    // second vehicle, case insensitive
+		{"tag-c", 1, true},    // second vehicle, additional tag
+	}
+
+	for _, tc := range tc {
+		t.Logf("%+v", tc)
+
+		ctrl := gomock.NewController(t)
+
+		charger := &struct {
+			*mock.MockCharger
+			*mock.MockIdentifier
+		}{
+			mock.NewMockCharger(ctrl),
+			mock.NewMockIdentifier(ctrl),
+		}
+
+		identifiers := [][]string{{"tag-a"}, {"tag-b", "tag-c"}}
+
+		var vehicles []api.Vehicle
+		for _, ids := range identifiers {
+			vehicle := mock.NewMockVehicle(ctrl)
+			vehicle.EXPECT().Title().Return("target").AnyTimes()
+			vehicle.EXPECT().Capacity().Return(int64(10)).AnyTimes()
+			vehicle.EXPECT().Identifiers().Return(ids).AnyTimes()
+			vehicle.EXPECT().Defaults().Return(api.VehicleDefaults{}).AnyTimes()
+			vehicles = append(vehicles, vehicle)
+		}
+
+		lp := &LoadPoint{
+			log:      util.NewLogger("foo"),
+			clock:    clock.NewMock(),
+			charger:  charger,
+			status:   api.StatusB,
+			vehicle:  vehicles[0],
+			vehicles: vehicles,
+		}
+
+		charger.MockIdentifier.EXPECT().Identify().Return(tc.id, nil)
+
+		if res := lp.identifyVehicle(); res != tc.identify {
+			t.Errorf("expected identified %v, got %v", tc.identify, res)
+		}
+
+		if lp.vehicle != vehicles[tc.active] {
+			t.Errorf("expected vehicle %d", tc.active)
+		}
+
+		if lp.vehicleID != tc.id {
+			t.Errorf("expected identification %s, got %s", tc.id, lp.vehicleID)
+		}
+
+		ctrl.Finish()
+	}
+}
+
+func TestVehicleDefaults(t *testing.T) {
+	ctrl := gomock.NewController(t)
+
+	phev := mock.NewMockVehicle(ctrl)
+	phev.EXPECT().Title().Return("phev").AnyTimes()
+	phev.EXPECT().Capacity().Return(int64(10)).AnyTimes()
+	phev.EXPECT().Defaults().Return(api.VehicleDefaults{
+		ActionConfig: api.ActionConfig{Mode: api.ModeNow, MinSoC: 20, TargetSoC: 100},
+		OnDisconnect: api.ActionConfig{TargetSoC: 90},
+	}).AnyTimes()
+
+	bev := mock.NewMockVehicle(ctrl)
+	bev.EXPECT().Title().Return("bev").AnyTimes()
+	bev.EXPECT().Capacity().Return(int64(80)).AnyTimes()
+	bev.EXPECT().Defaults().Return(api.VehicleDefaults{}).AnyTimes()
+
+	lp := &LoadPoint{
+		log:          util.NewLogger("foo"),
+		clock:        clock.NewMock(),
+		Mode:         api.ModeOff,
+		defaults:     api.ActionConfig{Mode: api.ModePV, MinSoC: 0, TargetSoC: 80},
+		OnDisconnect: api.ActionConfig{Mode: api.ModePV},
+		vehicles:     []api.Vehicle{phev, bev},
+	}
+
+	check := func(mode api.ChargeMode, min, target int) {
+		t.Helper()
+		if lp.Mode != mode || lp.SoC.Min != min || lp.SoC.Target != target {
+			t.Errorf("expected %s/%d%%/%d%%, got %s/%d%%/%d%%", mode, min, target, lp.Mode, lp.SoC.Min, lp.SoC.Target)
+		}
+	}
+
+	// startup keeps mode
+	lp.setActiveVehicle(phev)
+	check(api.ModeOff, 20, 100)
+
+	// vehicle without defaults uses loadpoint configuration
+	lp.setActiveVehicle(bev)
+	check(api.ModePV, 0, 80)
+
+	lp.setActiveVehicle(phev)
+	check(api.ModeNow, 20, 100)
+
+	if res := lp.vehicleDefaults().OnDisconnect; res != (api.ActionConfig{Mode: api.ModePV, TargetSoC: 90}) {
+		t.Errorf("unexpected disconnect defaults: %+v", res)
+	}
+
+	ctrl.Finish()
+}
+
+func TestRemotePowerLimit(t *testing.T) {
+	tc := []struct {
+		limit, site float64
+		expired     bool
+		expect      func(h *mock.MockCharger)
+	}{
+		{0, 0, false, func(h *mock.MockCharger) {
+			h.EXPECT().MaxCurrent(maxA)
+		}},
+		{2300, 0, false, func(h *mock.MockCharger) {
+			h.EXPECT().MaxCurrent(int64(10))
+		}},
+		{2300, 0, true, func(h *mock.MockCharger) {
+			h.EXPECT().MaxCurrent(maxA) // expired
+		}},
+		{0, 3450, false, func(h *mock.MockCharger) {
+			h.EXPECT().MaxCurrent(int64(15))
+		}},
+		{2300, 3450, false, func(h *mock.MockCharger) {
+			h.EXPECT().MaxCurrent(int64(10)) // lower limit applies
+		}},
+		{2300, 1150, false, func(h *mock.MockCharger) {
+			h.EXPECT().Enable(false) // below minA, ignore contactor delay
+		}},
+	}
+
+	for _, tc := range tc {
+		t.Logf("%+v", tc)
+
+		clck := clock.NewMock()
+		ctrl := gomock.NewController(t)
+		charger := mock.NewMockCharger(ctrl)
+
+		Voltage = 230 // V
+
+		lp := &LoadPoint{
+			log:            util.NewLogger("foo"),
+			bus:            evbus.New(),
+			clock:          clck,
+			charger:        charger,
+			MinCurrent:     minA,
+			MaxCurrent:     maxA,
+			Phases:         1,
+			GuardDuration:  time.Hour,
+			currentLimit:   noCurrentLimit,
+			sitePowerLimit: tc.site,
+			enabled:        true,
+			chargeCurrent:  float64(minA),
+			guardUpdated:   clck.Now(),
+		}
+
+		if tc.limit > 0 {
+			lp.SetRemotePowerLimit("test", tc.limit, time.Minute)
+		}
+
+		if tc.expired {
+			clck.Add(time.Minute)
+		}
+
+		tc.expect(charger)
+
+		if err := lp.setLimit(float64(maxA), true); err != nil {
+			t.Error(err)
+		}
+
+		ctrl.Finish()
+	}
+}
+
+func TestSessionEnergy(t *testing.T) {
+	lp := &LoadPoint{
+		log: util.NewLogger("foo"),
+	}
+
+	steps := []struct {
+		charged, share    float64
+		solar, percentage float64
+		cost              float64
+	}{
+		{1000, 1, 1000, 100, 0.1},  // solar at feed-in price
+		{2000, 0, 1000, 50, 0.4},   // grid at grid price
+		{4000, 0.5, 2000, 50, 0.8}, // mixed
+		{4000, 0, 2000, 50, 0.8},   // not charging
+		{1000, 1, 3000, 60, 0.9},   // charge rater reset
+	}
+
+	for _, s := range steps {
+		t.Logf("%+v", s)
+
+		lp.chargedEnergy = s.charged
+		lp.updateSessionEnergy(s.share, 0.3, 0.1)
+
+		if lp.chargedSolarEnergy != s.solar {
+			t.Errorf("expected solar energy %.0fWh, got %.0fWh", s.solar, lp.chargedSolarEnergy)
+		}
+		if p := lp.solarPercentage(); p != s.percentage {
+			t.Errorf("expected solar percentage %.0f%%, got %.0f%%", s.percentage, p)
+		}
+		if math.Abs(lp.sessionCost-s.cost) > 1e-9 {
+			t.Errorf("expected cost %.2f, got %.2f", s.cost, lp.sessionCost)
+		}
+	}
+}
+
+func TestFailSafe(t *testing.T) {
+	tc := []struct {
+		config  FailSafeConfig
+		trigger int // failed cycle applying fail-safe, zero if never
+		expect  func(h *mock.MockCharger)
+	}{
+		{FailSafeConfig{}, 0, nil},
+		{FailSafeConfig{Action: failSafeKeep}, 1, nil},
+		{FailSafeConfig{Action: failSafeDisable, Cycles: 3}, 3, func(h *mock.MockCharger) {
+			h.EXPECT().Enable(false)
+		}},
+		{FailSafeConfig{Action: failSafeMin, Timeout: time.Minute}, 3, func(h *mock.MockCharger) {
+			h.EXPECT().MaxCurrent(minA)
+		}},
+	}
+
+	for _, tc := range tc {
+		t.Logf("%+v", tc)
+
+		clck := clock.NewMock()
+		ctrl := gomock.NewController(t)
+		charger := mock.NewMockCharger(ctrl)
+		pushChan := make(chan push.Event, 1)
+
+		lp := &LoadPoint{
+			log:           util.NewLogger("foo"),
+			bus:           evbus.New(),
+			clock:         clck,
+			pushChan:      pushChan,
+			charger:       charger,
+			MinCurrent:    minA,
+			MaxCurrent:    maxA,
+			Phases:        1,
+			FailSafe:      tc.config,
+			currentLimit:  noCurrentLimit,
+			enabled:       true,
+			chargeCurrent: float64(maxA),
+		}
+
+		for i := 1; i <= 4; i++ {
+			if i == tc.trigger && tc.expect != nil {
+				tc.expect(charger)
+			}
+
+			lp.fault(errors.New("meter"))
+
+			if applied := tc.trigger > 0 && i >= tc.trigger; lp.failSafe != applied {
+				t.Errorf("cycle %d: expected fail-safe %t, got %t", i, applied, lp.failSafe)
+			}
+
+			clck.Add(30 * time.Second)
+		}
+
+		select {
+		case ev := <-pushChan:
+			if tc.trigger == 0 || ev.Event != evFault {
+				t.Errorf("unexpected event %v", ev)
+			}
+		default:
+			if tc.trigger > 0 {
+				t.Error("missing fault event")
+			}
+		}
+
+		lp.clearFault()
+		if lp.failSafe || lp.failures != 0 {
+			t.Error("fail-safe not released")
+		}
+
+		ctrl.Finish()
+	}
+}
+
+func TestPVFilteredPower(t *testing.T) {
+	tc := []struct {
+		sitePower, enablePower float64
+		current                float64
+	}{
+		{0, 0, 10},         // balanced
+		{-1150, -1150, 15}, // unfiltered
+		{-1150, -460, 12},  // increase limited by enable filter
+		{-1150, 460, 10},   // no increase, enable filter doesn't reduce
+		{460, -1150, 8},    // decrease follows disable filter
+	}
+
+	for _, tc := range tc {
+		t.Logf("%+v", tc)
+
+		Voltage = 230 // V
+
+		lp := &LoadPoint{
+			log:               util.NewLogger("foo"),
+			clock:             clock.NewMock(),
+			MinCurrent:        minA,
+			MaxCurrent:        maxA,
+			Phases:            1,
+			status:            api.StatusC,
+			enabled:           true,
+			chargeCurrent:     10,
+			enablePowerOffset: tc.enablePower - tc.sitePower,
+		}
+
+		if current := lp.pvMaxCurrent(api.ModePV, tc.sitePower); current != tc.current {
+			t.Errorf("expected %.0fA, got %.0fA", tc.current, current)
+		}
+	}
+}
+
+func TestLearnCurrent(t *testing.T) {
+	tc := []struct {
+		measured           float64
+		settled            bool
+		offset, vehicleMax float64
+		effective          float64
+	}{
+		{16, true, 0, 0, 16},            // following commanded current
+		{15, false, 0, 0, 16},           // not settled
+		{15, true, 0.2, 0, 15.8},        // learn offset
+		{10, true, 0, 10, 10},           // vehicle limit
+		{0.1, true, 0, 0, 16},           // not charging
+		{17, true, 0, 0, float64(maxA)}, // offset not negative
+	}
+
+	for _, tc := range tc {
+		t.Logf("%+v", tc)
+
+		clck := clock.NewMock()
+		lp := &LoadPoint{
+			log:           util.NewLogger("foo"),
+			clock:         clck,
+			MinCurrent:    minA,
+			MaxCurrent:    maxA,
+			status:        api.StatusC,
+			enabled:       true,
+			chargeCurrent: float64(maxA),
+		}
+
+		lp.currentUpdated = clck.Now()
+		if tc.settled {
+			clck.Add(currentSettleTime)
+		}
+
+		lp.learnCurrent([]float64{tc.measured, 0, 0})
+
+		if math.Abs(lp.currentOffset-tc.offset) > 1e-6 {
+			t.Errorf("expected offset %.2fA, got %.2fA", tc.offset, lp.currentOffset)
+		}
+		if lp.vehicleMaxCurrent != tc.vehicleMax {
+			t.Errorf("expected vehicle max current %.1fA, got %.1fA", tc.vehicleMax, lp.vehicleMaxCurrent)
+		}
+		if current := lp.effectiveCurrent(); math.Abs(current-tc.effective) > 1e-6 {
+			t.Errorf("expected effective current %.2fA, got %.2fA", tc.effective, current)
+		}
+	}
+}
+
+func TestCommandedCurrent(t *testing.T) {
+	lp := &LoadPoint{
+		MinCurrent:    minA,
+		MaxCurrent:    maxA,
+		currentOffset: 1,
+	}
+
+	tc := []struct {
+		target, max, commanded float64
+	}{
+		{0, float64(maxA), 0},                                     // disabled
+		{float64(minA), float64(maxA), float64(minA) + 1},         // compensated
+		{float64(maxA), float64(maxA), float64(maxA)},             // capped at max current
+		{float64(minA), float64(minA) + 0.5, float64(minA) + 0.5}, // capped at limit
+	}
+
+	for _, tc := range tc {
+		if current := lp.commandedCurrent(tc.target, tc.max); current != tc.commanded {
+			t.Errorf("target %.0fA: expected %.1fA, got %.1fA", tc.target, tc.commanded, current)
+		}
+	}
+
+	// compensated current honours site current limit
+	ctrl := gomock.NewController(t)
+	charger := mock.NewMockCharger(ctrl)
+
+	lp.log = util.NewLogger("foo")
+	lp.clock = clock.NewMock()
+	lp.bus = evbus.New()
+	lp.charger = charger
+	lp.enabled = true
+	lp.currentLimit = 10
+
+	charger.EXPECT().MaxCurrent(int64(10)).Return(nil)
+	if err := lp.setLimit(10, false); err != nil {
+		t.Error(err)
+	}
+
+	ctrl.Finish()
+}
+
+func TestFullPowerOffered(t *testing.T) {
+	tc := []struct {
+		status                api.ChargeStatus
+		current, currentLimit float64
+		phases                int64
+		powerLimit            float64
+		expect                bool
+	}{
+		{api.StatusC, float64(maxA), noCurrentLimit, 3, 0, true},
+		{api.StatusB, float64(maxA), noCurrentLimit, 3, 0, false},            // not charging
+		{api.StatusC, float64(minA), noCurrentLimit, 3, 0, false},            // reduced current
+		{api.StatusC, float64(maxA), noCurrentLimit, 1, 0, false},            // switched to 1p
+		{api.StatusC, float64(maxA), float64(maxA) - 1, 3, 0, false},         // site current limit
+		{api.StatusC, float64(maxA), noCurrentLimit, 3, 3 * 230 * 10, false}, // remote power limit
+		{api.StatusC, float64(maxA), noCurrentLimit, 3, 3 * 230 * float64(maxA), true},
+	}
+
+	for _, tc := range tc {
+		t.Logf("%+v", tc)
+
+		lp := &LoadPoint{
+			MaxCurrent:     maxA,
+			Phases:         tc.phases,
+			status:         tc.status,
+			chargeCurrent:  tc.current,
+			currentLimit:   tc.currentLimit,
+			sitePowerLimit: tc.powerLimit,
+			chargerPhases:  tc.phases,
+			maxPhases:      3,
+		}
+
+		if res := lp.fullPowerOffered(); res != tc.expect {
+			t.Errorf("expected %v, got %v", tc.expect, res)
+		}
+	}
+}
+
+func TestChargeLimit(t *testing.T) {
+	ctrl := gomock.NewController(t)
+
+	vhc := &struct {
+		*mock.MockVehicle
+		*mock.MockVehicleChargeLimit
+	}{
+		mock.NewMockVehicle(ctrl),
+		mock.NewMockVehicleChargeLimit(ctrl),
+	}
+
+	clck := clock.NewMock()
+	lp := &LoadPoint{
+		log:     util.NewLogger("foo"),
+		clock:   clck,
+		vehicle: vhc,
+		status:  api.StatusA,
+		SoC:     SoCConfig{Target: 80},
+	}
+	lp.SoC.Poll.Interval = time.Hour
+
+	// not connected
+	lp.updateChargeLimit()
+
+	// retry after poll interval
+	lp.status = api.StatusB
+	vhc.MockVehicleChargeLimit.EXPECT().SetChargeLimit(80).Return(errors.New("foo"))
+	lp.updateChargeLimit()
+	lp.updateChargeLimit()
+
+	clck.Add(time.Hour)
+	vhc.MockVehicleChargeLimit.EXPECT().SetChargeLimit(80).Return(nil)
+	lp.updateChargeLimit()
+	lp.updateChargeLimit()
+
+	// charging does not resend the limit
+	lp.pushChan = make(chan push.Event, 1)
+	lp.evChargeStartHandler()
+	lp.updateChargeLimit()
+
+	// target changed
+	lp.SoC.Target = 90
+	vhc.MockVehicleChargeLimit.EXPECT().SetChargeLimit(90).Return(nil)
+	lp.updateChargeLimit()
+
+	ctrl.Finish()
+}
+
+func TestPrecondition(t *testing.T) {
+	ctrl := gomock.NewController(t)
+
+	vhc := &struct {
+		*mock.MockVehicle
+		*mock.MockVehicleClimateController
+	}{
+		mock.NewMockVehicle(ctrl),
+		mock.NewMockVehicleClimateController(ctrl),
+	}
+
+	clck := clock.NewMock()
+	lp := &LoadPoint{
+		log:          util.NewLogger("foo"),
+		clock:        clck,
+		vehicle:      vhc,
+		status:       api.StatusB,
+		Precondition: PreconditionConfig{Duration: 15 * time.Minute, Temperature: 21},
+	}
+
+	lp.SetDepartureTime(clck.Now().Add(time.Hour))
+
+	// too early
+	lp.updatePrecondition()
+	if lp.preconditioning {
+		t.Error("unexpected preconditioning")
+	}
+
+	// start before departure
+	clck.Add(50 * time.Minute)
+	vhc.MockVehicleClimateController.EXPECT().StartClimate(21.0).Return(nil)
+	lp.updatePrecondition()
+	if !lp.preconditioning || !lp.climateRequired() {
+		t.Error("expected preconditioning")
+	}
+
+	// keep running
+	lp.updatePrecondition()
+
+	// departure postponed stops climate
+	vhc.MockVehicleClimateController.EXPECT().StopClimate().Return(nil)
+	lp.SetDepartureTime(clck.Now().Add(time.Hour))
+	lp.updatePrecondition()
+	if lp.preconditioning {
+		t.Error("unexpected preconditioning")
+	}
+
+	// departure time reached keeps climate running
+	clck.Add(50 * time.Minute)
+	vhc.MockVehicleClimateController.EXPECT().StartClimate(21.0).Return(nil)
+	lp.updatePrecondition()
+
+	clck.Add(10 * time.Minute)
+	lp.updatePrecondition()
+	if lp.preconditioning || !lp.departureTime.IsZero() {
+		t.Error("expected departure reset")
+	}
+
+	// disconnected
+	lp.SetDepartureTime(clck.Now().Add(10 * time.Minute))
+	lp.status = api.StatusA
+	lp.updatePrecondition()
+	if lp.preconditioning {
+		t.Error("unexpected preconditioning while disconnected")
+	}
+
+	ctrl.Finish()
+}
+
+func TestSwitchBudget(t *testing.T) {
+	clck := clock.NewMock()
+	lp := &LoadPoint{
+		log:          util.NewLogger("foo"),
+		bus:          evbus.New(),
+		clock:        clck,
+		MinCurrent:   minA,
+		MaxCurrent:   maxA,
+		Phases:       1,
+		currentLimit: noCurrentLimit,
+		status:       api.StatusC,
+		Switch: SwitchConfig{
+			MaxPerHour: 2,
+			MinCharge:  10 * time.Minute,
+			MinPause:   5 * time.Minute,
+		},
+	}
+
+	tc := []struct {
+		step    time.Duration
+		current int64
+		force   bool
+		expect  func(h *mock.MockCharger)
+	}{
+		{0, minA, false, func(h *mock.MockCharger) {
+			h.EXPECT().MaxCurrent(int64(minA))
+			h.EXPECT().Enable(true)
+		}},
+		{5 * time.Minute, 0, false, func(h *mock.MockCharger) {
+			// min charge duration
+		}},
+		{5 * time.Minute, 0, false, func(h *mock.MockCharger) {
+			h.EXPECT().Enable(false)
+		}},
+		{4 * time.Minute, maxA, false, func(h *mock.MockCharger) {
+			// min pause duration
+			h.EXPECT().MaxCurrent(int64(maxA))
+		}},
+		{time.Minute, maxA, false, func(h *mock.MockCharger) {
+			h.EXPECT().Enable(true)
+		}},
+		{10 * time.Minute, 0, false, func(h *mock.MockCharger) {
+			// budget exhausted, degrade to min current
+			h.EXPECT().MaxCurrent(int64(minA))
+		}},
+		{0, 0, true, func(h *mock.MockCharger) {
+			h.EXPECT().Enable(false)
+		}},
+		{5 * time.Minute, minA, false, func(h *mock.MockCharger) {
+			// budget exhausted
+		}},
+		{30 * time.Minute, minA, false, func(h *mock.MockCharger) {
+			// first cycle expired
+			h.EXPECT().Enable(true)
+		}},
+	}
+
+	for _, tc := range tc {
+		t.Logf("%+v", tc)
+
+		ctrl := gomock.NewController(t)
+		charger := mock.NewMockCharger(ctrl)
+		lp.charger = charger
+
+		clck.Add(tc.step)
+		tc.expect(charger)
+
+		if err := lp.setLimit(float64(tc.current), tc.force); err != nil {
+			t.Error(err)
+		}
+
+		ctrl.Finish()
+	}
+
+	if hour, day := lp.switchCycles(); hour != 2 || day != 3 {
+		t.Errorf("expected 2 cycles per hour and 3 per day, got %d and %d", hour, day)
+	}
+}
+
+func TestSessionRestore(t *testing.T) {
+	store, err := db.New(filepath.Join(t.TempDir(), "evcc.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	db.Instance = store
+	defer func() {
+		db.Instance = nil
+		store.Close()
+	}()
+
+	newLoadPoint := func() *LoadPoint {
+		lp := NewLoadPoint(util.NewLogger("lp-1"))
+		lp.socTimer = soc.NewTimer(lp.log, lp.adapter(), lp.MaxCurrent)
+		lp.restoreSettings()
+		return lp
+	}
+
+	// vehicle connected at startup with 0% soc
+	lp := newLoadPoint()
+	lp.status = api.StatusB
+	lp.startSession()
+	lp.updateSessionSoC(0)
+
+	lp.chargedEnergy = 2000
+	lp.persistSession()
+
+	// restart with charge rater continuing from 500Wh
+	lp = newLoadPoint()
+	if lp.session == nil {
+		t.Fatal("expected restored session")
+	}
+
+	lp.chargedEnergy = 500
+	lp.resumeSession()
+	lp.chargedEnergy = 1500
+
+	lp.updateSessionSoC(50)
+	lp.finishSession()
+
+	sessions, err := store.Sessions(time.Time{}, time.Time{}, "", "")
+	if err != nil || len(sessions) != 1 {
+		t.Fatalf("expected single session, got %v %v", sessions, err)
+	}
+
+	if s := sessions[0]; s.ChargedEnergy != 3 || s.SoCStart != 0 || s.SoCEnd != 50 {
+		t.Errorf("unexpected session %+v", s)
+	}
+
+	// finished session is not restored
+	if lp = newLoadPoint(); lp.session != nil {
+		t.Error("unexpected restored session")
+	}
+}
+
+func TestTakeOver(t *testing.T) {
+	clck := clock.NewMock()
+
+	old := NewLoadPoint(util.NewLogger("foo"))
+	old.clock = clck
+	old.status = api.StatusC
+	old.connectedTime = clck.Now()
+	old.switchStarts = []time.Time{clck.Now()}
+	old.session = &db.Session{Created: clck.Now()}
+	old.sessionOffset = 500
+	old.chargedEnergy = 1000
+
+	var handled bool
+	old.subscribe(evChargeStop, func() { handled = true })
+
+	lp := NewLoadPoint(util.NewLogger("foo"))
+	lp.clock = clck
+	lp.takeOver(old)
+	old.close()
+
+	old.bus.Publish(evChargeStop)
+	if handled {
+		t.Error("replaced loadpoint still handles events")
+	}
+
+	if !lp.charging() || lp.session != old.session || len(lp.switchStarts) != 1 || !lp.connectedTime.Equal(old.connectedTime) {
+		t.Errorf("state not taken over: %+v", lp)
+	}
+
+	// new charge rater starts from zero
+	lp.chargedEnergy = 200
+	lp.resumeSession()
+
+	lp.chargedEnergy = 300
+	if energy := lp.sessionChargedEnergy(); energy != 1600 {
+		t.Errorf("expected session energy 1600, got %.0f", energy)
+	}
+}
@@ -1,6 +1,9 @@
 package core
 
-import "strings"
+import (
+	"strings"
+	"time"
+)
 
 // RemoteDemand defines external status demand
 type RemoteDemand string
@@ -23,3 +26,29 @@ func RemoteDemandString(demand string) (RemoteDemand, error) {
 		return RemoteEnable, nil
 	}
 }
+
+// RemotePowerLimit defines an external charging power limit, e.g. requested by the grid operator
+type RemotePowerLimit struct {
+	Power  float64   `json:"power"`  // W, zero if not limited
+	Source string    `json:"source"` // Requesting party
+	Expiry time.Time `json:"expiry"` // Zero if not expiring
+}
+
+// newRemotePowerLimit creates a power limit expiring after duration. Zero duration does not expire.
+func newRemotePowerLimit(source string, power float64, duration time.Duration, now time.Time) RemotePowerLimit {
+	limit := RemotePowerLimit{
+		Power:  power,
+		Source: source,
+	}
+
+	if duration > 0 {
+		limit.Expiry = now.Add(duration)
+	}
+
+	return limit
+}
+
+// active returns true if the power limit is set and not expired
+func (l RemotePowerLimit) active(now time.Time) bool {
+	return l.Power > 0 && (l.Expiry.IsZero() || now.Before(l.Expiry))
+}
@@ -0,0 +1,79 @@
+package core
+
+import (
+	"time"
+
+	"github.com/andig/evcc/push"
+	"github.com/andig/evcc/util"
+	"github.com/benbjohnson/clock"
+)
+
+// simClock is a manually advanced clock. Unlike clock.Mock it does not
+// yield to the scheduler on every change which keeps long simulations fast.
+type simClock struct {
+	clock.Clock
+	now time.Time
+}
+
+func (c *simClock) Now() time.Time {
+	return c.now
+}
+
+func (c *simClock) Since(t time.Time) time.Duration {
+	return c.now.Sub(t)
+}
+
+// Simulation executes the site's control loop against simulated time
+type Simulation struct {
+	site  *Site
+	clock *simClock
+	done  chan struct{}
+}
+
+// NewSimulation prepares the site for simulation starting at the given time.
+// Published values and notifications are discarded until the simulation is closed.
+func NewSimulation(site *Site, start time.Time) *Simulation {
+	clck := &simClock{Clock: clock.NewMock(), now: start}
+	site.clock = clck
+	for _, lp := range site.loadpoints {
+		lp.clock = clck
+	}
+	for _, c := range site.consumers {
+		c.clock = clck
+	}
+
+	uiChan := make(chan util.Param)
+	pushChan := make(chan push.Event)
+	done := make(chan struct{})
+
+	go func() {
+		for {
+			select {
+			case <-uiChan:
+			case <-pushChan:
+			case <-done:
+				return
+			}
+		}
+	}()
+
+	site.Prepare(uiChan, pushChan)
+
+	return &Simulation{
+		site:  site,
+		clock: clck,
+		done:  done,
+	}
+}
+
+// Step advances the simulated time and executes a single control cycle
+func (s *Simulation) Step(now time.Time) {
+	s.clock.now = now
+	s.site.update()
+}
+
+// Close stops discarding published values and notifications when the simulation has finished
+func (s *Simulation) Close() {
+	s.site.stopLoadPoints()
+	close(s.done)
+}
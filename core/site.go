@@ -8,46 +8,79 @@ import (
 	"time"
 
 	"github.com/andig/evcc/api"
+	"github.com/andig/evcc/core/soc"
 	"github.com/andig/evcc/push"
+	"github.com/andig/evcc/server/db"
+	"github.com/andig/evcc/tariff"
 	"github.com/andig/evcc/util"
 	"github.com/avast/retry-go"
+	"github.com/benbjohnson/clock"
 )
 
 //go:generate mockgen -package mock -destination ../mock/mock_loadpoint.go github.com/andig/evcc/core Updater
 
 // Updater abstracts the LoadPoint implementation for testing
 type Updater interface {
-	Update(float64)
+	Update(sitePower float64, availableCurrent float64)
 }
 
 // Site is the main configuration container. A site can host multiple loadpoints.
 type Site struct {
 	uiChan       chan<- util.Param // client push messages
-	lpUpdateChan chan *LoadPoint
+	pushChan     chan<- push.Event // notifications
+	lpUpdateChan chan *LoadPoint   // loadpoint update requests
+	replaceChan  chan []*LoadPoint // loadpoint replacements
+	lpStopChan   []chan struct{}   // loadpoint message forwarding stop
 
 	*Health
 
 	sync.Mutex
-	log *util.Logger
+	log   *util.Logger
+	clock clock.Clock // mockable time
 
 	// configuration
+	ID            string       `mapstructure:"id"`            // Site id for namespacing outputs if multiple sites are configured
 	Title         string       `mapstructure:"title"`         // UI title
 	Voltage       float64      `mapstructure:"voltage"`       // Operating voltage. 230V for Germany.
 	ResidualPower float64      `mapstructure:"residualPower"` // PV meter only: household usage. Grid meter: household safety margin
 	Meters        MetersConfig // Meter references
 	PrioritySoC   float64      `mapstructure:"prioritySoC"` // prefer battery up to this SoC
+	MaxCurrent    float64      `mapstructure:"maxCurrent"`  // per-phase current limit of the grid connection (main fuse)
+
+	BatteryDischargeLock []string          `mapstructure:"batteryDischargeLock"` // loadpoint states blocking battery discharge
+	RemoteLimit          RemoteLimitConfig `mapstructure:"remoteLimit"`          // remote power limit input
+	FailSafe             FailSafeConfig    `mapstructure:"failSafe"`             // default loadpoint action when meters or chargers stop responding
+	Filter               SiteFilterConfig  `mapstructure:"filter"`               // site power filters for pv control
+	ConsumerConfigs      []ConsumerConfig  `mapstructure:"consumers"`            // flexible consumers using pv power
 
 	// meters
 	gridMeter    api.Meter // Grid usage meter
 	pvMeter      api.Meter // PV generation meter
 	batteryMeter api.Meter // Battery charging meter
 
-	loadpoints []*LoadPoint // Loadpoints
+	batteryController api.BatteryController   // Battery discharge control
+	remoteLimitG      func() (float64, error) // Remote power limit input
+	enableFilter      *powerFilter            // Site power filter for enabling and increasing current
+	disableFilter     *powerFilter            // Site power filter for disabling and reducing current
+
+	tariffs    tariff.Tariffs    // Tariffs
+	forecast   api.SolarForecast // PV production forecast
+	loadpoints []*LoadPoint      // Loadpoints
+	consumers  []*Consumer       // Flexible consumers
+	settings   *db.Settings      // Runtime settings
 
 	// cached state
-	gridPower    float64 // Grid power
-	pvPower      float64 // PV power
-	batteryPower float64 // Battery charge power
+	gridPower    float64   // Grid power
+	pvPower      float64   // PV power
+	batteryPower float64   // Battery charge power
+	gridCurrents []float64 // Grid phase currents
+
+	meterFault         bool // Site meters failed
+	batteryLocked      bool // Battery discharge locked
+	batteryLockApplied bool // Battery discharge lock state has been written
+
+	remotePowerLimit RemotePowerLimit        // External power limit, guarded by mutex
+	prices           map[string]*tariffPrice // Last known tariff prices by tariff name
 }
 
 // MetersConfig contains the loadpoint's meter configuration
@@ -63,14 +96,31 @@ func NewSiteFromConfig(
 	cp configProvider,
 	other map[string]interface{},
 	loadpoints []*LoadPoint,
+	tariffs tariff.Tariffs,
+	forecast api.SolarForecast,
 ) (*Site, error) {
 	site := NewSite()
 	if err := util.DecodeOther(other, &site); err != nil {
 		return nil, err
 	}
 
-	Voltage = site.Voltage
+	if site.ID != "" {
+		site.log = util.NewLogger("site-" + site.ID)
+	}
+
 	site.loadpoints = loadpoints
+	site.tariffs = tariffs
+	site.forecast = forecast
+
+	// runtime settings take precedence over configuration
+	if db.Instance != nil {
+		site.settings = db.Instance.Settings(site.settingsScope())
+		loadSetting(site.log, site.settings, "prioritySoC", &site.PrioritySoC)
+	}
+
+	for id, lp := range loadpoints {
+		site.configureLoadPoint(id, lp)
+	}
 
 	// configure meter from references
 	// if site.Meters.PVMeterRef == "" && site.Meters.GridMeterRef == "" {
@@ -89,22 +139,82 @@ func NewSiteFromConfig(
 		site.batteryMeter = cp.Meter(site.Meters.BatteryMeterRef)
 	}
 
+	// load management requires grid phase currents
+	if _, ok := site.gridMeter.(api.MeterCurrent); site.MaxCurrent > 0 && !ok {
+		return nil, errors.New("site max current requires grid meter with currents")
+	}
+
+	if err := site.FailSafe.validate(); err != nil {
+		return nil, err
+	}
+
+	if err := site.configureFilters(); err != nil {
+		return nil, err
+	}
+
+	if err := site.configureConsumers(cp); err != nil {
+		return nil, err
+	}
+
+	if err := site.configureBatteryLock(); err != nil {
+		return nil, err
+	}
+
+	if err := site.configureRemoteLimit(site.RemoteLimit); err != nil {
+		return nil, err
+	}
+
 	return site, nil
 }
 
 // NewSite creates a Site with sane defaults
 func NewSite() *Site {
 	lp := &Site{
-		log:     util.NewLogger("site"),
-		Health:  NewHealth(60 * time.Second),
-		Voltage: 230, // V
+		log:         util.NewLogger("site"),
+		clock:       clock.New(),
+		Health:      NewHealth(60 * time.Second),
+		Voltage:     230, // V
+		replaceChan: make(chan []*LoadPoint),
 	}
 
 	return lp
 }
 
+// configureLoadPoint attaches the loadpoint to the site, restores its runtime settings
+// and allows it to plan charging using the grid tariff and pv forecast
+func (site *Site) configureLoadPoint(id int, lp *LoadPoint) {
+	lp.siteID = site.ID
+	lp.siteVoltage = site.Voltage
+
+	if lp.ID == "" {
+		lp.ID = fmt.Sprintf("lp-%d", id+1)
+	}
+
+	// runtime settings take precedence over configuration
+	lp.restoreSettings()
+
+	lp.planner = soc.NewPlanner(lp.log, lp.adapter(), site.tariffs.Grid, lp.MaxCurrent)
+	lp.checkCheapest(lp.Mode)
+	lp.socTimer.Forecast = site.forecast
+
+	if lp.FailSafe.Action == "" {
+		lp.FailSafe = site.FailSafe
+	}
+}
+
+// settingsScope returns the scope of the site's runtime settings
+func (site *Site) settingsScope() string {
+	if site.ID == "" {
+		return "site"
+	}
+	return "site." + site.ID
+}
+
 // LoadPoints returns the array of associated loadpoints
 func (site *Site) LoadPoints() []LoadPointAPI {
+	site.Lock()
+	defer site.Unlock()
+
 	res := make([]LoadPointAPI, len(site.loadpoints))
 	for id, lp := range site.loadpoints {
 		res[id] = lp
@@ -158,6 +268,29 @@ func (site *Site) DumpConfig() {
 		if ok {
 			site.publish("prioritySoC", site.PrioritySoC)
 		}
+
+		if site.batteryController != nil {
+			site.log.INFO.Printf("  battery discharge lock: %v", site.BatteryDischargeLock)
+		}
+	}
+
+	if site.MaxCurrent > 0 {
+		site.log.INFO.Printf("  load management: max current %.0fA", site.MaxCurrent)
+		site.publish("maxCurrent", site.MaxCurrent)
+	}
+
+	site.log.INFO.Printf("  remote limit: input %s", presence[site.remoteLimitG != nil])
+
+	site.log.INFO.Printf("  tariffs:   grid %s, feed-in %s", presence[site.tariffs.Grid != nil], presence[site.tariffs.FeedIn != nil])
+	site.log.INFO.Printf("  forecast:  pv %s", presence[site.forecast != nil])
+
+	for i, c := range site.consumers {
+		site.log.INFO.Printf("  consumer %d: %s priority %d power %vW meter %s", i+1, c.Title, c.Priority, c.Power, presence[c.meter != nil])
+	}
+
+	site.publish("tariffConfigured", site.tariffs.Grid != nil)
+	if site.tariffs.Currency != "" {
+		site.publish("currency", site.tariffs.Currency)
 	}
 
 	for i, lp := range site.loadpoints {
@@ -165,16 +298,22 @@ func (site *Site) DumpConfig() {
 
 		lp.log.INFO.Printf("  mode:      %s", lp.GetMode())
 
+		if lp.FailSafe.Action != "" {
+			lp.log.INFO.Printf("  fail-safe: %s", lp.FailSafe.Action)
+		}
+
 		_, power := lp.charger.(api.Meter)
 		_, energy := lp.charger.(api.MeterEnergy)
 		_, currents := lp.charger.(api.MeterCurrent)
 		_, timer := lp.charger.(api.ChargeTimer)
+		_, phases := lp.charger.(api.ChargePhases)
 
-		lp.log.INFO.Printf("  charger:   power %s energy %s currents %s timer %s",
+		lp.log.INFO.Printf("  charger:   power %s energy %s currents %s timer %s phases %s",
 			presence[power],
 			presence[energy],
 			presence[currents],
 			presence[timer],
+			presence[phases],
 		)
 
 		lp.log.INFO.Printf("  meters:    charge %s", presence[lp.HasChargeMeter()])
@@ -191,8 +330,9 @@ func (site *Site) DumpConfig() {
 			_, finish := v.(api.VehicleFinishTimer)
 			_, status := v.(api.VehicleStatus)
 			_, climate := v.(api.VehicleClimater)
-			lp.log.INFO.Printf("    car %d:   range %s finish %s status %s climate %s",
-				i, presence[rng], presence[finish], presence[status], presence[climate],
+			_, precondition := v.(api.VehicleClimateController)
+			lp.log.INFO.Printf("    car %d:   range %s finish %s status %s climate %s precondition %s",
+				i, presence[rng], presence[finish], presence[status], presence[climate], presence[precondition],
 			)
 		}
 	}
@@ -206,8 +346,9 @@ func (site *Site) publish(key string, val interface{}) {
 	}
 
 	site.uiChan <- util.Param{
-		Key: key,
-		Val: val,
+		Site: site.ID,
+		Key:  key,
+		Val:  val,
 	}
 }
 
@@ -254,11 +395,15 @@ func (site *Site) updateMeters() error {
 	}
 
 	// currents
+	site.gridCurrents = nil
 	if phaseMeter, ok := site.gridMeter.(api.MeterCurrent); err == nil && ok {
 		i1, i2, i3, err := phaseMeter.Currents()
 		if err == nil {
-			site.log.TRACE.Printf("grid currents: %.3gA", []float64{i1, i2, i3})
-			site.publish("gridCurrents", []float64{i1, i2, i3})
+			site.gridCurrents = []float64{i1, i2, i3}
+			site.log.TRACE.Printf("grid currents: %.3gA", site.gridCurrents)
+			site.publish("gridCurrents", site.gridCurrents)
+		} else {
+			site.log.ERROR.Printf("updating grid currents: %v", err)
 		}
 	}
 
@@ -304,67 +449,243 @@ func (site *Site) sitePower() (float64, error) {
 	return sitePower, nil
 }
 
-func (site *Site) update(lp Updater) {
+// connectedLoadPoints returns the number of loadpoints with connected vehicle
+func (site *Site) connectedLoadPoints() int {
+	var connected int
+	for _, lp := range site.loadpoints {
+		if lp.connected() {
+			connected++
+		}
+	}
+	return connected
+}
+
+// availableCurrent returns the per-phase current each connected loadpoint may additionally
+// draw before the site's max current is reached. Negative values require loadpoints to reduce
+// their current. Headroom and overload are shared equally between connected loadpoints
+// to prevent simultaneously starting loadpoints from exceeding the max current.
+func (site *Site) availableCurrent() float64 {
+	if site.MaxCurrent == 0 {
+		return noCurrentLimit
+	}
+
+	// grid currents unknown- don't allow any increase
+	if site.gridCurrents == nil {
+		site.log.WARN.Println("load management: grid currents unavailable")
+		return 0
+	}
+
+	var maxCurrent float64
+	for _, current := range site.gridCurrents {
+		maxCurrent = math.Max(maxCurrent, math.Abs(current))
+	}
+
+	available := site.MaxCurrent - maxCurrent
+
+	connected := site.connectedLoadPoints()
+	if connected > 1 {
+		available /= float64(connected)
+	}
+
+	site.log.DEBUG.Printf("load management: %.1fA available (%.1fA max phase current, %d connected)", available, maxCurrent, connected)
+
+	return available
+}
+
+// update reads the site meters once and updates all loadpoints with their share of available power
+func (site *Site) update() {
 	site.log.DEBUG.Println("----")
 
-	if sitePower, err := site.sitePower(); err == nil {
-		lp.Update(sitePower)
-		site.Health.Update()
+	sitePower, err := site.sitePower()
+	if err != nil {
+		site.meterFault = true
+		site.publish("fault", err.Error())
+
+		// loadpoints are not updated without site power
+		for _, lp := range site.loadpoints {
+			lp.fault(err)
+		}
+
+		for _, c := range site.consumers {
+			c.fault(site.FailSafe)
+		}
+
+		return
+	}
+
+	if site.meterFault {
+		site.meterFault = false
+		site.publish("fault", "")
+
+		for _, c := range site.consumers {
+			c.clearFault()
+		}
+	}
+
+	site.updateRemoteLimit()
+	powerLimit := site.powerLimitShare()
+
+	enablePower, disablePower := site.filterPower(sitePower)
+
+	site.updateConsumerPower()
+
+	enableAllocation, enableBudgets := site.allocate(enablePower)
+	disableAllocation, disableBudgets := enableAllocation, enableBudgets
+	if disablePower != enablePower {
+		disableAllocation, disableBudgets = site.allocate(disablePower)
+	}
+
+	availableCurrent := site.availableCurrent()
+	for i, lpPower := range disableAllocation {
+		lp := site.loadpoints[i]
+		lp.sitePowerLimit = powerLimit
+		lp.enablePowerOffset = enableAllocation[i] - lpPower
+
+		// loadpoints connecting during this cycle have not been accounted for when sharing the
+		// headroom or power limit and may only start once they receive their share in the next cycle
+		current := availableCurrent
+		if (site.MaxCurrent > 0 || powerLimit > 0) && !lp.connected() {
+			current = math.Min(current, 0)
+		}
+
+		lp.Update(lpPower, current)
 	}
+
+	for i, c := range site.consumers {
+		c.Update(enableBudgets[i], disableBudgets[i])
+	}
+
+	site.updateSessionEnergy()
+
+	site.updateBatteryLock(site.batteryLockRequired())
+
+	site.Health.Update()
 }
 
 // Prepare attaches communication channels to site and loadpoints
 func (site *Site) Prepare(uiChan chan<- util.Param, pushChan chan<- push.Event) {
 	site.uiChan = uiChan
+	site.pushChan = pushChan
 	site.lpUpdateChan = make(chan *LoadPoint, 1) // 1 capacity to avoid deadlock
+	site.lpStopChan = make([]chan struct{}, len(site.loadpoints))
 
 	for id, lp := range site.loadpoints {
-		lpUIChan := make(chan util.Param)
-		lpPushChan := make(chan push.Event)
+		site.prepareLoadPoint(id, lp)
+	}
 
-		// pipe messages through go func to add id
-		go func(id int) {
-			for {
+	for id, c := range site.consumers {
+		c.Prepare(uiChan, site.ID, id)
+	}
+}
+
+// prepareLoadPoint attaches communication channels to the loadpoint
+func (site *Site) prepareLoadPoint(id int, lp *LoadPoint) {
+	lpUIChan := make(chan util.Param)
+	lpPushChan := make(chan push.Event)
+
+	stopC := make(chan struct{})
+	site.lpStopChan[id] = stopC
+
+	// pipe messages through go func to add id
+	go func() {
+		for {
+			select {
+			case <-stopC:
+				return
+			case param := <-lpUIChan:
+				param.Site = site.ID
+				param.LoadPoint = &id
+				select {
+				case site.uiChan <- param:
+				case <-stopC:
+					return
+				}
+			case ev := <-lpPushChan:
+				ev.Site = site.ID
+				ev.LoadPoint = &id
 				select {
-				case param := <-lpUIChan:
-					param.LoadPoint = &id
-					uiChan <- param
-				case ev := <-lpPushChan:
-					ev.LoadPoint = &id
-					pushChan <- ev
+				case site.pushChan <- ev:
+				case <-stopC:
+					return
 				}
 			}
-		}(id)
+		}
+	}()
 
-		lp.Prepare(lpUIChan, lpPushChan, site.lpUpdateChan)
+	lp.Prepare(lpUIChan, lpPushChan, site.lpUpdateChan)
+}
+
+// stopLoadPoints stops forwarding the loadpoints' published values and notifications
+func (site *Site) stopLoadPoints() {
+	for _, stopC := range site.lpStopChan {
+		close(stopC)
 	}
+	site.lpStopChan = nil
 }
 
-// loopLoadpoints keeps iterating across loadpoints sending the next to the given channel
-func (site *Site) loopLoadpoints(next chan<- Updater) {
-	for {
-		for _, lp := range site.loadpoints {
-			next <- lp
+// ReplaceLoadPoints replaces the site's loadpoints between control cycles. The number of
+// loadpoints must not change. Nil entries keep the existing loadpoint. Fails if the control
+// loop does not accept the replacement before the deadline.
+func (site *Site) ReplaceLoadPoints(loadpoints []*LoadPoint, deadline time.Time) error {
+	if len(loadpoints) != len(site.loadpoints) {
+		return errors.New("number of loadpoints must not change")
+	}
+
+	timer := time.NewTimer(time.Until(deadline))
+	defer timer.Stop()
+
+	select {
+	case site.replaceChan <- loadpoints:
+		return nil
+	case <-timer.C:
+		return errors.New("timeout replacing loadpoints")
+	}
+}
+
+// replaceLoadPoints replaces loadpoints with their new configuration
+func (site *Site) replaceLoadPoints(loadpoints []*LoadPoint) {
+	for id, lp := range loadpoints {
+		if lp == nil {
+			continue
 		}
+
+		site.log.INFO.Printf("replacing loadpoint %d", id+1)
+
+		site.configureLoadPoint(id, lp)
+
+		// continue vehicle status, session and timers, detach replaced loadpoint
+		old := site.loadpoints[id]
+		lp.takeOver(old)
+		old.close()
+		close(site.lpStopChan[id])
+
+		site.prepareLoadPoint(id, lp)
+
+		site.Lock()
+		site.loadpoints[id] = lp
+		site.Unlock()
 	}
+
+	site.DumpConfig()
 }
 
 // Run is the main control loop. It reacts to trigger events by
 // updating measurements and executing control logic.
 func (site *Site) Run(stopC chan struct{}, interval time.Duration) {
-	loadpointChan := make(chan Updater)
-	go site.loopLoadpoints(loadpointChan)
-
 	ticker := time.NewTicker(interval)
-	site.update(<-loadpointChan) // start immediately
+	site.update() // start immediately
 
 	for {
 		select {
 		case <-ticker.C:
-			site.update(<-loadpointChan)
-		case lp := <-site.lpUpdateChan:
-			site.update(lp)
+			site.update()
+		case <-site.lpUpdateChan:
+			site.update()
+		case loadpoints := <-site.replaceChan:
+			site.replaceLoadPoints(loadpoints)
 		case <-stopC:
+			// don't leave battery locked
+			site.updateBatteryLock(false)
 			return
 		}
 	}
@@ -0,0 +1,172 @@
+package core
+
+import (
+	"math"
+	"sort"
+
+	"github.com/andig/evcc/api"
+)
+
+// minAllocation is the power below which remaining power is not shared any further.
+// It prevents endless sharing of rounding residues.
+const minAllocation = 1e-6 // W
+
+// powerDemand describes a loadpoint's power requirements for pv allocation
+type powerDemand struct {
+	priority  int
+	min, max  float64 // W
+	mandatory bool    // minimum power is required regardless of availability
+}
+
+// allocatePower splits the available power between demands. Minimum power is assigned in order of
+// priority first. Remaining power is then assigned in order of priority and shared equally between
+// demands of same priority. Left over or missing power is assigned to the first demand in order of
+// priority that could not be satisfied or to the highest priority demand.
+func allocatePower(available float64, demands []powerDemand) []float64 {
+	res := make([]float64, len(demands))
+	if len(demands) == 0 {
+		return res
+	}
+
+	order := make([]int, len(demands))
+	for i := range order {
+		order[i] = i
+	}
+
+	sort.SliceStable(order, func(i, j int) bool {
+		return demands[order[i]].priority > demands[order[j]].priority
+	})
+
+	// minimum power
+	satisfied := make([]bool, len(demands))
+	for _, i := range order {
+		if d := demands[i]; d.mandatory || available >= d.min {
+			res[i] = d.min
+			satisfied[i] = true
+			available -= d.min
+		}
+	}
+
+	// remaining power by priority
+	for start := 0; start < len(order); {
+		end := start + 1
+		for end < len(order) && demands[order[end]].priority == demands[order[start]].priority {
+			end++
+		}
+
+		for available > minAllocation {
+			var open []int
+			for _, i := range order[start:end] {
+				if satisfied[i] && res[i] < demands[i].max {
+					open = append(open, i)
+				}
+			}
+
+			if len(open) == 0 {
+				break
+			}
+
+			share := available / float64(len(open))
+
+			var added float64
+			for _, i := range open {
+				add := math.Min(share, demands[i].max-res[i])
+				res[i] += add
+				added += add
+			}
+
+			// no progress due to rounding
+			if added == 0 {
+				break
+			}
+
+			available -= added
+		}
+
+		start = end
+	}
+
+	// left over power
+	receiver := order[0]
+	for _, i := range order {
+		if !satisfied[i] {
+			receiver = i
+			break
+		}
+	}
+	res[receiver] += available
+
+	return res
+}
+
+// pvManaged returns true if the loadpoint's current is controlled by available pv power
+func (lp *LoadPoint) pvManaged() bool {
+	mode := lp.GetMode()
+	return lp.connected() && (mode == api.ModePV || mode == api.ModeMinPV)
+}
+
+// powerDemand returns the loadpoint's power requirements for pv allocation
+func (lp *LoadPoint) powerDemand() powerDemand {
+	minPhases, maxPhases := lp.Phases, lp.Phases
+	if _, ok := lp.charger.(api.ChargePhases); ok && lp.maxPhases == 3 {
+		minPhases, maxPhases = 1, 3
+	}
+
+	return powerDemand{
+		priority:  lp.Priority,
+		min:       float64(lp.MinCurrent*minPhases) * lp.voltage(),
+		max:       lp.maxVehicleCurrent() * float64(maxPhases) * lp.voltage(),
+		mandatory: lp.GetMode() == api.ModeMinPV,
+	}
+}
+
+// allocate distributes the site's available pv power between pv-managed loadpoints and consumers.
+// It returns the site power as seen by each loadpoint, i.e. the loadpoint's own
+// charge power minus its allocated budget, and the consumers' power budgets.
+// Loadpoints not managed by pv see the site power.
+func (site *Site) allocate(sitePower float64) ([]float64, []float64) {
+	res := make([]float64, len(site.loadpoints))
+	budgets := make([]float64, len(site.consumers))
+
+	var managed []*LoadPoint
+	var demands []powerDemand
+
+	available := -sitePower
+	for i, lp := range site.loadpoints {
+		res[i] = sitePower
+
+		if lp.pvManaged() {
+			managed = append(managed, lp)
+			demands = append(demands, lp.powerDemand())
+			available += lp.chargePower
+		}
+	}
+
+	for _, c := range site.consumers {
+		demands = append(demands, c.powerDemand())
+		available += c.power
+	}
+
+	// single or no loadpoint without consumers sees the site power
+	if len(managed) < 2 && len(site.consumers) == 0 {
+		return res, budgets
+	}
+
+	allocation := allocatePower(available, demands)
+
+	for i, lp := range site.loadpoints {
+		for j, m := range managed {
+			if lp == m {
+				lp.log.DEBUG.Printf("pv budget: %.0fW of %.0fW (priority %d)", allocation[j], available, lp.Priority)
+				res[i] = lp.chargePower - allocation[j]
+			}
+		}
+	}
+
+	for i, c := range site.consumers {
+		budgets[i] = allocation[len(managed)+i]
+		c.log.DEBUG.Printf("pv budget: %.0fW of %.0fW (priority %d)", budgets[i], available, c.Priority)
+	}
+
+	return res, budgets
+}
@@ -2,15 +2,24 @@ package core
 
 import (
 	"errors"
+	"time"
 
 	"github.com/andig/evcc/api"
 )
 
 // SiteAPI is the external site API
 type SiteAPI interface {
+	Name() string
 	Healthy() bool
 	LoadPoints() []LoadPointAPI
+	Consumers() []ConsumerAPI
 	SetPrioritySoC(float64) error
+	SetRemotePowerLimit(string, float64, time.Duration)
+}
+
+// Name returns the site id, empty if not configured
+func (site *Site) Name() string {
+	return site.ID
 }
 
 // GetPrioritySoC returns the PrioritySoC
@@ -32,5 +41,31 @@ func (site *Site) SetPrioritySoC(soc float64) error {
 	site.PrioritySoC = soc
 	site.publish("prioritySoC", site.PrioritySoC)
 
+	if err := site.settings.Save("prioritySoC", soc); err != nil {
+		site.log.ERROR.Printf("persist prioritySoC: %v", err)
+	}
+
 	return nil
 }
+
+// SetRemotePowerLimit limits the charging power of all loadpoints. Zero power removes the limit, zero duration does not expire.
+func (site *Site) SetRemotePowerLimit(source string, power float64, duration time.Duration) {
+	site.setRemotePowerLimit(source, power, duration)
+
+	// apply immediately
+	select {
+	case site.lpUpdateChan <- nil:
+	default:
+	}
+}
+
+// setRemotePowerLimit sets the site's remote power limit
+func (site *Site) setRemotePowerLimit(source string, power float64, duration time.Duration) {
+	site.Lock()
+	defer site.Unlock()
+
+	site.log.INFO.Printf("remote power limit: %.0fW (%s)", power, source)
+
+	site.remotePowerLimit = newRemotePowerLimit(source, power, duration, site.clock.Now())
+	site.publishRemotePowerLimit()
+}
@@ -0,0 +1,88 @@
+package core
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/andig/evcc/api"
+)
+
+// Battery discharge lock rules
+const (
+	batteryLockNow      = "now"      // loadpoint charging in now mode
+	batteryLockTarget   = "target"   // loadpoint charging for target time
+	batteryLockCharging = "charging" // loadpoint charging in any mode
+)
+
+// configureBatteryLock validates the battery discharge lock rules
+func (site *Site) configureBatteryLock() error {
+	if len(site.BatteryDischargeLock) == 0 {
+		return nil
+	}
+
+	for _, rule := range site.BatteryDischargeLock {
+		switch rule {
+		case batteryLockNow, batteryLockTarget, batteryLockCharging:
+		default:
+			return fmt.Errorf("invalid battery discharge lock: %s", rule)
+		}
+	}
+
+	controller, ok := site.batteryMeter.(api.BatteryController)
+	if !ok {
+		return errors.New("battery discharge lock requires battery meter with lock")
+	}
+
+	site.batteryController = controller
+
+	return nil
+}
+
+// holdsBattery returns true if the loadpoint's charging state matches the battery discharge lock rule
+func (lp *LoadPoint) holdsBattery(rule string) bool {
+	if !lp.charging() {
+		return false
+	}
+
+	switch rule {
+	case batteryLockNow:
+		return lp.GetMode() == api.ModeNow
+	case batteryLockTarget:
+		return lp.targetCharging
+	case batteryLockCharging:
+		return true
+	}
+
+	return false
+}
+
+// batteryLockRequired returns true if any loadpoint matches any battery discharge lock rule
+func (site *Site) batteryLockRequired() bool {
+	for _, lp := range site.loadpoints {
+		for _, rule := range site.BatteryDischargeLock {
+			if lp.holdsBattery(rule) {
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
+// updateBatteryLock blocks or allows battery discharge if required
+func (site *Site) updateBatteryLock(lock bool) {
+	if site.batteryController == nil || site.batteryLockApplied && lock == site.batteryLocked {
+		return
+	}
+
+	site.log.DEBUG.Printf("battery discharge lock: %t", lock)
+
+	if err := site.batteryController.LockDischarge(lock); err != nil {
+		site.log.ERROR.Printf("battery discharge lock: %v", err)
+		return
+	}
+
+	site.batteryLocked = lock
+	site.batteryLockApplied = true
+	site.publish("batteryDischargeLocked", lock)
+}
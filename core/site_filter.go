@@ -0,0 +1,155 @@
+package core
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+	"time"
+)
+
+// Filter types
+const (
+	filterAverage     = "average"     // moving average
+	filterExponential = "exponential" // exponential smoothing
+	filterPercentile  = "percentile"  // percentile
+)
+
+// FilterConfig configures a filter for site power
+type FilterConfig struct {
+	Type       string        `mapstructure:"type"`       // average, exponential or percentile
+	Window     time.Duration `mapstructure:"window"`     // averaging window or time constant
+	Percentile float64       `mapstructure:"percentile"` // percentile of site power within window (default 50)
+}
+
+// SiteFilterConfig configures separate filters for increasing and decreasing charge power
+type SiteFilterConfig struct {
+	Enable  FilterConfig `mapstructure:"enable"`  // filter for enabling and increasing current
+	Disable FilterConfig `mapstructure:"disable"` // filter for disabling and reducing current
+}
+
+type powerSample struct {
+	ts    time.Time
+	value float64
+}
+
+// powerFilter smoothes power values over time
+type powerFilter struct {
+	FilterConfig
+	samples []powerSample // samples within window
+	value   float64       // exponential smoothing value
+}
+
+// newPowerFilter creates a filter from configuration. It returns nil if no filter is configured.
+func newPowerFilter(cc FilterConfig) (*powerFilter, error) {
+	switch cc.Type {
+	case "":
+		return nil, nil
+	case filterAverage, filterExponential:
+	case filterPercentile:
+		if cc.Percentile == 0 {
+			cc.Percentile = 50
+		}
+		if cc.Percentile < 0 || cc.Percentile > 100 {
+			return nil, fmt.Errorf("invalid percentile: %.0f", cc.Percentile)
+		}
+	default:
+		return nil, fmt.Errorf("invalid type: %s", cc.Type)
+	}
+
+	if cc.Window <= 0 {
+		return nil, errors.New("missing window")
+	}
+
+	return &powerFilter{FilterConfig: cc}, nil
+}
+
+// add adds a new sample and returns the filtered value
+func (f *powerFilter) add(ts time.Time, value float64) float64 {
+	var prev powerSample
+	if len(f.samples) > 0 {
+		prev = f.samples[len(f.samples)-1]
+	}
+
+	// drop samples outside window but keep the latest
+	f.samples = append(f.samples, powerSample{ts, value})
+	for len(f.samples) > 1 && ts.Sub(f.samples[0].ts) > f.Window {
+		f.samples = f.samples[1:]
+	}
+
+	switch f.Type {
+	case filterExponential:
+		if prev.ts.IsZero() {
+			f.value = value
+		} else {
+			alpha := 1 - math.Exp(-float64(ts.Sub(prev.ts))/float64(f.Window))
+			f.value += alpha * (value - f.value)
+		}
+		f.samples = f.samples[len(f.samples)-1:]
+		return f.value
+
+	case filterPercentile:
+		values := make([]float64, 0, len(f.samples))
+		for _, s := range f.samples {
+			values = append(values, s.value)
+		}
+		sort.Float64s(values)
+
+		// nearest rank
+		rank := int(math.Ceil(f.Percentile / 100 * float64(len(values))))
+		if rank < 1 {
+			rank = 1
+		}
+		return values[rank-1]
+
+	default:
+		var sum float64
+		for _, s := range f.samples {
+			sum += s.value
+		}
+		return sum / float64(len(f.samples))
+	}
+}
+
+// configureFilters creates the site power filters
+func (site *Site) configureFilters() (err error) {
+	if site.enableFilter, err = newPowerFilter(site.Filter.Enable); err != nil {
+		return fmt.Errorf("enable filter: %w", err)
+	}
+
+	if site.disableFilter, err = newPowerFilter(site.Filter.Disable); err != nil {
+		return fmt.Errorf("disable filter: %w", err)
+	}
+
+	return nil
+}
+
+// filterPower returns the site power filtered for enable and disable decisions.
+// Filters are applied to site power excluding the loadpoints' charge power, which
+// follows the control loop's own decisions, and charge power is added back afterwards.
+func (site *Site) filterPower(sitePower float64) (float64, float64) {
+	if site.enableFilter == nil && site.disableFilter == nil {
+		return sitePower, sitePower
+	}
+
+	var chargePower float64
+	for _, lp := range site.loadpoints {
+		chargePower += lp.chargePower
+	}
+
+	now := site.clock.Now()
+	basePower := sitePower - chargePower
+
+	filter := func(name string, f *powerFilter) float64 {
+		if f == nil {
+			return sitePower
+		}
+
+		power := f.add(now, basePower) + chargePower
+		site.log.DEBUG.Printf("%s site power: %.0fW (%s)", name, power, f.Type)
+
+		return power
+	}
+
+	return filter("enable", site.enableFilter), filter("disable", site.disableFilter)
+}
@@ -0,0 +1,110 @@
+package core
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/andig/evcc/provider"
+)
+
+// remoteLimitSource is the source of power limits read from the configured input
+const remoteLimitSource = "input"
+
+// RemoteLimitConfig configures an input for the site's remote power limit, e.g. a ripple control relay
+type RemoteLimitConfig struct {
+	Power float64          `mapstructure:"power"` // Power limit in W applied while relay is active
+	Relay *provider.Config `mapstructure:"relay"` // Bool getter, limit is active if true
+	Limit *provider.Config `mapstructure:"limit"` // Int getter providing the power limit in W, zero if not limited
+}
+
+// configureRemoteLimit creates the remote power limit input
+func (site *Site) configureRemoteLimit(cc RemoteLimitConfig) error {
+	switch {
+	case cc.Relay != nil && cc.Limit != nil:
+		return errors.New("remote limit: either relay or limit required")
+
+	case cc.Relay != nil:
+		if cc.Power <= 0 {
+			return errors.New("remote limit: relay requires power")
+		}
+
+		relayG, err := provider.NewBoolGetterFromConfig(*cc.Relay)
+		if err != nil {
+			return fmt.Errorf("remote limit: relay: %w", err)
+		}
+
+		site.remoteLimitG = func() (float64, error) {
+			active, err := relayG()
+			if active {
+				return cc.Power, err
+			}
+			return 0, err
+		}
+
+	case cc.Limit != nil:
+		limitG, err := provider.NewIntGetterFromConfig(*cc.Limit)
+		if err != nil {
+			return fmt.Errorf("remote limit: limit: %w", err)
+		}
+
+		site.remoteLimitG = func() (float64, error) {
+			limit, err := limitG()
+			return float64(limit), err
+		}
+	}
+
+	return nil
+}
+
+// updateRemoteLimit applies the power limit read from the configured input
+func (site *Site) updateRemoteLimit() {
+	if site.remoteLimitG == nil {
+		return
+	}
+
+	power, err := site.remoteLimitG()
+	if err != nil {
+		site.log.ERROR.Printf("remote limit: %v", err)
+		return
+	}
+
+	site.Lock()
+	limit := site.remotePowerLimit
+	site.Unlock()
+
+	// don't override limits set by other sources unless the input requests a limit
+	if power == limit.Power || power == 0 && limit.Source != remoteLimitSource {
+		return
+	}
+
+	site.setRemotePowerLimit(remoteLimitSource, power, 0)
+}
+
+// powerLimitShare returns each connected loadpoint's equal share of the site's remote power limit, zero if not limited
+func (site *Site) powerLimitShare() float64 {
+	site.Lock()
+	defer site.Unlock()
+
+	limit := site.remotePowerLimit
+	if limit.Power > 0 && !limit.active(site.clock.Now()) {
+		site.log.INFO.Printf("remote power limit expired (%s)", limit.Source)
+		site.remotePowerLimit = RemotePowerLimit{}
+		site.publishRemotePowerLimit()
+	}
+
+	if site.remotePowerLimit.Power == 0 {
+		return 0
+	}
+
+	if connected := site.connectedLoadPoints(); connected > 1 {
+		return site.remotePowerLimit.Power / float64(connected)
+	}
+
+	return site.remotePowerLimit.Power
+}
+
+// publishRemotePowerLimit publishes the site's remote power limit
+func (site *Site) publishRemotePowerLimit() {
+	site.publish("remotePowerLimit", site.remotePowerLimit.Power)
+	site.publish("remotePowerLimitSource", site.remotePowerLimit.Source)
+}
@@ -0,0 +1,90 @@
+package core
+
+import (
+	"math"
+	"time"
+
+	"github.com/andig/evcc/api"
+)
+
+// solarShare returns the share of the loadpoints' charge power covered by pv surplus.
+// Charge power is attributed to grid import and battery discharge first.
+func (site *Site) solarShare() float64 {
+	var chargePower float64
+	for _, lp := range site.loadpoints {
+		chargePower += lp.chargePower
+	}
+
+	if chargePower <= 0 {
+		return 0
+	}
+
+	surplus := chargePower - math.Max(0, site.gridPower) - math.Max(0, site.batteryPower)
+
+	// charge power can't be covered by more than the pv production
+	if site.pvMeter != nil {
+		surplus = math.Min(surplus, math.Abs(site.pvPower))
+	}
+
+	return math.Max(0, surplus) / chargePower
+}
+
+// tariffPrice is the last known price of a tariff
+type tariffPrice struct {
+	price  float64
+	failed bool // Failure has been logged
+}
+
+// price returns the tariff's current price per kWh. If the tariff fails, the
+// last known price is used and the failure logged once until the tariff recovers.
+func (site *Site) price(name string, tariff api.Tariff, now time.Time) float64 {
+	if tariff == nil {
+		return 0
+	}
+
+	if site.prices == nil {
+		site.prices = make(map[string]*tariffPrice)
+	}
+
+	last, ok := site.prices[name]
+	if !ok {
+		last = &tariffPrice{}
+		site.prices[name] = last
+	}
+
+	rates, err := tariff.Rates()
+	if err == nil {
+		var rate api.Rate
+		if rate, err = rates.Current(now); err == nil {
+			if last.failed {
+				site.log.INFO.Printf("%s tariff: price available", name)
+			}
+
+			last.price = rate.Price
+			last.failed = false
+
+			return rate.Price
+		}
+	}
+
+	if !last.failed {
+		site.log.ERROR.Printf("%s tariff: %v, using last known price %.3g", name, err, last.price)
+	}
+	last.failed = true
+
+	return last.price
+}
+
+// updateSessionEnergy attributes the loadpoints' charged energy to pv surplus and grid using current prices
+func (site *Site) updateSessionEnergy() {
+	share := site.solarShare()
+	site.log.DEBUG.Printf("solar share: %.0f%%", 100*share)
+
+	now := site.clock.Now()
+	gridPrice := site.price("grid", site.tariffs.Grid, now)
+	feedInPrice := site.price("feed-in", site.tariffs.FeedIn, now)
+
+	for _, lp := range site.loadpoints {
+		lp.updateSessionEnergy(share, gridPrice, feedInPrice)
+	}
+}
@@ -1,7 +1,16 @@
 package core
 
 import (
+	"errors"
+	"math"
 	"testing"
+	"time"
+
+	"github.com/andig/evcc/api"
+	"github.com/andig/evcc/mock"
+	"github.com/andig/evcc/util"
+	"github.com/benbjohnson/clock"
+	"github.com/golang/mock/gomock"
 )
 
 func TestSiteApi(t *testing.T) {
@@ -29,4 +38,332 @@ func TestSitePower(t *testing.T) {
 	}
 }
 
+func TestAvailableCurrent(t *testing.T) {
+	tc := []struct {
+		maxCurrent float64
+		currents   []float64
+		connected  int
+		available  float64
+	}{
+		{0, []float64{10, 10, 10}, 1, noCurrentLimit}, // load management disabled
+		{25, nil, 1, 0},                    // currents unknown
+		{25, []float64{10, 20, 5}, 0, 5},   // highest phase counts
+		{25, []float64{10, 20, 5}, 1, 5},   // single loadpoint gets all headroom
+		{25, []float64{10, 15, -5}, 2, 5},  // headroom shared
+		{25, []float64{10, 31, 5}, 2, -3},  // overload shared
+		{25, []float64{-30, 10, 5}, 1, -5}, // feed-in counts towards fuse
+	}
+
+	for _, tc := range tc {
+		t.Log(tc)
+
+		site := NewSite()
+		site.MaxCurrent = tc.maxCurrent
+		site.gridCurrents = tc.currents
+
+		for i := 0; i < 2; i++ {
+			status := api.StatusA
+			if i < tc.connected {
+				status = api.StatusB
+			}
+			site.loadpoints = append(site.loadpoints, &LoadPoint{
+				log:    util.NewLogger("foo"),
+				status: status,
+			})
+		}
+
+		if res := site.availableCurrent(); res != tc.available {
+			t.Errorf("expected %.1fA, got %.1fA", tc.available, res)
+		}
+	}
+}
+
+func TestAllocatePower(t *testing.T) {
+	lp := func(priority int, min, max float64) powerDemand {
+		return powerDemand{priority: priority, min: min, max: max}
+	}
+
+	tc := []struct {
+		available float64
+		demands   []powerDemand
+		res       []float64
+	}{
+		// single loadpoint receives everything
+		{1000, []powerDemand{lp(0, 1400, 11000)}, []float64{1000}},
+		{5000, []powerDemand{lp(0, 1400, 11000)}, []float64{5000}},
+		{20000, []powerDemand{lp(0, 1400, 11000)}, []float64{20000}},
+		// same priority shares
+		{6000, []powerDemand{lp(0, 1400, 11000), lp(0, 1400, 11000)}, []float64{3000, 3000}},
+		{6000, []powerDemand{lp(0, 1400, 2500), lp(0, 1400, 11000)}, []float64{2500, 3500}},
+		{16000, []powerDemand{lp(0, 1400, 4000), lp(0, 1400, 11000)}, []float64{5000, 11000}},
+		// minimum power for both, remainder by priority
+		{6000, []powerDemand{lp(0, 1400, 11000), lp(1, 1400, 11000)}, []float64{1400, 4600}},
+		// minimum power for higher priority only
+		{2000, []powerDemand{lp(0, 1400, 11000), lp(1, 1400, 11000)}, []float64{0, 2000}},
+		// higher priority at max, left over to lower priority
+		{2000, []powerDemand{lp(0, 1400, 11000), lp(1, 1400, 1500)}, []float64{500, 1500}},
+		// not enough for any minimum
+		{1000, []powerDemand{lp(0, 1400, 11000), lp(1, 1400, 11000)}, []float64{0, 1000}},
+		// mandatory minimum
+		{1000, []powerDemand{lp(1, 1400, 11000), {min: 1400, max: 11000, mandatory: true}}, []float64{-400, 1400}},
+	}
+
+	for _, tc := range tc {
+		t.Logf("%+v", tc)
+
+		res := allocatePower(tc.available, tc.demands)
+		for i := range res {
+			if res[i] != tc.res[i] {
+				t.Errorf("expected %v, got %v", tc.res, res)
+				break
+			}
+		}
+	}
+}
+
+func TestAllocatePowerSmallHeadroom(t *testing.T) {
+	demands := []powerDemand{
+		{min: 0, max: 0.3},
+		{min: 0, max: 0.7},
+		{min: 0, max: 1.1},
+		{min: 0, max: 1.3},
+		{min: 0, max: 100},
+	}
+
+	for _, available := range []float64{1, 1.1, 3.4, 50} {
+		done := make(chan []float64)
+		go func() {
+			done <- allocatePower(available, demands)
+		}()
+
+		select {
+		case res := <-done:
+			var sum float64
+			for _, p := range res {
+				sum += p
+			}
+			if math.Abs(sum-available) > 1e-6 {
+				t.Errorf("%.1fW: expected all power allocated, got %v", available, res)
+			}
+		case <-time.After(time.Second):
+			t.Fatalf("%.1fW: allocation did not finish", available)
+		}
+	}
+}
+
 // TODO add test case for battery priority charging
+
+func TestBatteryLock(t *testing.T) {
+	tc := []struct {
+		rules  []string
+		status api.ChargeStatus
+		mode   api.ChargeMode
+		target bool
+		lock   bool
+	}{
+		{[]string{"now"}, api.StatusB, api.ModeNow, false, false},        // not charging
+		{[]string{"now"}, api.StatusC, api.ModeNow, false, true},         // fast charging
+		{[]string{"now"}, api.StatusC, api.ModePV, false, false},         // pv charging
+		{[]string{"now"}, api.StatusC, api.ModePV, true, false},          // target charging
+		{[]string{"now", "target"}, api.StatusC, api.ModePV, true, true}, // target charging
+		{[]string{"charging"}, api.StatusC, api.ModePV, false, true},     // any charging
+	}
+
+	for _, tc := range tc {
+		t.Logf("%+v", tc)
+
+		ctrl := gomock.NewController(t)
+		battery := mock.NewMockBatteryController(ctrl)
+
+		site := NewSite()
+		site.BatteryDischargeLock = tc.rules
+		site.batteryController = battery
+		site.loadpoints = []*LoadPoint{{
+			log:            util.NewLogger("foo"),
+			status:         tc.status,
+			Mode:           tc.mode,
+			targetCharging: tc.target,
+		}}
+
+		if res := site.batteryLockRequired(); res != tc.lock {
+			t.Errorf("expected lock %v, got %v", tc.lock, res)
+		}
+
+		// initial state is always written, changes only
+		battery.EXPECT().LockDischarge(tc.lock).Return(nil)
+		site.updateBatteryLock(tc.lock)
+		site.updateBatteryLock(tc.lock)
+
+		battery.EXPECT().LockDischarge(!tc.lock).Return(nil)
+		site.updateBatteryLock(!tc.lock)
+
+		ctrl.Finish()
+	}
+}
+
+func TestPowerLimitShare(t *testing.T) {
+	clck := clock.NewMock()
+
+	tc := []struct {
+		limit     RemotePowerLimit
+		connected int
+		share     float64
+	}{
+		{RemotePowerLimit{}, 1, 0},                                                  // not limited
+		{RemotePowerLimit{Power: 4200}, 0, 4200},                                    // no loadpoint connected
+		{RemotePowerLimit{Power: 4200}, 1, 4200},                                    // single loadpoint gets all
+		{RemotePowerLimit{Power: 4200}, 2, 2100},                                    // limit shared
+		{RemotePowerLimit{Power: 4200, Expiry: clck.Now().Add(-time.Minute)}, 1, 0}, // expired
+	}
+
+	for _, tc := range tc {
+		t.Logf("%+v", tc)
+
+		site := NewSite()
+		site.clock = clck
+		site.remotePowerLimit = tc.limit
+
+		for i := 0; i < 3; i++ {
+			status := api.StatusA
+			if i < tc.connected {
+				status = api.StatusB
+			}
+			site.loadpoints = append(site.loadpoints, &LoadPoint{
+				log:    util.NewLogger("foo"),
+				status: status,
+			})
+		}
+
+		if res := site.powerLimitShare(); res != tc.share {
+			t.Errorf("expected %.0fW, got %.0fW", tc.share, res)
+		}
+	}
+}
+
+func TestSolarShare(t *testing.T) {
+	tc := []struct {
+		grid, pv, battery, charge float64
+		share                     float64
+	}{
+		{0, 0, 0, 0, 0},             // not charging
+		{-1000, 5000, 0, 4000, 1},   // surplus exceeds charge power
+		{1000, 3000, 0, 4000, 0.75}, // grid import
+		{0, 3000, 1000, 4000, 0.75}, // battery discharge
+		{-500, 1000, 0, 4000, 0.25}, // limited by pv production
+		{4000, 0, 0, 4000, 0},       // night
+	}
+
+	for _, tc := range tc {
+		t.Logf("%+v", tc)
+
+		site := NewSite()
+		site.pvMeter = &Null{}
+		site.gridPower, site.pvPower, site.batteryPower = tc.grid, tc.pv, tc.battery
+		site.loadpoints = []*LoadPoint{{chargePower: tc.charge}}
+
+		if res := site.solarShare(); res != tc.share {
+			t.Errorf("expected %.2f, got %.2f", tc.share, res)
+		}
+	}
+}
+
+func TestPowerFilter(t *testing.T) {
+	tc := []struct {
+		config FilterConfig
+		values []float64
+		result []float64
+	}{
+		// window contains 3 samples
+		{FilterConfig{Type: filterAverage, Window: 20 * time.Second}, []float64{300, 0, 0, 600}, []float64{300, 150, 100, 200}},
+		{FilterConfig{Type: filterPercentile, Window: 20 * time.Second, Percentile: 100}, []float64{300, 0, 0, 600}, []float64{300, 300, 300, 600}},
+		{FilterConfig{Type: filterPercentile, Window: 20 * time.Second}, []float64{300, 0, 100, 600}, []float64{300, 0, 100, 100}},
+		// time constant of one interval
+		{FilterConfig{Type: filterExponential, Window: 10 * time.Second}, []float64{1000, 0, 0}, []float64{1000, 1000 / math.E, 1000 / math.E / math.E}},
+	}
+
+	for _, tc := range tc {
+		t.Logf("%+v", tc)
+
+		f, err := newPowerFilter(tc.config)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		ts := time.Now()
+		for i, v := range tc.values {
+			if res := f.add(ts, v); math.Abs(res-tc.result[i]) > 1e-6 {
+				t.Errorf("sample %d: expected %.1f, got %.1f", i, tc.result[i], res)
+			}
+			ts = ts.Add(10 * time.Second)
+		}
+	}
+
+	for _, cc := range []FilterConfig{
+		{Type: "foo", Window: time.Minute},
+		{Type: filterAverage},
+		{Type: filterPercentile, Window: time.Minute, Percentile: 101},
+	} {
+		if _, err := newPowerFilter(cc); err == nil {
+			t.Errorf("%+v: expected error", cc)
+		}
+	}
+}
+
+// testTariff returns the configured rates or error
+type testTariff struct {
+	rates api.Rates
+	err   error
+}
+
+func (t *testTariff) Rates() (api.Rates, error) {
+	return t.rates, t.err
+}
+
+func TestPrice(t *testing.T) {
+	now := clock.NewMock().Now()
+	rates := api.Rates{{Start: now, End: now.Add(time.Hour), Price: 0.3}}
+
+	site := NewSite()
+	tf := &testTariff{}
+
+	tc := []struct {
+		rates api.Rates
+		err   error
+		price float64
+	}{
+		{nil, errors.New("foo"), 0},   // no price known yet
+		{rates, nil, 0.3},             // current price
+		{nil, errors.New("foo"), 0.3}, // last known price
+		{nil, nil, 0.3},               // no current rate
+	}
+
+	for _, tc := range tc {
+		t.Logf("%+v", tc)
+
+		tf.rates, tf.err = tc.rates, tc.err
+		if res := site.price("grid", tf, now); res != tc.price {
+			t.Errorf("expected %.2f, got %.2f", tc.price, res)
+		}
+	}
+}
+
+func TestReplaceLoadPointsDeadline(t *testing.T) {
+	site := &Site{
+		loadpoints:  []*LoadPoint{{}},
+		replaceChan: make(chan []*LoadPoint),
+	}
+
+	if err := site.ReplaceLoadPoints(nil, time.Now().Add(time.Hour)); err == nil {
+		t.Error("expected error for changed number of loadpoints")
+	}
+
+	// control loop not running
+	if err := site.ReplaceLoadPoints([]*LoadPoint{nil}, time.Now()); err == nil {
+		t.Error("expected timeout")
+	}
+
+	go func() { <-site.replaceChan }()
+	if err := site.ReplaceLoadPoints([]*LoadPoint{nil}, time.Now().Add(time.Hour)); err != nil {
+		t.Error(err)
+	}
+}
@@ -0,0 +1,174 @@
+package soc
+
+import (
+	"math"
+	"sort"
+	"time"
+
+	"github.com/andig/evcc/api"
+)
+
+const curveStep = 5 // SoC % per learned charge curve bucket
+
+// curvePower returns the curve's charge power at the given SoC, zero if the curve is empty.
+// Power is interpolated linearly between points and constant beyond the first and last point.
+func curvePower(curve api.ChargeCurve, soc float64) float64 {
+	if len(curve) == 0 {
+		return 0
+	}
+
+	if soc <= curve[0].SoC {
+		return curve[0].Power
+	}
+
+	for i := 1; i < len(curve); i++ {
+		if p := curve[i]; soc <= p.SoC {
+			prev := curve[i-1]
+			return prev.Power + (p.Power-prev.Power)*(soc-prev.SoC)/(p.SoC-prev.SoC)
+		}
+	}
+
+	return curve[len(curve)-1].Power
+}
+
+// sortedCurve returns a copy of the curve sorted by SoC without invalid or duplicate points
+func sortedCurve(curve api.ChargeCurve) api.ChargeCurve {
+	res := make(api.ChargeCurve, 0, len(curve))
+	for _, p := range curve {
+		if p.Power > 0 && p.SoC >= 0 && p.SoC <= 100 {
+			res = append(res, p)
+		}
+	}
+
+	sort.SliceStable(res, func(i, j int) bool {
+		return res[i].SoC < res[j].SoC
+	})
+
+	for i := 1; i < len(res); i++ {
+		if res[i].SoC == res[i-1].SoC {
+			res = append(res[:i], res[i+1:]...)
+			i--
+		}
+	}
+
+	if len(res) == 0 {
+		return nil
+	}
+
+	return res
+}
+
+// curveBucket returns the learned charge curve bucket of the given SoC
+func curveBucket(soc float64) int {
+	return int(math.Min(math.Max(soc, 0), 100-curveStep) / curveStep)
+}
+
+// chargeSegment is a SoC interval charged at constant power
+type chargeSegment struct {
+	energy float64 // Wh
+	power  float64 // W
+}
+
+// chargeSegments splits the charge from current to target SoC into segments of one percent
+// charged at the given power limited by the vehicle's charge curve
+func (s *Estimator) chargeSegments(chargePower float64, targetSoC int) []chargeSegment {
+	curve := s.chargeCurve()
+
+	var res []chargeSegment
+	for soc := s.socCharge; soc < float64(targetSoC); {
+		next := math.Min(math.Floor(soc)+1, float64(targetSoC))
+
+		power := chargePower
+		if limit := curvePower(curve, (soc+next)/2); limit > 0 {
+			power = math.Min(power, limit)
+		}
+
+		res = append(res, chargeSegment{
+			energy: (next - soc) / 100 * s.virtualCapacity,
+			power:  power,
+		})
+
+		soc = next
+	}
+
+	return res
+}
+
+// chargeCurve returns the configured charge curve, falling back to the learned curve
+func (s *Estimator) chargeCurve() api.ChargeCurve {
+	if len(s.curve) > 0 {
+		return s.curve
+	}
+	return s.LearnedChargeCurve()
+}
+
+// SetChargeCurve sets the configured charge curve. It takes precedence over the learned curve.
+func (s *Estimator) SetChargeCurve(curve api.ChargeCurve) {
+	s.curve = sortedCurve(curve)
+}
+
+// LearnedChargeCurve returns the charge curve learned from charge power samples, nil if unknown
+func (s *Estimator) LearnedChargeCurve() api.ChargeCurve {
+	var res api.ChargeCurve
+	for i, power := range s.learnedCurve {
+		if power > 0 {
+			res = append(res, api.ChargeCurvePoint{
+				SoC:   float64(i*curveStep) + curveStep/2.0,
+				Power: power,
+			})
+		}
+	}
+	return res
+}
+
+// SetLearnedChargeCurve restores the learned charge curve
+func (s *Estimator) SetLearnedChargeCurve(curve api.ChargeCurve) {
+	s.learnedCurve = [100 / curveStep]float64{}
+	for _, p := range sortedCurve(curve) {
+		s.learnedCurve[curveBucket(p.SoC)] = p.Power
+	}
+}
+
+// LearnChargePower adds a sample of the power the vehicle accepts at the current SoC.
+// Samples must only be taken while the charger offers its maximum power.
+// It returns true if the sample is the first of a new bucket, i.e. the learned curve should be persisted.
+func (s *Estimator) LearnChargePower(power float64) bool {
+	if power <= 0 || s.socCharge <= 0 {
+		return false
+	}
+
+	bucket := curveBucket(s.socCharge)
+	if learned := s.learnedCurve[bucket]; learned > 0 {
+		power = learned + learnWeight*(power-learned)
+	}
+
+	s.learnedCurve[bucket] = power
+	s.log.TRACE.Printf("charge curve learned: %.0fW at %.0f%%", power, s.socCharge)
+
+	changed := bucket != s.curveBucket
+	s.curveBucket = bucket
+
+	return changed
+}
+
+// chargeEnergyBefore returns the energy in Wh charged during the given duration immediately
+// before reaching the target SoC at the given charge power limited by the charge curve
+func (s *Estimator) chargeEnergyBefore(d time.Duration, chargePower float64, targetSoC int) float64 {
+	segments := s.chargeSegments(chargePower, targetSoC)
+	remaining := d.Hours()
+
+	var energy float64
+	for i := len(segments) - 1; i >= 0 && remaining > 0; i-- {
+		seg := segments[i]
+		hours := seg.energy / seg.power
+
+		if hours > remaining {
+			return energy + seg.power*remaining
+		}
+
+		energy += seg.energy
+		remaining -= hours
+	}
+
+	return energy
+}
@@ -0,0 +1,140 @@
+package soc
+
+import (
+	"math"
+	"testing"
+	"time"
+
+	"github.com/andig/evcc/api"
+	"github.com/andig/evcc/mock"
+	"github.com/andig/evcc/util"
+	"github.com/golang/mock/gomock"
+)
+
+func TestCurvePower(t *testing.T) {
+	curve := sortedCurve(api.ChargeCurve{
+		{SoC: 90, Power: 2000},
+		{SoC: 50, Power: 10000},
+		{SoC: 70, Power: 0}, // invalid
+		{SoC: 80, Power: 6000},
+	})
+
+	tc := []struct {
+		soc, power float64
+	}{
+		{0, 10000},
+		{50, 10000},
+		{65, 8000},
+		{80, 6000},
+		{85, 4000},
+		{100, 2000},
+	}
+
+	for _, tc := range tc {
+		if power := curvePower(curve, tc.soc); power != tc.power {
+			t.Errorf("expected %.0fW at %.0f%%, got %.0fW", tc.power, tc.soc, power)
+		}
+	}
+
+	if power := curvePower(nil, 50); power != 0 {
+		t.Errorf("expected no power for empty curve, got %.0fW", power)
+	}
+}
+
+func TestRemainingChargeDurationCurve(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	vehicle := mock.NewMockVehicle(ctrl)
+
+	// 9 kWh user battery capacity is 100Wh per soc percent
+	vehicle.EXPECT().Capacity().Return(int64(9))
+
+	ce := NewEstimator(util.NewLogger("foo"), vehicle, false)
+	ce.socCharge = 70
+
+	// constant 1kW charge power without curve
+	if remaining := ce.RemainingChargeDuration(1000, 90); remaining != 2*time.Hour {
+		t.Errorf("expected 2h remaining, got %v", remaining)
+	}
+
+	// 500W above 80%
+	ce.SetChargeCurve(api.ChargeCurve{{SoC: 0, Power: 1000}, {SoC: 80, Power: 1000}, {SoC: 80.001, Power: 500}})
+	if remaining := ce.RemainingChargeDuration(1000, 90); remaining != 3*time.Hour {
+		t.Errorf("expected 3h remaining, got %v", remaining)
+	}
+
+	// energy charged during last hours at 500W and 1kW
+	for d, expected := range map[time.Duration]float64{
+		90 * time.Minute:  750,
+		150 * time.Minute: 1500,
+		4 * time.Hour:     2000,
+	} {
+		if energy := ce.chargeEnergyBefore(d, 1000, 90); math.Abs(energy-expected) > 1e-6 {
+			t.Errorf("expected %.0fWh within %v, got %.0fWh", expected, d, energy)
+		}
+	}
+
+	// grid charging covers the tapered range
+	targetTime := time.Date(2021, 1, 1, 12, 0, 0, 0, time.UTC)
+	gridEnergy := func(d time.Duration) float64 {
+		return ce.chargeEnergyBefore(d, 1000, 90)
+	}
+
+	if grid, _ := solarPlan(nil, targetTime.Add(-6*time.Hour), targetTime, 2000, 1000, gridEnergy); grid != 3*time.Hour {
+		t.Errorf("expected 3h grid charging, got %v", grid)
+	}
+}
+
+func TestLearnChargeCurve(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	vehicle := mock.NewMockVehicle(ctrl)
+	vehicle.EXPECT().Capacity().Return(int64(9)).AnyTimes()
+
+	ce := NewEstimator(util.NewLogger("foo"), vehicle, false)
+
+	// no soc
+	if ce.LearnChargePower(11000) {
+		t.Error("unexpected bucket change")
+	}
+	if curve := ce.LearnedChargeCurve(); curve != nil {
+		t.Errorf("expected empty curve, got %v", curve)
+	}
+
+	// persist on bucket change only
+	ce.socCharge = 42
+	for i, tc := range []struct {
+		power   float64
+		changed bool
+	}{
+		{11000, true},
+		{7000, false},
+	} {
+		if changed := ce.LearnChargePower(tc.power); changed != tc.changed {
+			t.Errorf("%d: expected bucket change %v, got %v", i, tc.changed, changed)
+		}
+	}
+
+	ce.socCharge = 91
+	if !ce.LearnChargePower(3000) {
+		t.Error("expected bucket change")
+	}
+
+	expected := api.ChargeCurve{{SoC: 42.5, Power: 10000}, {SoC: 92.5, Power: 3000}}
+
+	curve := ce.LearnedChargeCurve()
+	if len(curve) != len(expected) || curve[0] != expected[0] || curve[1] != expected[1] {
+		t.Errorf("expected curve %v, got %v", expected, curve)
+	}
+
+	// restored curve is used unless configured
+	ce = NewEstimator(util.NewLogger("foo"), vehicle, false)
+	ce.SetLearnedChargeCurve(curve)
+
+	if power := curvePower(ce.chargeCurve(), 95); power != 3000 {
+		t.Errorf("expected learned 3000W, got %.0fW", power)
+	}
+
+	ce.SetChargeCurve(api.ChargeCurve{{SoC: 0, Power: 5000}})
+	if power := curvePower(ce.chargeCurve(), 95); power != 5000 {
+		t.Errorf("expected configured 5000W, got %.0fW", power)
+	}
+}
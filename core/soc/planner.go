@@ -0,0 +1,109 @@
+package soc
+
+import (
+	"sort"
+	"time"
+
+	"github.com/andig/evcc/api"
+	"github.com/andig/evcc/util"
+)
+
+// Planner selects the cheapest tariff slots for reaching the target soc before the target time
+type Planner struct {
+	Adapter
+	log        *util.Logger
+	tariff     api.Tariff
+	maxCurrent int64
+}
+
+// NewPlanner creates a Planner
+func NewPlanner(log *util.Logger, adapter Adapter, tariff api.Tariff, maxCurrent int64) *Planner {
+	lp := &Planner{
+		log:        log,
+		Adapter:    adapter,
+		tariff:     tariff,
+		maxCurrent: maxCurrent,
+	}
+
+	return lp
+}
+
+// Configured returns true if a tariff is available for planning
+func (lp *Planner) Configured() bool {
+	return lp != nil && lp.tariff != nil
+}
+
+// Active returns true if the current time falls into one of the cheapest slots required
+// to reach the target soc. If target time is zero all known slots are taken into account.
+func (lp *Planner) Active(targetSoC int, targetTime time.Time) bool {
+	if lp == nil || lp.tariff == nil {
+		return false
+	}
+
+	se := lp.SocEstimator()
+	if se == nil {
+		lp.log.DEBUG.Println("plan: vehicle soc required")
+		return false
+	}
+
+	power := float64(lp.maxCurrent*lp.ActivePhases()) * lp.Voltage()
+	requiredDuration := se.RemainingChargeDuration(power, targetSoC)
+	if requiredDuration <= 0 {
+		lp.Publish("planActive", false)
+		return false
+	}
+
+	rates, err := lp.tariff.Rates()
+	if err != nil {
+		lp.log.ERROR.Printf("plan: %v", err)
+		return false
+	}
+
+	active := lp.plan(lp.Clock().Now(), rates, requiredDuration, targetTime)
+	lp.log.DEBUG.Printf("plan: %v required until %v, active: %v", requiredDuration.Round(time.Minute), targetTime, active)
+	lp.Publish("planActive", active)
+
+	return active
+}
+
+// plan selects the cheapest slots covering the required duration and returns true if the current slot is selected
+func (lp *Planner) plan(now time.Time, rates api.Rates, requiredDuration time.Duration, targetTime time.Time) bool {
+	// consider only slots between now and target time
+	slots := make(api.Rates, 0, len(rates))
+	for _, slot := range rates {
+		if !slot.End.After(now) || !targetTime.IsZero() && !slot.Start.Before(targetTime) {
+			continue
+		}
+
+		if slot.Start.Before(now) {
+			slot.Start = now
+		}
+		if !targetTime.IsZero() && slot.End.After(targetTime) {
+			slot.End = targetTime
+		}
+
+		slots = append(slots, slot)
+	}
+
+	// cheapest first, earlier slot wins for identical prices
+	sort.SliceStable(slots, func(i, j int) bool {
+		if slots[i].Price == slots[j].Price {
+			return slots[i].Start.Before(slots[j].Start)
+		}
+		return slots[i].Price < slots[j].Price
+	})
+
+	for _, slot := range slots {
+		if requiredDuration <= 0 {
+			break
+		}
+
+		if !slot.Start.After(now) && slot.End.After(now) {
+			return true
+		}
+
+		requiredDuration -= slot.End.Sub(slot.Start)
+	}
+
+	return false
+}
@@ -0,0 +1,48 @@
+package soc
+
+import (
+	"testing"
+	"time"
+
+	"github.com/andig/evcc/api"
+	"github.com/andig/evcc/util"
+	"github.com/benbjohnson/clock"
+)
+
+func TestPlan(t *testing.T) {
+	now := clock.NewMock().Now()
+
+	// hourly rates starting now: 3, 1, 2, 1
+	var rates api.Rates
+	for i, price := range []float64{3, 1, 2, 1} {
+		start := now.Add(time.Duration(i) * time.Hour)
+		rates = append(rates, api.Rate{Start: start, End: start.Add(time.Hour), Price: price})
+	}
+
+	p := &Planner{
+		log: util.NewLogger("foo"),
+	}
+
+	tc := []struct {
+		offset     time.Duration // time since start
+		required   time.Duration
+		targetTime time.Time
+		active     bool
+	}{
+		{0, time.Hour, time.Time{}, false},                          // cheapest slot is next hour
+		{time.Hour, time.Hour, time.Time{}, true},                   // cheapest slot
+		{0, 3 * time.Hour, time.Time{}, false},                      // three cheapest slots exclude first
+		{0, 4 * time.Hour, time.Time{}, true},                       // all slots required
+		{0, time.Hour, now.Add(time.Hour), true},                    // target time leaves only first slot
+		{2 * time.Hour, time.Hour, now.Add(3 * time.Hour), true},    // only single slot left before target
+		{2 * time.Hour, time.Hour, time.Time{}, false},              // last slot is cheaper
+		{90 * time.Minute, time.Hour, now.Add(4 * time.Hour), true}, // remainder of current slot is cheapest
+		{5 * time.Hour, time.Hour, time.Time{}, false},              // no rates left
+	}
+
+	for _, tc := range tc {
+		if active := p.plan(now.Add(tc.offset), rates, tc.required, tc.targetTime); active != tc.active {
+			t.Errorf("%+v: expected %v, got %v", tc, tc.active, active)
+		}
+	}
+}
@@ -9,7 +9,15 @@ import (
 	"github.com/andig/evcc/util"
 )
 
-const chargeEfficiency = 0.9 // assume charge 90% efficiency
+const (
+	chargeEfficiency = 0.9 // assume charge 90% efficiency
+
+	minLearnEfficiency = 0.7  // samples below this charge efficiency are outliers
+	maxLearnEfficiency = 1.0  // samples above this charge efficiency are outliers
+	maxLearnDeviation  = 0.2  // samples deviating more from the learned value are outliers
+	learnWeight        = 0.25 // weight of each sample when learning
+	minLearnSamples    = 3    // consistent samples required to learn initially or to replace the learned value
+)
 
 // Estimator provides vehicle soc and charge duration
 // Vehicle SoC can be estimated to provide more granularity
@@ -18,12 +26,18 @@ type Estimator struct {
 	vehicle  api.Vehicle
 	estimate bool
 
-	capacity          float64 // vehicle capacity in Wh cached to simplify testing
-	virtualCapacity   float64 // estimated virtual vehicle capacity in Wh
-	socCharge         float64 // estimated vehicle SoC
-	prevSoC           float64 // previous vehicle SoC in %
-	prevChargedEnergy float64 // previous charged energy in Wh
-	energyPerSocStep  float64 // Energy per SoC percent in Wh
+	capacity          float64   // vehicle capacity in Wh cached to simplify testing
+	virtualCapacity   float64   // estimated virtual vehicle capacity in Wh
+	socCharge         float64   // estimated vehicle SoC
+	prevSoC           float64   // previous vehicle SoC in %
+	prevChargedEnergy float64   // previous charged energy in Wh
+	energyPerSocStep  float64   // Energy per SoC percent in Wh
+	learned           float64   // Energy per SoC percent in Wh learned across sessions, zero if unknown
+	pending           []float64 // consistent samples not yet learned
+
+	curve        api.ChargeCurve          // configured charge curve
+	learnedCurve [100 / curveStep]float64 // learned charge power in W per SoC bucket, zero if unknown
+	curveBucket  int                      // bucket of the last charge power sample, -1 if none
 }
 
 // NewEstimator creates new estimator
@@ -43,12 +57,82 @@ func NewEstimator(log *util.Logger, vehicle api.Vehicle, estimate bool) *Estimat
 func (s *Estimator) Reset() {
 	s.prevSoC = 0
 	s.prevChargedEnergy = 0
+	s.curveBucket = -1
 	s.capacity = float64(s.vehicle.Capacity()) * 1e3  // cache to simplify debugging
 	s.virtualCapacity = s.capacity / chargeEfficiency // initial capacity taking efficiency into account
+
+	// prefer learned value
+	if s.learned > 0 {
+		s.virtualCapacity = s.learned * 100
+	}
+
 	s.energyPerSocStep = s.virtualCapacity / 100
 }
 
-// RemainingChargeDuration returns the remaining duration estimate based on SoC, target and charge power
+// EnergyPerSocStep returns the learned energy per SoC percent in Wh, zero if unknown
+func (s *Estimator) EnergyPerSocStep() float64 {
+	return s.learned
+}
+
+// SetEnergyPerSocStep restores the learned energy per SoC percent in Wh
+func (s *Estimator) SetEnergyPerSocStep(energy float64) {
+	s.learned = energy
+	s.Reset()
+}
+
+// learn updates the learned energy per SoC percent from a gradient sample unless it is an outlier.
+// Initially, or if samples repeatedly deviate from the learned value, the learned value is
+// replaced by the average of consistent samples.
+func (s *Estimator) learn(energyPerSocStep float64) {
+	if s.capacity > 0 {
+		efficiency := s.capacity / 100 / energyPerSocStep
+		if efficiency < minLearnEfficiency || efficiency > maxLearnEfficiency {
+			s.log.DEBUG.Printf("soc gradient outlier: %.0fWh (efficiency %.0f%%)", energyPerSocStep, 100*efficiency)
+			return
+		}
+	}
+
+	if s.learned > 0 && !deviates(energyPerSocStep, s.learned) {
+		s.pending = nil
+		s.learned += learnWeight * (energyPerSocStep - s.learned)
+		s.log.DEBUG.Printf("soc gradient learned: %.0fWh", s.learned)
+		return
+	}
+
+	// restart collecting if the sample does not match previous samples
+	if len(s.pending) > 0 && deviates(energyPerSocStep, average(s.pending)) {
+		s.pending = nil
+	}
+
+	s.pending = append(s.pending, energyPerSocStep)
+
+	if len(s.pending) < minLearnSamples {
+		s.log.DEBUG.Printf("soc gradient sample: %.0fWh (learned %.0fWh, %d/%d consistent samples)", energyPerSocStep, s.learned, len(s.pending), minLearnSamples)
+		return
+	}
+
+	s.learned = average(s.pending)
+	s.pending = nil
+
+	s.log.DEBUG.Printf("soc gradient learned: %.0fWh", s.learned)
+}
+
+// deviates returns true if the sample deviates from the reference by more than the allowed deviation
+func deviates(sample, reference float64) bool {
+	return math.Abs(sample-reference) > maxLearnDeviation*reference
+}
+
+// average returns the arithmetic mean of the values
+func average(values []float64) float64 {
+	var sum float64
+	for _, v := range values {
+		sum += v
+	}
+	return sum / float64(len(values))
+}
+
+// RemainingChargeDuration returns the remaining duration estimate based on SoC, target and charge power.
+// Charge power is limited by the vehicle's charge curve if known.
 func (s *Estimator) RemainingChargeDuration(chargePower float64, targetSoC int) time.Duration {
 	if chargePower > 0 {
 		percentRemaining := float64(targetSoC) - s.socCharge
@@ -69,9 +153,19 @@ func (s *Estimator) RemainingChargeDuration(chargePower float64, targetSoC int)
 			}
 		}
 
-		// estimate remaining time
-		whRemaining := percentRemaining / 100 * s.virtualCapacity
-		return time.Duration(float64(time.Hour) * whRemaining / chargePower).Round(time.Second)
+		// estimate remaining time at constant power
+		curve := s.chargeCurve()
+		if len(curve) == 0 {
+			whRemaining := percentRemaining / 100 * s.virtualCapacity
+			return time.Duration(float64(time.Hour) * whRemaining / chargePower).Round(time.Second)
+		}
+
+		// integrate over charge curve
+		var hours float64
+		for _, seg := range s.chargeSegments(chargePower, targetSoC) {
+			hours += seg.energy / seg.power
+		}
+		return time.Duration(float64(time.Hour) * hours).Round(time.Second)
 	}
 
 	return -1
@@ -105,26 +199,26 @@ func (s *Estimator) SoC(chargedEnergy float64) (float64, error) {
 
 	s.socCharge = f
 
-	if s.estimate {
-		socDelta := s.socCharge - s.prevSoC
-		energyDelta := math.Max(chargedEnergy, 0) - s.prevChargedEnergy
-
-		if socDelta != 0 || energyDelta < 0 { // soc value change or unexpected energy reset
-			// calculate gradient, wh per soc %
-			// TODO: drop samples with unmatching state of evse and vehicle
-			if socDelta > 2 && energyDelta > 0 && s.prevSoC > 0 {
-				s.energyPerSocStep = energyDelta / socDelta
-				s.virtualCapacity = s.energyPerSocStep * 100
-				s.log.TRACE.Printf("soc gradient updated: energyPerSocStep: %0.0fWh, virtualCapacity: %0.0fWh", s.energyPerSocStep, s.virtualCapacity)
-			}
+	socDelta := s.socCharge - s.prevSoC
+	energyDelta := math.Max(chargedEnergy, 0) - s.prevChargedEnergy
 
-			// sample charged energy at soc change, reset energy delta
-			s.prevChargedEnergy = math.Max(chargedEnergy, 0)
-			s.prevSoC = s.socCharge
-		} else {
-			s.socCharge = math.Min(f+energyDelta/s.energyPerSocStep, 100)
-			s.log.TRACE.Printf("soc estimated: %.2f%% (vehicle: %.2f%%)", s.socCharge, f)
+	if socDelta != 0 || energyDelta < 0 { // soc value change or unexpected energy reset
+		// calculate gradient, wh per soc %
+		// TODO: drop samples with unmatching state of evse and vehicle
+		if socDelta > 2 && energyDelta > 0 && s.prevSoC > 0 {
+			s.energyPerSocStep = energyDelta / socDelta
+			s.virtualCapacity = s.energyPerSocStep * 100
+			s.log.TRACE.Printf("soc gradient updated: energyPerSocStep: %0.0fWh, virtualCapacity: %0.0fWh", s.energyPerSocStep, s.virtualCapacity)
+
+			s.learn(s.energyPerSocStep)
 		}
+
+		// sample charged energy at soc change, reset energy delta
+		s.prevChargedEnergy = math.Max(chargedEnergy, 0)
+		s.prevSoC = s.socCharge
+	} else if s.estimate {
+		s.socCharge = math.Min(f+energyDelta/s.energyPerSocStep, 100)
+		s.log.TRACE.Printf("soc estimated: %.2f%% (vehicle: %.2f%%)", s.socCharge, f)
 	}
 
 	return s.socCharge, nil
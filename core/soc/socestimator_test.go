@@ -100,3 +100,57 @@ func TestSoCEstimation(t *testing.T) {
 		}
 	}
 }
+
+func TestLearnEnergyPerSocStep(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	vehicle := mock.NewMockVehicle(ctrl)
+
+	// 9 kWh user battery capacity is 90Wh per soc percent at 100% efficiency
+	vehicle.EXPECT().Capacity().Return(int64(9)).AnyTimes()
+
+	ce := NewEstimator(util.NewLogger("foo"), vehicle, true)
+
+	tc := []struct {
+		chargedEnergy float64
+		vehicleSoC    float64
+		learned       float64
+	}{
+		{0, 10, 0},         // initial soc
+		{1000, 20, 0},      // first sample
+		{3000, 30, 0},      // efficiency outlier
+		{4000, 40, 0},      // second sample
+		{5030, 50, 101},    // learn average of consistent samples
+		{6130, 60, 103.25}, // learn
+		{7380, 70, 103.25}, // deviation outlier
+		{8630, 80, 103.25}, // deviation outlier
+		{9880, 90, 125},    // replace by consistent outliers
+	}
+
+	for _, tc := range tc {
+		t.Logf("%+v", tc)
+		vehicle.EXPECT().SoC().Return(tc.vehicleSoC, nil)
+
+		if _, err := ce.SoC(tc.chargedEnergy); err != nil {
+			t.Error(err)
+		}
+
+		if learned := ce.EnergyPerSocStep(); learned != tc.learned {
+			t.Errorf("expected learned energy per soc: %v, got: %v", tc.learned, learned)
+		}
+	}
+
+	// next session starts with learned value
+	ce.Reset()
+	if ce.virtualCapacity != 12500 {
+		t.Errorf("expected virtual capacity: %v, got: %v", 12500, ce.virtualCapacity)
+	}
+
+	// restored value
+	ce = NewEstimator(util.NewLogger("foo"), vehicle, true)
+	ce.SetEnergyPerSocStep(120)
+	ce.socCharge = 50
+
+	if energy := ce.RemainingChargeEnergy(100); energy != 6 {
+		t.Errorf("expected remaining energy: %v, got: %v", 6, energy)
+	}
+}
@@ -4,7 +4,9 @@ import (
 	"math"
 	"time"
 
+	"github.com/andig/evcc/api"
 	"github.com/andig/evcc/util"
+	"github.com/benbjohnson/clock"
 )
 
 const (
@@ -17,6 +19,7 @@ type Adapter interface {
 	SocEstimator() *Estimator
 	ActivePhases() int64
 	Voltage() float64
+	Clock() clock.Clock
 }
 
 // Timer is the target charging handler
@@ -27,6 +30,7 @@ type Timer struct {
 	current        float64
 	SoC            int
 	Time           time.Time
+	Forecast       api.SolarForecast
 	finishAt       time.Time
 	chargeRequired bool
 }
@@ -68,7 +72,10 @@ func (lp *Timer) StartRequired() bool {
 
 	// time
 	remainingDuration := se.RemainingChargeDuration(power, lp.SoC)
-	lp.finishAt = time.Now().Add(remainingDuration).Round(time.Minute)
+	if lp.Forecast != nil {
+		remainingDuration = lp.gridDuration(se, power)
+	}
+	lp.finishAt = lp.Clock().Now().Add(remainingDuration).Round(time.Minute)
 	lp.log.DEBUG.Printf("target charging active for %v: projected %v (%v remaining)", lp.Time, lp.finishAt, remainingDuration.Round(time.Minute))
 
 	lp.chargeRequired = lp.finishAt.After(lp.Time)
@@ -77,9 +84,30 @@ func (lp *Timer) StartRequired() bool {
 	return lp.chargeRequired
 }
 
+// gridDuration returns the duration of grid charging required to reach the target soc
+// in time after using the forecasted pv energy and publishes the charging plan. Grid charging
+// covers the final SoC range where charge power is limited by the vehicle's charge curve.
+func (lp *Timer) gridDuration(se *Estimator, power float64) time.Duration {
+	forecast, err := lp.Forecast.Forecast()
+	if err != nil {
+		lp.log.ERROR.Printf("forecast: %v", err)
+	}
+
+	energy := se.RemainingChargeEnergy(lp.SoC) * 1e3
+	gridEnergy := func(d time.Duration) float64 {
+		return se.chargeEnergyBefore(d, power, lp.SoC)
+	}
+
+	gridDuration, plan := solarPlan(forecast, lp.Clock().Now(), lp.Time, energy, power, gridEnergy)
+	lp.log.DEBUG.Printf("target charging: %v grid charging required after pv", gridDuration.Round(time.Minute))
+	lp.Publish("plan", plan)
+
+	return gridDuration
+}
+
 // active returns true if there is an active target charging request
 func (lp *Timer) active() bool {
-	inactive := lp.Time.IsZero() || lp.Time.Before(time.Now())
+	inactive := lp.Time.IsZero() || lp.Time.Before(lp.Clock().Now())
 	lp.Publish("timerSet", !inactive)
 
 	// reset active
@@ -0,0 +1,104 @@
+package soc
+
+import (
+	"math"
+	"time"
+
+	"github.com/andig/evcc/api"
+)
+
+// energyTolerance is the rounding tolerance for comparing integrated energies in Wh
+const energyTolerance = 0.01
+
+// Plan sources
+const (
+	SourcePV   = "pv"
+	SourceGrid = "grid"
+)
+
+// PlanSlot is a planned charging slot
+type PlanSlot struct {
+	Start  time.Time `json:"start"`
+	End    time.Time `json:"end"`
+	Source string    `json:"source"`
+	Power  float64   `json:"power"` // W
+}
+
+// solarEnergy returns the forecasted pv energy in Wh usable for charging between from and to
+func solarEnergy(forecast api.Forecast, from, to time.Time, maxPower float64) float64 {
+	var energy float64
+	for _, slot := range clipForecast(forecast, from, to) {
+		energy += math.Min(slot.Power, maxPower) * slot.End.Sub(slot.Start).Hours()
+	}
+	return energy
+}
+
+// clipForecast returns the forecast slots between from and to
+func clipForecast(forecast api.Forecast, from, to time.Time) api.Forecast {
+	var res api.Forecast
+	for _, slot := range forecast {
+		if !slot.End.After(from) || !slot.Start.Before(to) || slot.Power <= 0 {
+			continue
+		}
+
+		if slot.Start.Before(from) {
+			slot.Start = from
+		}
+		if slot.End.After(to) {
+			slot.End = to
+		}
+
+		res = append(res, slot)
+	}
+	return res
+}
+
+// solarPlan determines the grid charging duration required immediately before the target time
+// if forecasted pv power is used for charging until then. Energy is given in Wh, charge power in W.
+// Grid energy returns the energy charged within the grid duration, constant charge power if nil.
+func solarPlan(forecast api.Forecast, now, targetTime time.Time, energy, power float64, gridEnergy func(time.Duration) float64) (time.Duration, []PlanSlot) {
+	if power <= 0 {
+		return 0, nil
+	}
+
+	if gridEnergy == nil {
+		gridEnergy = func(d time.Duration) float64 {
+			return power * d.Hours()
+		}
+	}
+
+	// extend grid charging until pv energy before and grid energy afterwards are sufficient
+	var gridDuration time.Duration
+	var prevGrid float64
+	for {
+		solar := solarEnergy(forecast, now, targetTime.Add(-gridDuration), power)
+		grid := gridEnergy(gridDuration)
+		if solar+grid >= energy-energyTolerance || gridDuration > 0 && grid <= prevGrid {
+			break
+		}
+
+		prevGrid = grid
+		gridDuration += time.Minute
+	}
+
+	var plan []PlanSlot
+	for _, slot := range clipForecast(forecast, now, targetTime.Add(-gridDuration)) {
+		plan = append(plan, PlanSlot{
+			Start:  slot.Start,
+			End:    slot.End,
+			Source: SourcePV,
+			Power:  math.Min(slot.Power, power),
+		})
+	}
+
+	if gridDuration > 0 {
+		plan = append(plan, PlanSlot{
+			Start:  targetTime.Add(-gridDuration),
+			End:    targetTime,
+			Source: SourceGrid,
+			Power:  power,
+		})
+	}
+
+	return gridDuration, plan
+}
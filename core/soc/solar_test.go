@@ -0,0 +1,48 @@
+package soc
+
+import (
+	"testing"
+	"time"
+
+	"github.com/andig/evcc/api"
+)
+
+func TestSolarPlan(t *testing.T) {
+	now := time.Date(2021, 1, 1, 10, 0, 0, 0, time.UTC)
+	targetTime := now.Add(4 * time.Hour)
+
+	// hourly forecast starting now: 0, 5, 5, 1 kW
+	var forecast api.Forecast
+	for i, power := range []float64{0, 5000, 5000, 1000} {
+		start := now.Add(time.Duration(i) * time.Hour)
+		forecast = append(forecast, api.ForecastSlot{Start: start, End: start.Add(time.Hour), Power: power})
+	}
+
+	tc := []struct {
+		forecast api.Forecast
+		energy   float64 // Wh
+		power    float64 // W
+		grid     time.Duration
+	}{
+		{forecast, 5000, 10000, 0},              // pv sufficient
+		{forecast, 11000, 10000, 0},             // pv exactly sufficient
+		{forecast, 20000, 10000, time.Hour},     // grid replaces last pv hour
+		{forecast, 30000, 10000, 3 * time.Hour}, // grid replaces most pv
+		{nil, 20000, 10000, 2 * time.Hour},      // no forecast
+		{forecast, 12000, 3000, 4 * time.Hour},  // pv limited by charge power
+		{forecast, 20000, 5000, 4 * time.Hour},  // grid charging until target
+	}
+
+	for _, tc := range tc {
+		t.Logf("%+v", tc)
+
+		grid, plan := solarPlan(tc.forecast, now, targetTime, tc.energy, tc.power, nil)
+		if grid != tc.grid {
+			t.Errorf("expected %v grid charging, got %v", tc.grid, grid)
+		}
+
+		if grid > 0 && (len(plan) == 0 || plan[len(plan)-1].Source != SourceGrid || !plan[len(plan)-1].End.Equal(targetTime)) {
+			t.Errorf("expected final grid slot, got %+v", plan)
+		}
+	}
+}
@@ -0,0 +1,41 @@
+package forecast
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/andig/evcc/api"
+)
+
+type forecastRegistry map[string]func(map[string]interface{}) (api.SolarForecast, error)
+
+func (r forecastRegistry) Add(name string, factory func(map[string]interface{}) (api.SolarForecast, error)) {
+	if _, exists := r[name]; exists {
+		panic(fmt.Sprintf("cannot register duplicate forecast type: %s", name))
+	}
+	r[name] = factory
+}
+
+func (r forecastRegistry) Get(name string) (func(map[string]interface{}) (api.SolarForecast, error), error) {
+	factory, exists := r[name]
+	if !exists {
+		return nil, fmt.Errorf("forecast type not registered: %s", name)
+	}
+	return factory, nil
+}
+
+var registry forecastRegistry = make(map[string]func(map[string]interface{}) (api.SolarForecast, error))
+
+// NewFromConfig creates solar forecast from configuration
+func NewFromConfig(typ string, other map[string]interface{}) (v api.SolarForecast, err error) {
+	factory, err := registry.Get(strings.ToLower(typ))
+	if err == nil {
+		if v, err = factory(other); err != nil {
+			err = fmt.Errorf("cannot create type '%s': %w", typ, err)
+		}
+	} else {
+		err = fmt.Errorf("invalid forecast type: %s", typ)
+	}
+
+	return
+}
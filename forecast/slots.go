@@ -0,0 +1,52 @@
+package forecast
+
+import (
+	"github.com/andig/evcc/api"
+	"github.com/andig/evcc/provider/slots"
+	"github.com/andig/evcc/util"
+)
+
+// Slots is a solar forecast retrieved from a JSON web service or local file
+type Slots struct {
+	*slots.Provider
+}
+
+func init() {
+	registry.Add("http", NewHTTPFromConfig)
+	registry.Add("file", NewFileFromConfig)
+}
+
+// NewHTTPFromConfig creates a HTTP solar forecast from config
+func NewHTTPFromConfig(other map[string]interface{}) (api.SolarForecast, error) {
+	p, err := slots.NewHTTPFromConfig(util.NewLogger("forecast"), "power", other)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Slots{p}, nil
+}
+
+// NewFileFromConfig creates a file solar forecast from config
+func NewFileFromConfig(other map[string]interface{}) (api.SolarForecast, error) {
+	p, err := slots.NewFileFromConfig(util.NewLogger("forecast"), "power", other)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Slots{p}, nil
+}
+
+// Forecast implements the api.SolarForecast interface
+func (f *Slots) Forecast() (api.Forecast, error) {
+	s, err := f.Slots()
+	if err != nil {
+		return nil, err
+	}
+
+	res := make(api.Forecast, 0, len(s))
+	for _, slot := range s {
+		res = append(res, api.ForecastSlot{Start: slot.Start, End: slot.End, Power: slot.Value})
+	}
+
+	return res, nil
+}
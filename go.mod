@@ -55,6 +55,7 @@ require (
 	github.com/tv42/httpunix v0.0.0-20191220191345-2ba4b9c3382c
 	github.com/uhthomas/tesla v0.0.0-20210202211959-8f97ef33b7b3
 	github.com/volkszaehler/mbmd v0.0.0-20210117183837-59dcc46d62d4
+	go.etcd.io/bbolt v1.3.5
 	golang.org/x/net v0.0.0-20201216054612-986b41b23924
 	golang.org/x/oauth2 v0.0.0-20210126194326-f9ce19ea3013
 	gopkg.in/sourcemap.v1 v1.0.5 // indirect
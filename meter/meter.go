@@ -13,7 +13,7 @@ func init() {
 	registry.Add("default", NewConfigurableFromConfig)
 }
 
-//go:generate go run ../cmd/tools/decorate.go -p meter -f decorateMeter -b api.Meter -o meter_decorators -t "api.MeterEnergy,TotalEnergy,func() (float64, error)" -t "api.MeterCurrent,Currents,func() (float64, float64, float64, error)" -t "api.Battery,SoC,func() (float64, error)"
+//go:generate go run ../cmd/tools/decorate.go -p meter -f decorateMeter -b api.Meter -o meter_decorators -t "api.MeterEnergy,TotalEnergy,func() (float64, error)" -t "api.MeterCurrent,Currents,func() (float64, float64, float64, error)" -t "api.Battery,SoC,func() (float64, error)" -t "api.BatteryController,LockDischarge,func(lock bool) error"
 
 // NewConfigurableFromConfig creates api.Meter from config
 func NewConfigurableFromConfig(other map[string]interface{}) (api.Meter, error) {
@@ -22,6 +22,7 @@ func NewConfigurableFromConfig(other map[string]interface{}) (api.Meter, error)
 		Energy   *provider.Config  // optional
 		SoC      *provider.Config  // optional
 		Currents []provider.Config // optional
+		Lock     *provider.Config  // optional
 	}{}
 
 	if err := util.DecodeOther(other, &cc); err != nil {
@@ -73,7 +74,15 @@ func NewConfigurableFromConfig(other map[string]interface{}) (api.Meter, error)
 		}
 	}
 
-	res := m.Decorate(m.totalEnergyG, m.currentsG, m.batterySoCG)
+	// decorate Meter with BatteryController
+	if cc.Lock != nil {
+		m.lockDischargeS, err = provider.NewBoolSetterFromConfig("lock", *cc.Lock)
+		if err != nil {
+			return nil, fmt.Errorf("lock: %w", err)
+		}
+	}
+
+	res := m.Decorate(m.totalEnergyG, m.currentsG, m.batterySoCG, m.lockDischargeS)
 
 	return res, nil
 }
@@ -88,10 +97,11 @@ func NewConfigurable(currentPowerG func() (float64, error)) (*Meter, error) {
 
 // Meter is an api.Meter implementation with configurable getters and setters.
 type Meter struct {
-	currentPowerG func() (float64, error)
-	totalEnergyG  func() (float64, error)
-	currentsG     []func() (float64, error)
-	batterySoCG   func() (float64, error)
+	currentPowerG  func() (float64, error)
+	totalEnergyG   func() (float64, error)
+	currentsG      []func() (float64, error)
+	batterySoCG    func() (float64, error)
+	lockDischargeS func(bool) error
 }
 
 // Decorate attaches additional capabilities to the base meter
@@ -99,6 +109,7 @@ func (m *Meter) Decorate(
 	totalEnergyG func() (float64, error),
 	currentsG []func() (float64, error),
 	batterySoCG func() (float64, error),
+	lockDischargeS func(bool) error,
 ) api.Meter {
 	var totalEnergy func() (float64, error)
 	if totalEnergyG != nil {
@@ -118,7 +129,13 @@ func (m *Meter) Decorate(
 		batterySoC = m.batterySoC
 	}
 
-	return decorateMeter(m, totalEnergy, currents, batterySoC)
+	var lockDischarge func(bool) error
+	if lockDischargeS != nil {
+		m.lockDischargeS = lockDischargeS
+		lockDischarge = m.lockDischarge
+	}
+
+	return decorateMeter(m, totalEnergy, currents, batterySoC, lockDischarge)
 }
 
 // CurrentPower implements the Meter.CurrentPower interface
@@ -150,3 +167,8 @@ func (m *Meter) currents() (float64, float64, float64, error) {
 func (m *Meter) batterySoC() (float64, error) {
 	return m.batterySoCG()
 }
+
+// lockDischarge implements the BatteryController.LockDischarge interface
+func (m *Meter) lockDischarge(lock bool) error {
+	return m.lockDischargeS(lock)
+}
@@ -6,12 +6,12 @@ import (
 	"github.com/andig/evcc/api"
 )
 
-func decorateMeter(base api.Meter, meterEnergy func() (float64, error), meterCurrent func() (float64, float64, float64, error), battery func() (float64, error)) api.Meter {
+func decorateMeter(base api.Meter, meterEnergy func() (float64, error), meterCurrent func() (float64, float64, float64, error), battery func() (float64, error), batteryController func(lock bool) error) api.Meter {
 	switch {
-	case battery == nil && meterCurrent == nil && meterEnergy == nil:
+	case battery == nil && batteryController == nil && meterCurrent == nil && meterEnergy == nil:
 		return base
 
-	case battery == nil && meterCurrent == nil && meterEnergy != nil:
+	case battery == nil && batteryController == nil && meterCurrent == nil && meterEnergy != nil:
 		return &struct {
 			api.Meter
 			api.MeterEnergy
@@ -22,7 +22,7 @@ func decorateMeter(base api.Meter, meterEnergy func() (float64, error), meterCur
 			},
 		}
 
-	case battery == nil && meterCurrent != nil && meterEnergy == nil:
+	case battery == nil && batteryController == nil && meterCurrent != nil && meterEnergy == nil:
 		return &struct {
 			api.Meter
 			api.MeterCurrent
@@ -33,7 +33,7 @@ func decorateMeter(base api.Meter, meterEnergy func() (float64, error), meterCur
 			},
 		}
 
-	case battery == nil && meterCurrent != nil && meterEnergy != nil:
+	case battery == nil && batteryController == nil && meterCurrent != nil && meterEnergy != nil:
 		return &struct {
 			api.Meter
 			api.MeterCurrent
@@ -48,7 +48,7 @@ func decorateMeter(base api.Meter, meterEnergy func() (float64, error), meterCur
 			},
 		}
 
-	case battery != nil && meterCurrent == nil && meterEnergy == nil:
+	case battery != nil && batteryController == nil && meterCurrent == nil && meterEnergy == nil:
 		return &struct {
 			api.Meter
 			api.Battery
@@ -59,7 +59,7 @@ func decorateMeter(base api.Meter, meterEnergy func() (float64, error), meterCur
 			},
 		}
 
-	case battery != nil && meterCurrent == nil && meterEnergy != nil:
+	case battery != nil && batteryController == nil && meterCurrent == nil && meterEnergy != nil:
 		return &struct {
 			api.Meter
 			api.Battery
@@ -74,7 +74,7 @@ func decorateMeter(base api.Meter, meterEnergy func() (float64, error), meterCur
 			},
 		}
 
-	case battery != nil && meterCurrent != nil && meterEnergy == nil:
+	case battery != nil && batteryController == nil && meterCurrent != nil && meterEnergy == nil:
 		return &struct {
 			api.Meter
 			api.Battery
@@ -89,7 +89,7 @@ func decorateMeter(base api.Meter, meterEnergy func() (float64, error), meterCur
 			},
 		}
 
-	case battery != nil && meterCurrent != nil && meterEnergy != nil:
+	case battery != nil && batteryController == nil && meterCurrent != nil && meterEnergy != nil:
 		return &struct {
 			api.Meter
 			api.Battery
@@ -107,6 +107,142 @@ func decorateMeter(base api.Meter, meterEnergy func() (float64, error), meterCur
 				meterEnergy: meterEnergy,
 			},
 		}
+
+	case battery == nil && batteryController != nil && meterCurrent == nil && meterEnergy == nil:
+		return &struct {
+			api.Meter
+			api.BatteryController
+		}{
+			Meter: base,
+			BatteryController: &decorateMeterBatteryControllerImpl{
+				batteryController: batteryController,
+			},
+		}
+
+	case battery == nil && batteryController != nil && meterCurrent == nil && meterEnergy != nil:
+		return &struct {
+			api.Meter
+			api.BatteryController
+			api.MeterEnergy
+		}{
+			Meter: base,
+			BatteryController: &decorateMeterBatteryControllerImpl{
+				batteryController: batteryController,
+			},
+			MeterEnergy: &decorateMeterMeterEnergyImpl{
+				meterEnergy: meterEnergy,
+			},
+		}
+
+	case battery == nil && batteryController != nil && meterCurrent != nil && meterEnergy == nil:
+		return &struct {
+			api.Meter
+			api.BatteryController
+			api.MeterCurrent
+		}{
+			Meter: base,
+			BatteryController: &decorateMeterBatteryControllerImpl{
+				batteryController: batteryController,
+			},
+			MeterCurrent: &decorateMeterMeterCurrentImpl{
+				meterCurrent: meterCurrent,
+			},
+		}
+
+	case battery == nil && batteryController != nil && meterCurrent != nil && meterEnergy != nil:
+		return &struct {
+			api.Meter
+			api.BatteryController
+			api.MeterCurrent
+			api.MeterEnergy
+		}{
+			Meter: base,
+			BatteryController: &decorateMeterBatteryControllerImpl{
+				batteryController: batteryController,
+			},
+			MeterCurrent: &decorateMeterMeterCurrentImpl{
+				meterCurrent: meterCurrent,
+			},
+			MeterEnergy: &decorateMeterMeterEnergyImpl{
+				meterEnergy: meterEnergy,
+			},
+		}
+
+	case battery != nil && batteryController != nil && meterCurrent == nil && meterEnergy == nil:
+		return &struct {
+			api.Meter
+			api.Battery
+			api.BatteryController
+		}{
+			Meter: base,
+			Battery: &decorateMeterBatteryImpl{
+				battery: battery,
+			},
+			BatteryController: &decorateMeterBatteryControllerImpl{
+				batteryController: batteryController,
+			},
+		}
+
+	case battery != nil && batteryController != nil && meterCurrent == nil && meterEnergy != nil:
+		return &struct {
+			api.Meter
+			api.Battery
+			api.BatteryController
+			api.MeterEnergy
+		}{
+			Meter: base,
+			Battery: &decorateMeterBatteryImpl{
+				battery: battery,
+			},
+			BatteryController: &decorateMeterBatteryControllerImpl{
+				batteryController: batteryController,
+			},
+			MeterEnergy: &decorateMeterMeterEnergyImpl{
+				meterEnergy: meterEnergy,
+			},
+		}
+
+	case battery != nil && batteryController != nil && meterCurrent != nil && meterEnergy == nil:
+		return &struct {
+			api.Meter
+			api.Battery
+			api.BatteryController
+			api.MeterCurrent
+		}{
+			Meter: base,
+			Battery: &decorateMeterBatteryImpl{
+				battery: battery,
+			},
+			BatteryController: &decorateMeterBatteryControllerImpl{
+				batteryController: batteryController,
+			},
+			MeterCurrent: &decorateMeterMeterCurrentImpl{
+				meterCurrent: meterCurrent,
+			},
+		}
+
+	case battery != nil && batteryController != nil && meterCurrent != nil && meterEnergy != nil:
+		return &struct {
+			api.Meter
+			api.Battery
+			api.BatteryController
+			api.MeterCurrent
+			api.MeterEnergy
+		}{
+			Meter: base,
+			Battery: &decorateMeterBatteryImpl{
+				battery: battery,
+			},
+			BatteryController: &decorateMeterBatteryControllerImpl{
+				batteryController: batteryController,
+			},
+			MeterCurrent: &decorateMeterMeterCurrentImpl{
+				meterCurrent: meterCurrent,
+			},
+			MeterEnergy: &decorateMeterMeterEnergyImpl{
+				meterEnergy: meterEnergy,
+			},
+		}
 	}
 
 	return nil
@@ -120,6 +256,14 @@ func (impl *decorateMeterBatteryImpl) SoC() (float64, error) {
 	return impl.battery()
 }
 
+type decorateMeterBatteryControllerImpl struct {
+	batteryController func(lock bool) error
+}
+
+func (impl *decorateMeterBatteryControllerImpl) LockDischarge(lock bool) error {
+	return impl.batteryController(lock)
+}
+
 type decorateMeterMeterCurrentImpl struct {
 	meterCurrent func() (float64, float64, float64, error)
 }
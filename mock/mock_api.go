@@ -1,5 +1,5 @@
 // Code generated by MockGen. DO NOT EDIT.
-// Source: github.com/andig/evcc/api (interfaces: Charger,Meter,MeterEnergy,Vehicle,ChargeRater)
+// Source: github.com/andig/evcc/api (interfaces: BatteryController,Charger,ChargePhases,Identifier,Meter,MeterEnergy,Vehicle,ChargeRater,VehicleClimateController,VehicleStatus,VehicleStartCharge,VehicleStopCharge,VehicleChargeCurrent,VehicleChargeLimit)
 
 // Package mock is a generated GoMock package.
 package mock
@@ -10,6 +10,43 @@ import (
 	reflect "reflect"
 )
 
+// MockBatteryController is a mock of BatteryController interface
+type MockBatteryController struct {
+	ctrl     *gomock.Controller
+	recorder *MockBatteryControllerMockRecorder
+}
+
+// MockBatteryControllerMockRecorder is the mock recorder for MockBatteryController
+type MockBatteryControllerMockRecorder struct {
+	mock *MockBatteryController
+}
+
+// NewMockBatteryController creates a new mock instance
+func NewMockBatteryController(ctrl *gomock.Controller) *MockBatteryController {
+	mock := &MockBatteryController{ctrl: ctrl}
+	mock.recorder = &MockBatteryControllerMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use
+func (m *MockBatteryController) EXPECT() *MockBatteryControllerMockRecorder {
+	return m.recorder
+}
+
+// LockDischarge mocks base method
+func (m *MockBatteryController) LockDischarge(arg0 bool) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "LockDischarge", arg0)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// LockDischarge indicates an expected call of LockDischarge
+func (mr *MockBatteryControllerMockRecorder) LockDischarge(arg0 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "LockDischarge", reflect.TypeOf((*MockBatteryController)(nil).LockDischarge), arg0)
+}
+
 // MockCharger is a mock of Charger interface
 type MockCharger struct {
 	ctrl     *gomock.Controller
@@ -91,6 +128,81 @@ func (mr *MockChargerMockRecorder) Status() *gomock.Call {
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Status", reflect.TypeOf((*MockCharger)(nil).Status))
 }
 
+// MockChargePhases is a mock of ChargePhases interface
+type MockChargePhases struct {
+	ctrl     *gomock.Controller
+	recorder *MockChargePhasesMockRecorder
+}
+
+// MockChargePhasesMockRecorder is the mock recorder for MockChargePhases
+type MockChargePhasesMockRecorder struct {
+	mock *MockChargePhases
+}
+
+// NewMockChargePhases creates a new mock instance
+func NewMockChargePhases(ctrl *gomock.Controller) *MockChargePhases {
+	mock := &MockChargePhases{ctrl: ctrl}
+	mock.recorder = &MockChargePhasesMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use
+func (m *MockChargePhases) EXPECT() *MockChargePhasesMockRecorder {
+	return m.recorder
+}
+
+// Phases1p3p mocks base method
+func (m *MockChargePhases) Phases1p3p(arg0 int) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Phases1p3p", arg0)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Phases1p3p indicates an expected call of Phases1p3p
+func (mr *MockChargePhasesMockRecorder) Phases1p3p(arg0 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Phases1p3p", reflect.TypeOf((*MockChargePhases)(nil).Phases1p3p), arg0)
+}
+
+// MockIdentifier is a mock of Identifier interface
+type MockIdentifier struct {
+	ctrl     *gomock.Controller
+	recorder *MockIdentifierMockRecorder
+}
+
+// MockIdentifierMockRecorder is the mock recorder for MockIdentifier
+type MockIdentifierMockRecorder struct {
+	mock *MockIdentifier
+}
+
+// NewMockIdentifier creates a new mock instance
+func NewMockIdentifier(ctrl *gomock.Controller) *MockIdentifier {
+	mock := &MockIdentifier{ctrl: ctrl}
+	mock.recorder = &MockIdentifierMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use
+func (m *MockIdentifier) EXPECT() *MockIdentifierMockRecorder {
+	return m.recorder
+}
+
+// Identify mocks base method
+func (m *MockIdentifier) Identify() (string, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Identify")
+	ret0, _ := ret[0].(string)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Identify indicates an expected call of Identify
+func (mr *MockIdentifierMockRecorder) Identify() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Identify", reflect.TypeOf((*MockIdentifier)(nil).Identify))
+}
+
 // MockMeter is a mock of Meter interface
 type MockMeter struct {
 	ctrl     *gomock.Controller
@@ -204,6 +316,34 @@ func (mr *MockVehicleMockRecorder) Capacity() *gomock.Call {
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Capacity", reflect.TypeOf((*MockVehicle)(nil).Capacity))
 }
 
+// Defaults mocks base method
+func (m *MockVehicle) Defaults() api.VehicleDefaults {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Defaults")
+	ret0, _ := ret[0].(api.VehicleDefaults)
+	return ret0
+}
+
+// Defaults indicates an expected call of Defaults
+func (mr *MockVehicleMockRecorder) Defaults() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Defaults", reflect.TypeOf((*MockVehicle)(nil).Defaults))
+}
+
+// Identifiers mocks base method
+func (m *MockVehicle) Identifiers() []string {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Identifiers")
+	ret0, _ := ret[0].([]string)
+	return ret0
+}
+
+// Identifiers indicates an expected call of Identifiers
+func (mr *MockVehicleMockRecorder) Identifiers() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Identifiers", reflect.TypeOf((*MockVehicle)(nil).Identifiers))
+}
+
 // SoC mocks base method
 func (m *MockVehicle) SoC() (float64, error) {
 	m.ctrl.T.Helper()
@@ -270,3 +410,240 @@ func (mr *MockChargeRaterMockRecorder) ChargedEnergy() *gomock.Call {
 	mr.mock.ctrl.T.Helper()
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ChargedEnergy", reflect.TypeOf((*MockChargeRater)(nil).ChargedEnergy))
 }
+
+// MockVehicleClimateController is a mock of VehicleClimateController interface
+type MockVehicleClimateController struct {
+	ctrl     *gomock.Controller
+	recorder *MockVehicleClimateControllerMockRecorder
+}
+
+// MockVehicleClimateControllerMockRecorder is the mock recorder for MockVehicleClimateController
+type MockVehicleClimateControllerMockRecorder struct {
+	mock *MockVehicleClimateController
+}
+
+// NewMockVehicleClimateController creates a new mock instance
+func NewMockVehicleClimateController(ctrl *gomock.Controller) *MockVehicleClimateController {
+	mock := &MockVehicleClimateController{ctrl: ctrl}
+	mock.recorder = &MockVehicleClimateControllerMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use
+func (m *MockVehicleClimateController) EXPECT() *MockVehicleClimateControllerMockRecorder {
+	return m.recorder
+}
+
+// StartClimate mocks base method
+func (m *MockVehicleClimateController) StartClimate(arg0 float64) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "StartClimate", arg0)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// StartClimate indicates an expected call of StartClimate
+func (mr *MockVehicleClimateControllerMockRecorder) StartClimate(arg0 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "StartClimate", reflect.TypeOf((*MockVehicleClimateController)(nil).StartClimate), arg0)
+}
+
+// StopClimate mocks base method
+func (m *MockVehicleClimateController) StopClimate() error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "StopClimate")
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// StopClimate indicates an expected call of StopClimate
+func (mr *MockVehicleClimateControllerMockRecorder) StopClimate() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "StopClimate", reflect.TypeOf((*MockVehicleClimateController)(nil).StopClimate))
+}
+
+// MockVehicleStatus is a mock of VehicleStatus interface
+type MockVehicleStatus struct {
+	ctrl     *gomock.Controller
+	recorder *MockVehicleStatusMockRecorder
+}
+
+// MockVehicleStatusMockRecorder is the mock recorder for MockVehicleStatus
+type MockVehicleStatusMockRecorder struct {
+	mock *MockVehicleStatus
+}
+
+// NewMockVehicleStatus creates a new mock instance
+func NewMockVehicleStatus(ctrl *gomock.Controller) *MockVehicleStatus {
+	mock := &MockVehicleStatus{ctrl: ctrl}
+	mock.recorder = &MockVehicleStatusMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use
+func (m *MockVehicleStatus) EXPECT() *MockVehicleStatusMockRecorder {
+	return m.recorder
+}
+
+// Status mocks base method
+func (m *MockVehicleStatus) Status() (api.ChargeStatus, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Status")
+	ret0, _ := ret[0].(api.ChargeStatus)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Status indicates an expected call of Status
+func (mr *MockVehicleStatusMockRecorder) Status() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Status", reflect.TypeOf((*MockVehicleStatus)(nil).Status))
+}
+
+// MockVehicleStartCharge is a mock of VehicleStartCharge interface
+type MockVehicleStartCharge struct {
+	ctrl     *gomock.Controller
+	recorder *MockVehicleStartChargeMockRecorder
+}
+
+// MockVehicleStartChargeMockRecorder is the mock recorder for MockVehicleStartCharge
+type MockVehicleStartChargeMockRecorder struct {
+	mock *MockVehicleStartCharge
+}
+
+// NewMockVehicleStartCharge creates a new mock instance
+func NewMockVehicleStartCharge(ctrl *gomock.Controller) *MockVehicleStartCharge {
+	mock := &MockVehicleStartCharge{ctrl: ctrl}
+	mock.recorder = &MockVehicleStartChargeMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use
+func (m *MockVehicleStartCharge) EXPECT() *MockVehicleStartChargeMockRecorder {
+	return m.recorder
+}
+
+// StartCharge mocks base method
+func (m *MockVehicleStartCharge) StartCharge() error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "StartCharge")
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// StartCharge indicates an expected call of StartCharge
+func (mr *MockVehicleStartChargeMockRecorder) StartCharge() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "StartCharge", reflect.TypeOf((*MockVehicleStartCharge)(nil).StartCharge))
+}
+
+// MockVehicleStopCharge is a mock of VehicleStopCharge interface
+type MockVehicleStopCharge struct {
+	ctrl     *gomock.Controller
+	recorder *MockVehicleStopChargeMockRecorder
+}
+
+// MockVehicleStopChargeMockRecorder is the mock recorder for MockVehicleStopCharge
+type MockVehicleStopChargeMockRecorder struct {
+	mock *MockVehicleStopCharge
+}
+
+// NewMockVehicleStopCharge creates a new mock instance
+func NewMockVehicleStopCharge(ctrl *gomock.Controller) *MockVehicleStopCharge {
+	mock := &MockVehicleStopCharge{ctrl: ctrl}
+	mock.recorder = &MockVehicleStopChargeMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use
+func (m *MockVehicleStopCharge) EXPECT() *MockVehicleStopChargeMockRecorder {
+	return m.recorder
+}
+
+// StopCharge mocks base method
+func (m *MockVehicleStopCharge) StopCharge() error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "StopCharge")
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// StopCharge indicates an expected call of StopCharge
+func (mr *MockVehicleStopChargeMockRecorder) StopCharge() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "StopCharge", reflect.TypeOf((*MockVehicleStopCharge)(nil).StopCharge))
+}
+
+// MockVehicleChargeCurrent is a mock of VehicleChargeCurrent interface
+type MockVehicleChargeCurrent struct {
+	ctrl     *gomock.Controller
+	recorder *MockVehicleChargeCurrentMockRecorder
+}
+
+// MockVehicleChargeCurrentMockRecorder is the mock recorder for MockVehicleChargeCurrent
+type MockVehicleChargeCurrentMockRecorder struct {
+	mock *MockVehicleChargeCurrent
+}
+
+// NewMockVehicleChargeCurrent creates a new mock instance
+func NewMockVehicleChargeCurrent(ctrl *gomock.Controller) *MockVehicleChargeCurrent {
+	mock := &MockVehicleChargeCurrent{ctrl: ctrl}
+	mock.recorder = &MockVehicleChargeCurrentMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use
+func (m *MockVehicleChargeCurrent) EXPECT() *MockVehicleChargeCurrentMockRecorder {
+	return m.recorder
+}
+
+// SetChargeCurrent mocks base method
+func (m *MockVehicleChargeCurrent) SetChargeCurrent(arg0 int64) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "SetChargeCurrent", arg0)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// SetChargeCurrent indicates an expected call of SetChargeCurrent
+func (mr *MockVehicleChargeCurrentMockRecorder) SetChargeCurrent(arg0 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SetChargeCurrent", reflect.TypeOf((*MockVehicleChargeCurrent)(nil).SetChargeCurrent), arg0)
+}
+
+// MockVehicleChargeLimit is a mock of VehicleChargeLimit interface
+type MockVehicleChargeLimit struct {
+	ctrl     *gomock.Controller
+	recorder *MockVehicleChargeLimitMockRecorder
+}
+
+// MockVehicleChargeLimitMockRecorder is the mock recorder for MockVehicleChargeLimit
+type MockVehicleChargeLimitMockRecorder struct {
+	mock *MockVehicleChargeLimit
+}
+
+// NewMockVehicleChargeLimit creates a new mock instance
+func NewMockVehicleChargeLimit(ctrl *gomock.Controller) *MockVehicleChargeLimit {
+	mock := &MockVehicleChargeLimit{ctrl: ctrl}
+	mock.recorder = &MockVehicleChargeLimitMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use
+func (m *MockVehicleChargeLimit) EXPECT() *MockVehicleChargeLimitMockRecorder {
+	return m.recorder
+}
+
+// SetChargeLimit mocks base method
+func (m *MockVehicleChargeLimit) SetChargeLimit(arg0 int) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "SetChargeLimit", arg0)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// SetChargeLimit indicates an expected call of SetChargeLimit
+func (mr *MockVehicleChargeLimitMockRecorder) SetChargeLimit(arg0 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SetChargeLimit", reflect.TypeOf((*MockVehicleChargeLimit)(nil).SetChargeLimit), arg0)
+}
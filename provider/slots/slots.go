@@ -0,0 +1,195 @@
+package slots
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/andig/evcc/provider"
+	"github.com/andig/evcc/util"
+	"github.com/andig/evcc/util/jq"
+	"github.com/benbjohnson/clock"
+	"github.com/itchyny/gojq"
+)
+
+// Slot is a value valid within the given time slot
+type Slot struct {
+	Start, End time.Time
+	Value      float64
+}
+
+// Provider retrieves time slots from a JSON array of {start, end, <key>} objects,
+// optionally transformed by jq. Tariffs and solar forecasts use it for their http and file types.
+type Provider struct {
+	mu      sync.Mutex
+	log     *util.Logger
+	clock   clock.Clock
+	getter  func() ([]byte, error)
+	jq      *gojq.Query
+	key     string
+	cache   time.Duration
+	updated time.Time
+	slots   []Slot
+}
+
+// NewHTTPFromConfig creates a provider retrieving slots from a JSON web service
+func NewHTTPFromConfig(log *util.Logger, key string, other map[string]interface{}) (*Provider, error) {
+	cc := struct {
+		URI, Method string
+		Headers     map[string]string
+		Body        string
+		Jq          string
+		Insecure    bool
+		Auth        provider.Auth
+		Cache       time.Duration
+	}{
+		Headers: make(map[string]string),
+		Cache:   time.Hour,
+	}
+
+	if err := util.DecodeOther(other, &cc); err != nil {
+		return nil, err
+	}
+
+	// handle basic auth
+	if cc.Auth.Type != "" {
+		if err := provider.AuthHeaders(log, cc.Auth, cc.Headers); err != nil {
+			return nil, fmt.Errorf("http auth: %w", err)
+		}
+	}
+
+	// jq is applied to the full response to obtain the array result
+	p, err := provider.NewHTTP(log, cc.Method, cc.URI, cc.Headers, cc.Body, cc.Insecure, "", 0)
+	if err != nil {
+		return nil, err
+	}
+
+	g := p.StringGetter()
+	getter := func() ([]byte, error) {
+		s, err := g()
+		return []byte(s), err
+	}
+
+	return NewProvider(log, getter, cc.Jq, key, cc.Cache)
+}
+
+// NewFileFromConfig creates a provider reading slots from a local JSON file.
+// The file is re-read on each call to allow external updates.
+func NewFileFromConfig(log *util.Logger, key string, other map[string]interface{}) (*Provider, error) {
+	cc := struct {
+		Path string
+		Jq   string
+	}{}
+
+	if err := util.DecodeOther(other, &cc); err != nil {
+		return nil, err
+	}
+
+	getter := func() ([]byte, error) {
+		return ioutil.ReadFile(cc.Path)
+	}
+
+	return NewProvider(log, getter, cc.Jq, key, 0)
+}
+
+// NewProvider creates a slot provider. Results are cached for the given duration.
+func NewProvider(log *util.Logger, getter func() ([]byte, error), query, key string, cache time.Duration) (*Provider, error) {
+	q, err := ParseQuery(query)
+	if err != nil {
+		return nil, err
+	}
+
+	p := &Provider{
+		log:    log,
+		clock:  clock.New(),
+		getter: getter,
+		jq:     q,
+		key:    key,
+		cache:  cache,
+	}
+
+	return p, nil
+}
+
+// Slots returns the slots sorted by start time
+func (p *Provider) Slots() ([]Slot, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.slots != nil && p.clock.Since(p.updated) < p.cache {
+		return p.slots, nil
+	}
+
+	b, err := p.getter()
+	if err != nil {
+		return nil, err
+	}
+
+	slots, err := Parse(b, p.jq, p.key)
+	if err != nil {
+		return nil, err
+	}
+
+	p.log.TRACE.Printf("%s: %+v", p.key, slots)
+
+	p.slots = slots
+	p.updated = p.clock.Now()
+
+	return p.slots, nil
+}
+
+// ParseQuery compiles an optional jq query
+func ParseQuery(query string) (*gojq.Query, error) {
+	if query == "" {
+		return nil, nil
+	}
+
+	op, err := gojq.Parse(query)
+	if err != nil {
+		return nil, fmt.Errorf("invalid jq query '%s': %w", query, err)
+	}
+
+	return op, nil
+}
+
+// Parse decodes a JSON array of {start, end, <key>} objects, optionally transformed by jq.
+// Start and end must be RFC3339 formatted.
+func Parse(b []byte, query *gojq.Query, key string) ([]Slot, error) {
+	if query != nil {
+		v, err := jq.Query(query, b)
+		if err != nil {
+			return nil, err
+		}
+
+		if b, err = json.Marshal(v); err != nil {
+			return nil, err
+		}
+	}
+
+	var raw []map[string]json.RawMessage
+	if err := json.Unmarshal(b, &raw); err != nil {
+		return nil, fmt.Errorf("invalid %s: %w", key, err)
+	}
+
+	res := make([]Slot, 0, len(raw))
+	for _, r := range raw {
+		var s Slot
+
+		for k, v := range map[string]interface{}{"start": &s.Start, "end": &s.End, key: &s.Value} {
+			if err := json.Unmarshal(r[k], v); err != nil {
+				return nil, fmt.Errorf("invalid %s: %s: %w", key, k, err)
+			}
+		}
+
+		res = append(res, s)
+	}
+
+	sort.Slice(res, func(i, j int) bool {
+		return res[i].Start.Before(res[j].Start)
+	})
+
+	return res, nil
+}
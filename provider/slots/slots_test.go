@@ -0,0 +1,73 @@
+package slots
+
+import (
+	"testing"
+	"time"
+
+	"github.com/andig/evcc/util"
+	"github.com/benbjohnson/clock"
+)
+
+func TestParse(t *testing.T) {
+	// map a service specific format to {start, end, power}
+	query, err := ParseQuery(`[.result[] | {start: .from, end: .to, power: (.kw * 1000)}]`)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	res, err := Parse([]byte(`{"result":[
+		{"from":"2021-01-01T13:00:00Z","to":"2021-01-01T14:00:00Z","kw":2.5},
+		{"from":"2021-01-01T12:00:00Z","to":"2021-01-01T13:00:00Z","kw":3}
+	]}`), query, "power")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(res) != 2 || res[0].Value != 3000 || res[1].Value != 2500 {
+		t.Errorf("unexpected slots %+v", res)
+	}
+
+	if _, err := Parse([]byte(`[{"start":"2021-01-01T13:00:00Z","end":"2021-01-01T14:00:00Z","price":0.3}]`), nil, "power"); err == nil {
+		t.Error("expected error for missing value")
+	}
+}
+
+func TestProviderCache(t *testing.T) {
+	var calls int
+	getter := func() ([]byte, error) {
+		calls++
+		return []byte(`[{"start":"2021-01-01T12:00:00Z","end":"2021-01-01T13:00:00Z","price":0.3}]`), nil
+	}
+
+	p, err := NewProvider(util.NewLogger("foo"), getter, "", "price", time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	clck := clock.NewMock()
+	p.clock = clck
+
+	for _, tc := range []struct {
+		advance time.Duration
+		calls   int
+	}{
+		{0, 1},
+		{30 * time.Minute, 1},
+		{30 * time.Minute, 2},
+	} {
+		clck.Add(tc.advance)
+
+		res, err := p.Slots()
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if len(res) != 1 || res[0].Value != 0.3 {
+			t.Errorf("unexpected slots %+v", res)
+		}
+
+		if calls != tc.calls {
+			t.Errorf("expected %d calls, got %d", tc.calls, calls)
+		}
+	}
+}
@@ -8,14 +8,17 @@ import (
 
 // Event is a notification event
 type Event struct {
-	LoadPoint *int // optional loadpoint id
+	Site      string // optional site id
+	LoadPoint *int   // optional loadpoint id
 	Event     string
+	Time      time.Time
 }
 
 // Hub subscribes to event notifications and sends them to client devices
 type Hub struct {
 	definitions map[string]EventTemplate
 	sender      []Sender
+	webhooks    []*Webhook
 	cache       *util.Cache
 }
 
@@ -33,8 +36,13 @@ func (h *Hub) Add(sender Sender) {
 	h.sender = append(h.sender, sender)
 }
 
-// apply applies the event template to the content to produce the actual message
-func (h *Hub) apply(ev Event, template string) (string, error) {
+// AddWebhook adds a webhook to the list of webhooks
+func (h *Hub) AddWebhook(webhook *Webhook) {
+	h.webhooks = append(h.webhooks, webhook)
+}
+
+// values returns the cached values of the event's site and loadpoint
+func (h *Hub) values(ev Event) map[string]interface{} {
 	attr := make(map[string]interface{})
 
 	// let cache catch up, refs reverted https://github.com/andig/evcc/pull/445
@@ -42,17 +50,27 @@ func (h *Hub) apply(ev Event, template string) (string, error) {
 
 	// get all values from cache
 	for _, p := range h.cache.All() {
-		if p.LoadPoint == nil || ev.LoadPoint == p.LoadPoint {
+		if p.Site == ev.Site && p.Consumer == nil && (p.LoadPoint == nil || ev.LoadPoint == p.LoadPoint) {
 			attr[p.Key] = p.Val
 		}
 	}
 
-	return util.ReplaceFormatted(template, attr)
+	return attr
 }
 
 // Run is the Hub's main publishing loop
 func (h *Hub) Run(events <-chan Event) {
 	for ev := range events {
+		if len(h.sender) == 0 && len(h.webhooks) == 0 {
+			continue
+		}
+
+		attr := h.values(ev)
+
+		for _, webhook := range h.webhooks {
+			webhook.Send(NewPayload(ev, attr))
+		}
+
 		if len(h.sender) == 0 {
 			continue
 		}
@@ -62,7 +80,7 @@ func (h *Hub) Run(events <-chan Event) {
 			continue
 		}
 
-		msg, err := h.apply(ev, definition.Msg)
+		msg, err := util.ReplaceFormatted(definition.Msg, attr)
 		if err != nil {
 			log.ERROR.Printf("invalid template for %s: %v", ev.Event, err)
 			continue
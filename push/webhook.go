@@ -0,0 +1,315 @@
+package push
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/andig/evcc/util"
+	"github.com/andig/evcc/util/request"
+)
+
+const (
+	webhookMinBackoff = 10 * time.Second // delay of first retry
+	webhookMaxBackoff = 10 * time.Minute // maximum delay between retries
+	webhookMaxAge     = 24 * time.Hour   // undelivered events are dropped after this duration
+)
+
+// WebhookConfig is the webhook configuration
+type WebhookConfig struct {
+	URI     string
+	Events  []string      // Events to send, all if empty
+	Secret  string        // HMAC-SHA256 signing key, unsigned if empty
+	Timeout time.Duration // Request timeout
+}
+
+// Payload is the structured event sent by webhooks
+type Payload struct {
+	Event          string    `json:"event"`
+	Time           time.Time `json:"time"`
+	Site           string    `json:"site,omitempty"`
+	LoadPoint      *int      `json:"loadpoint,omitempty"`
+	Title          string    `json:"title,omitempty"`          // Loadpoint title
+	Vehicle        string    `json:"vehicle,omitempty"`        // Vehicle title
+	SoC            *float64  `json:"soc,omitempty"`            // Vehicle SoC in %
+	ChargedEnergy  *float64  `json:"chargedEnergy,omitempty"`  // Session energy in Wh
+	ChargeDuration float64   `json:"chargeDuration,omitempty"` // Session charge duration in s
+}
+
+// NewPayload creates the webhook payload from the event and the event's cached values
+func NewPayload(ev Event, attr map[string]interface{}) Payload {
+	p := Payload{
+		Event:     ev.Event,
+		Time:      ev.Time,
+		Site:      ev.Site,
+		LoadPoint: ev.LoadPoint,
+	}
+
+	if p.Time.IsZero() {
+		p.Time = time.Now()
+	}
+
+	p.Title, _ = attr["title"].(string)
+	p.Vehicle, _ = attr["socTitle"].(string)
+
+	if d, ok := attr["chargeDuration"].(time.Duration); ok {
+		p.ChargeDuration = d.Seconds()
+	}
+
+	if soc, ok := attr["socCharge"].(float64); ok {
+		p.SoC = &soc
+	}
+
+	if energy, ok := attr["chargedEnergy"].(float64); ok {
+		p.ChargedEnergy = &energy
+	}
+
+	return p
+}
+
+// Store persists undelivered webhook events
+type Store interface {
+	Put(bucket, key string, val interface{}) error
+	Delete(bucket, key string) error
+	ForEach(bucket string, fn func(key string, val []byte) error) error
+}
+
+type webhookEntry struct {
+	key     string
+	payload Payload
+}
+
+// Webhook posts events as JSON to a url. Events are queued and retried with
+// increasing delay until delivered. If a store is given, the queue survives restarts.
+type Webhook struct {
+	*request.Helper
+	log    *util.Logger
+	uri    string
+	events map[string]bool
+	secret string
+
+	mu      sync.Mutex
+	queue   []webhookEntry
+	seq     uint64
+	store   Store
+	bucket  string
+	notifyC chan struct{}
+
+	minBackoff, maxBackoff, maxAge time.Duration
+}
+
+// NewWebhookFromConfig creates a webhook from generic config
+func NewWebhookFromConfig(other map[string]interface{}, store Store) (*Webhook, error) {
+	var cc WebhookConfig
+	if err := util.DecodeOther(other, &cc); err != nil {
+		return nil, err
+	}
+
+	return NewWebhook(cc.URI, cc.Events, cc.Secret, cc.Timeout, store)
+}
+
+// NewWebhook creates a webhook and starts delivering queued events
+func NewWebhook(uri string, events []string, secret string, timeout time.Duration, store Store) (*Webhook, error) {
+	w, err := newWebhook(uri, events, secret, timeout, store)
+	if err == nil {
+		go w.run()
+	}
+
+	return w, err
+}
+
+// newWebhook creates a webhook and restores undelivered events
+func newWebhook(uri string, events []string, secret string, timeout time.Duration, store Store) (*Webhook, error) {
+	if uri == "" {
+		return nil, errors.New("webhook: missing uri")
+	}
+
+	log := util.NewLogger("webhook")
+
+	w := &Webhook{
+		Helper:     request.NewHelper(log),
+		log:        log,
+		uri:        uri,
+		secret:     secret,
+		store:      store,
+		bucket:     "webhook " + uri,
+		notifyC:    make(chan struct{}, 1),
+		minBackoff: webhookMinBackoff,
+		maxBackoff: webhookMaxBackoff,
+		maxAge:     webhookMaxAge,
+	}
+
+	if timeout > 0 {
+		w.Client.Timeout = timeout
+	}
+
+	if len(events) > 0 {
+		w.events = make(map[string]bool)
+		for _, ev := range events {
+			w.events[ev] = true
+		}
+	}
+
+	if err := w.restore(); err != nil {
+		return nil, fmt.Errorf("webhook: %v", err)
+	}
+
+	return w, nil
+}
+
+// restore loads undelivered events from the store
+func (w *Webhook) restore() error {
+	if w.store == nil {
+		return nil
+	}
+
+	err := w.store.ForEach(w.bucket, func(key string, val []byte) error {
+		var p Payload
+		if err := json.Unmarshal(val, &p); err != nil {
+			return err
+		}
+
+		if seq, err := strconv.ParseUint(key, 10, 64); err == nil && seq >= w.seq {
+			w.seq = seq + 1
+		}
+
+		w.queue = append(w.queue, webhookEntry{key: key, payload: p})
+		return nil
+	})
+
+	if len(w.queue) > 0 {
+		w.log.INFO.Printf("%d undelivered events for %s", len(w.queue), w.uri)
+	}
+
+	return err
+}
+
+// Send queues the event for delivery unless filtered
+func (w *Webhook) Send(p Payload) {
+	if w.events != nil && !w.events[p.Event] {
+		return
+	}
+
+	w.mu.Lock()
+
+	// fixed width keys keep events in order
+	entry := webhookEntry{key: fmt.Sprintf("%020d", w.seq), payload: p}
+	w.seq++
+	w.queue = append(w.queue, entry)
+
+	if w.store != nil {
+		if err := w.store.Put(w.bucket, entry.key, p); err != nil {
+			w.log.ERROR.Printf("persist event: %v", err)
+		}
+	}
+
+	w.mu.Unlock()
+
+	select {
+	case w.notifyC <- struct{}{}:
+	default:
+	}
+}
+
+// next returns the oldest queued event
+func (w *Webhook) next() (webhookEntry, bool) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if len(w.queue) == 0 {
+		return webhookEntry{}, false
+	}
+
+	return w.queue[0], true
+}
+
+// remove removes the oldest queued event
+func (w *Webhook) remove(entry webhookEntry) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	w.queue = w.queue[1:]
+
+	if w.store != nil {
+		if err := w.store.Delete(w.bucket, entry.key); err != nil {
+			w.log.ERROR.Printf("remove event: %v", err)
+		}
+	}
+}
+
+// run delivers queued events in order
+func (w *Webhook) run() {
+	backoff := w.minBackoff
+
+	for {
+		entry, ok := w.next()
+		if !ok {
+			<-w.notifyC
+			continue
+		}
+
+		err := w.post(entry.payload)
+		if err == nil {
+			w.remove(entry)
+			backoff = w.minBackoff
+			continue
+		}
+
+		var se request.StatusError
+		if errors.As(err, &se) && se.StatusCode() >= 400 && se.StatusCode() < 500 &&
+			!se.HasStatus(http.StatusRequestTimeout, http.StatusTooManyRequests) {
+			w.log.ERROR.Printf("%s event rejected: %v", entry.payload.Event, err)
+			w.remove(entry)
+			continue
+		}
+
+		if time.Since(entry.payload.Time) > w.maxAge {
+			w.log.ERROR.Printf("%s event dropped after %v: %v", entry.payload.Event, w.maxAge, err)
+			w.remove(entry)
+			continue
+		}
+
+		w.log.WARN.Printf("%s event failed, retry in %v: %v", entry.payload.Event, backoff, err)
+		time.Sleep(backoff)
+
+		if backoff *= 2; backoff > w.maxBackoff {
+			backoff = w.maxBackoff
+		}
+	}
+}
+
+// post sends the event, signing the body if a secret is configured
+func (w *Webhook) post(p Payload) error {
+	body, err := json.Marshal(p)
+	if err != nil {
+		return err
+	}
+
+	req, err := request.New(http.MethodPost, w.uri, bytes.NewReader(body), request.JSONEncoding)
+	if err != nil {
+		return err
+	}
+
+	req.Header.Set("X-Evcc-Event", p.Event)
+	if w.secret != "" {
+		req.Header.Set("X-Evcc-Signature", "sha256="+Sign(w.secret, body))
+	}
+
+	_, err = w.DoBody(req)
+	return err
+}
+
+// Sign returns the hex-encoded HMAC-SHA256 signature of the body
+func Sign(secret string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	_, _ = mac.Write(body)
+	return hex.EncodeToString(mac.Sum(nil))
+}
@@ -0,0 +1,142 @@
+package push
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/andig/evcc/server/db"
+)
+
+func TestWebhookPayload(t *testing.T) {
+	id := 1
+	ts := time.Date(2021, 1, 1, 12, 0, 0, 0, time.UTC)
+
+	p := NewPayload(Event{Event: "stop", LoadPoint: &id, Time: ts}, map[string]interface{}{
+		"title":          "Garage",
+		"socTitle":       "Zoe",
+		"socCharge":      80.0,
+		"chargedEnergy":  12345.0,
+		"chargeDuration": 90 * time.Minute,
+	})
+
+	b, err := json.Marshal(p)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := `{"event":"stop","time":"2021-01-01T12:00:00Z","loadpoint":1,"title":"Garage","vehicle":"Zoe","soc":80,"chargedEnergy":12345,"chargeDuration":5400}`
+	if string(b) != expected {
+		t.Errorf("expected %s, got %s", expected, b)
+	}
+}
+
+func TestWebhookDelivery(t *testing.T) {
+	reqC := make(chan *http.Request, 10)
+	bodyC := make(chan []byte, 10)
+
+	var fail int
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		b, _ := ioutil.ReadAll(r.Body)
+
+		// fail first request
+		if fail++; fail == 1 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+
+		reqC <- r
+		bodyC <- b
+	}))
+	defer srv.Close()
+
+	w, err := newWebhook(srv.URL, []string{"connect"}, "secret", 0, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	w.minBackoff = time.Millisecond
+
+	go w.run()
+
+	w.Send(Payload{Event: "start", Time: time.Now()}) // filtered
+	w.Send(Payload{Event: "connect", Time: time.Now()})
+
+	select {
+	case req := <-reqC:
+		body := <-bodyC
+
+		var p Payload
+		if err := json.Unmarshal(body, &p); err != nil || p.Event != "connect" {
+			t.Errorf("unexpected payload: %s", body)
+		}
+
+		if sig := req.Header.Get("X-Evcc-Signature"); sig != "sha256="+Sign("secret", body) {
+			t.Errorf("invalid signature: %s", sig)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("event not delivered")
+	}
+
+	select {
+	case <-reqC:
+		t.Error("unexpected filtered event")
+	case <-time.After(50 * time.Millisecond):
+	}
+}
+
+func TestWebhookQueue(t *testing.T) {
+	store, err := db.New(filepath.Join(t.TempDir(), "evcc.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer store.Close()
+
+	eventC := make(chan string, 10)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		eventC <- r.Header.Get("X-Evcc-Event")
+	}))
+	defer srv.Close()
+
+	// queue events while not delivering
+	w, err := newWebhook(srv.URL, nil, "", 0, store)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	w.Send(Payload{Event: "connect", Time: time.Now()})
+	w.Send(Payload{Event: "start", Time: time.Now()})
+
+	// restart delivers in order
+	w, err = newWebhook(srv.URL, nil, "", 0, store)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if w.seq != 2 {
+		t.Errorf("expected sequence 2, got %d", w.seq)
+	}
+
+	go w.run()
+
+	for _, expected := range []string{"connect", "start"} {
+		select {
+		case ev := <-eventC:
+			if ev != expected {
+				t.Errorf("expected %s, got %s", expected, ev)
+			}
+		case <-time.After(time.Second):
+			t.Fatalf("%s not delivered", expected)
+		}
+	}
+
+	// delivered events are removed from store
+	time.Sleep(50 * time.Millisecond)
+	w, _ = newWebhook(srv.URL, nil, "", 0, store)
+	if len(w.queue) != 0 {
+		t.Errorf("expected empty queue, got %d", len(w.queue))
+	}
+}
@@ -0,0 +1,93 @@
+package db
+
+import (
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"time"
+
+	bolt "go.etcd.io/bbolt"
+)
+
+// Instance is the database singleton
+var Instance *DB
+
+// ErrNotFound indicates that the requested key does not exist
+var ErrNotFound = errors.New("not found")
+
+// DB is a simple embedded key/value store. Values are stored JSON-encoded.
+type DB struct {
+	*bolt.DB
+}
+
+// New opens the database file, creating file and directory if required
+func New(file string) (*DB, error) {
+	if err := os.MkdirAll(filepath.Dir(file), 0755); err != nil {
+		return nil, err
+	}
+
+	bdb, err := bolt.Open(file, 0600, &bolt.Options{Timeout: time.Second})
+	if err != nil {
+		return nil, err
+	}
+
+	return &DB{DB: bdb}, nil
+}
+
+// Put stores the JSON-encoded value under given bucket and key
+func (db *DB) Put(bucket, key string, val interface{}) error {
+	b, err := json.Marshal(val)
+	if err != nil {
+		return err
+	}
+
+	return db.Update(func(tx *bolt.Tx) error {
+		bucket, err := tx.CreateBucketIfNotExists([]byte(bucket))
+		if err == nil {
+			err = bucket.Put([]byte(key), b)
+		}
+		return err
+	})
+}
+
+// Get decodes the value stored under given bucket and key into val
+func (db *DB) Get(bucket, key string, val interface{}) error {
+	return db.View(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket([]byte(bucket))
+		if bucket == nil {
+			return ErrNotFound
+		}
+
+		b := bucket.Get([]byte(key))
+		if b == nil {
+			return ErrNotFound
+		}
+
+		return json.Unmarshal(b, val)
+	})
+}
+
+// Delete removes given key from bucket
+func (db *DB) Delete(bucket, key string) error {
+	return db.Update(func(tx *bolt.Tx) error {
+		if bucket := tx.Bucket([]byte(bucket)); bucket != nil {
+			return bucket.Delete([]byte(key))
+		}
+		return nil
+	})
+}
+
+// ForEach invokes fn for each key and raw JSON value in given bucket in key order
+func (db *DB) ForEach(bucket string, fn func(key string, val []byte) error) error {
+	return db.View(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket([]byte(bucket))
+		if bucket == nil {
+			return nil
+		}
+
+		return bucket.ForEach(func(k, v []byte) error {
+			return fn(string(k), v)
+		})
+	})
+}
@@ -0,0 +1,112 @@
+package db
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+	"time"
+
+	"github.com/andig/evcc/api"
+	bolt "go.etcd.io/bbolt"
+)
+
+const sessionBucket = "sessions"
+
+// Session is a single charging session from vehicle connect to disconnect
+type Session struct {
+	ID              uint64         `json:"id"`
+	Created         time.Time      `json:"created"`
+	Finished        time.Time      `json:"finished"`
+	Site            string         `json:"site,omitempty"`
+	LoadPoint       string         `json:"loadpoint"`
+	Vehicle         string         `json:"vehicle"`
+	ChargedEnergy   float64        `json:"chargedEnergy"`   // kWh
+	SolarPercentage float64        `json:"solarPercentage"` // Share of charged energy covered by pv surplus
+	Cost            float64        `json:"cost"`            // Cost of charged energy in tariff currency
+	SoCStart        float64        `json:"socStart"`
+	SoCEnd          float64        `json:"socEnd"`
+	Mode            api.ChargeMode `json:"mode"`
+}
+
+// PersistSession stores a finished session and assigns its id
+func (db *DB) PersistSession(s *Session) error {
+	return db.Update(func(tx *bolt.Tx) error {
+		bucket, err := tx.CreateBucketIfNotExists([]byte(sessionBucket))
+		if err != nil {
+			return err
+		}
+
+		if s.ID, err = bucket.NextSequence(); err != nil {
+			return err
+		}
+
+		b, err := json.Marshal(s)
+		if err == nil {
+			// fixed width keys keep sessions in chronological order
+			err = bucket.Put([]byte(fmt.Sprintf("%020d", s.ID)), b)
+		}
+
+		return err
+	})
+}
+
+// Sessions returns all sessions created within [from, to). Zero times are not limiting.
+// If site or loadpoint are not empty only sessions of that site or loadpoint are returned.
+func (db *DB) Sessions(from, to time.Time, site, loadpoint string) ([]Session, error) {
+	res := make([]Session, 0)
+
+	err := db.ForEach(sessionBucket, func(_ string, b []byte) error {
+		var s Session
+		if err := json.Unmarshal(b, &s); err != nil {
+			return err
+		}
+
+		if (!from.IsZero() && s.Created.Before(from)) || (!to.IsZero() && !s.Created.Before(to)) {
+			return nil
+		}
+
+		if site != "" && s.Site != site || loadpoint != "" && s.LoadPoint != loadpoint {
+			return nil
+		}
+
+		res = append(res, s)
+		return nil
+	})
+
+	return res, err
+}
+
+// WriteCSV writes sessions as CSV including header row
+func WriteCSV(w io.Writer, sessions []Session) error {
+	cw := csv.NewWriter(w)
+
+	if err := cw.Write([]string{
+		"id", "created", "finished", "site", "loadpoint", "vehicle", "chargedEnergy", "solarPercentage", "cost", "socStart", "socEnd", "mode",
+	}); err != nil {
+		return err
+	}
+
+	for _, s := range sessions {
+		if err := cw.Write([]string{
+			strconv.FormatUint(s.ID, 10),
+			s.Created.Format(time.RFC3339),
+			s.Finished.Format(time.RFC3339),
+			s.Site,
+			s.LoadPoint,
+			s.Vehicle,
+			strconv.FormatFloat(s.ChargedEnergy, 'f', 3, 64),
+			strconv.FormatFloat(s.SolarPercentage, 'f', 0, 64),
+			strconv.FormatFloat(s.Cost, 'f', 2, 64),
+			strconv.FormatFloat(s.SoCStart, 'f', 0, 64),
+			strconv.FormatFloat(s.SoCEnd, 'f', 0, 64),
+			s.Mode.String(),
+		}); err != nil {
+			return err
+		}
+	}
+
+	cw.Flush()
+	return cw.Error()
+}
@@ -0,0 +1,88 @@
+package db
+
+import (
+	"bytes"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestSessions(t *testing.T) {
+	db, err := New(filepath.Join(t.TempDir(), "evcc.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	start := time.Date(2021, 1, 1, 12, 0, 0, 0, time.UTC)
+
+	for i, lp := range []string{"Garage", "Carport", "Garage"} {
+		site := "north"
+		if i == 2 {
+			site = "south"
+		}
+
+		s := &Session{
+			Created:       start.Add(time.Duration(i) * 24 * time.Hour),
+			Finished:      start.Add(time.Duration(i)*24*time.Hour + time.Hour),
+			Site:          site,
+			LoadPoint:     lp,
+			ChargedEnergy: float64(i + 1),
+		}
+
+		if err := db.PersistSession(s); err != nil {
+			t.Fatal(err)
+		}
+
+		if s.ID != uint64(i+1) {
+			t.Errorf("expected id %d, got %d", i+1, s.ID)
+		}
+	}
+
+	tc := []struct {
+		from, to        time.Time
+		site, loadpoint string
+		ids             []uint64
+	}{
+		{time.Time{}, time.Time{}, "", "", []uint64{1, 2, 3}},
+		{start.Add(time.Hour), time.Time{}, "", "", []uint64{2, 3}},
+		{time.Time{}, start.Add(48 * time.Hour), "", "", []uint64{1, 2}},
+		{time.Time{}, time.Time{}, "", "Garage", []uint64{1, 3}},
+		{time.Time{}, time.Time{}, "north", "Garage", []uint64{1}},
+	}
+
+	for _, tc := range tc {
+		res, err := db.Sessions(tc.from, tc.to, tc.site, tc.loadpoint)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		var ids []uint64
+		for _, s := range res {
+			ids = append(ids, s.ID)
+		}
+
+		if len(ids) != len(tc.ids) {
+			t.Errorf("%+v: expected %v, got %v", tc, tc.ids, ids)
+			continue
+		}
+
+		for i := range ids {
+			if ids[i] != tc.ids[i] {
+				t.Errorf("%+v: expected %v, got %v", tc, tc.ids, ids)
+			}
+		}
+	}
+
+	res, _ := db.Sessions(time.Time{}, time.Time{}, "", "Carport")
+
+	var b bytes.Buffer
+	if err := WriteCSV(&b, res); err != nil {
+		t.Fatal(err)
+	}
+
+	if lines := strings.Split(strings.TrimSpace(b.String()), "\n"); len(lines) != 2 || !strings.HasPrefix(lines[1], "2,2021-01-02T12:00:00Z") {
+		t.Errorf("unexpected csv: %s", b.String())
+	}
+}
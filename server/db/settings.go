@@ -0,0 +1,45 @@
+package db
+
+import "fmt"
+
+const settingsBucket = "settings"
+
+// Settings persists runtime settings within a scope, e.g. a loadpoint or vehicle.
+// A nil Settings does not persist anything.
+type Settings struct {
+	db    *DB
+	scope string
+}
+
+// Settings returns the settings store for given scope
+func (db *DB) Settings(scope string) *Settings {
+	return &Settings{db: db, scope: scope}
+}
+
+func (s *Settings) key(key string) string {
+	return fmt.Sprintf("%s.%s", s.scope, key)
+}
+
+// Save stores the setting's value
+func (s *Settings) Save(key string, val interface{}) error {
+	if s == nil {
+		return nil
+	}
+	return s.db.Put(settingsBucket, s.key(key), val)
+}
+
+// Load restores the setting's value into val. Returns ErrNotFound if the setting has not been stored.
+func (s *Settings) Load(key string, val interface{}) error {
+	if s == nil {
+		return ErrNotFound
+	}
+	return s.db.Get(settingsBucket, s.key(key), val)
+}
+
+// Delete removes the setting
+func (s *Settings) Delete(key string) error {
+	if s == nil {
+		return nil
+	}
+	return s.db.Delete(settingsBucket, s.key(key))
+}
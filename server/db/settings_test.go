@@ -0,0 +1,40 @@
+package db
+
+import (
+	"errors"
+	"path/filepath"
+	"testing"
+)
+
+func TestSettings(t *testing.T) {
+	db, err := New(filepath.Join(t.TempDir(), "evcc.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	lp1, lp2 := db.Settings("loadpoint.Garage"), db.Settings("loadpoint.Carport")
+
+	if err := lp1.Save("mode", "pv"); err != nil {
+		t.Fatal(err)
+	}
+
+	var mode string
+	if err := lp1.Load("mode", &mode); err != nil || mode != "pv" {
+		t.Errorf("unexpected mode %s: %v", mode, err)
+	}
+
+	// scopes are separate
+	if err := lp2.Load("mode", &mode); !errors.Is(err, ErrNotFound) {
+		t.Errorf("expected ErrNotFound, got %v", err)
+	}
+
+	// nil settings don't persist
+	var none *Settings
+	if err := none.Save("mode", "pv"); err != nil {
+		t.Error(err)
+	}
+	if err := none.Load("mode", &mode); !errors.Is(err, ErrNotFound) {
+		t.Errorf("expected ErrNotFound, got %v", err)
+	}
+}
@@ -11,6 +11,7 @@ import (
 
 	"github.com/andig/evcc/api"
 	"github.com/andig/evcc/core"
+	"github.com/andig/evcc/server/db"
 	"github.com/andig/evcc/util"
 	"github.com/andig/evcc/util/test"
 	"github.com/gorilla/handlers"
@@ -49,7 +50,7 @@ func routeLogger(inner http.Handler) http.HandlerFunc {
 	}
 }
 
-func indexHandler(site core.SiteAPI, useLocal bool) http.HandlerFunc {
+func indexHandler(sites []core.SiteAPI, useLocal bool) http.HandlerFunc {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		w.Header().Set("Content-Type", "text/html; charset=UTF-8")
 
@@ -64,10 +65,15 @@ func indexHandler(site core.SiteAPI, useLocal bool) http.HandlerFunc {
 			log.FATAL.Fatal("httpd: failed to create main page template:", err.Error())
 		}
 
+		var configured int
+		for _, site := range sites {
+			configured += len(site.LoadPoints())
+		}
+
 		if err := t.Execute(w, map[string]interface{}{
 			"Version":    Version,
 			"Commit":     Commit,
-			"Configured": len(site.LoadPoints()),
+			"Configured": configured,
 		}); err != nil {
 			log.ERROR.Println("httpd: failed to render main page:", err.Error())
 		}
@@ -89,12 +95,14 @@ func jsonResponse(w http.ResponseWriter, r *http.Request, content interface{}) {
 	}
 }
 
-// HealthHandler returns current charge mode
-func HealthHandler(site core.SiteAPI) http.HandlerFunc {
+// HealthHandler returns the health status of all sites
+func HealthHandler(sites []core.SiteAPI) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
-		if !site.Healthy() {
-			w.WriteHeader(http.StatusInternalServerError)
-			return
+		for _, site := range sites {
+			if !site.Healthy() {
+				w.WriteHeader(http.StatusInternalServerError)
+				return
+			}
 		}
 
 		w.WriteHeader(http.StatusOK)
@@ -263,6 +271,48 @@ func RemoteDemandHandler(loadpoint core.LoadPointAPI) http.HandlerFunc {
 	}
 }
 
+// RemotePowerLimitHandler sets the remote power limit. The optional duration query parameter defines the expiry.
+func RemotePowerLimitHandler(set func(string, float64, time.Duration)) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		vars := mux.Vars(r)
+
+		power, err := strconv.ParseFloat(vars["power"], 64)
+		source := vars["source"]
+
+		var duration time.Duration
+		if err == nil && r.URL.Query().Get("duration") != "" {
+			duration, err = time.ParseDuration(r.URL.Query().Get("duration"))
+		}
+
+		if err != nil || source == "" {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+
+		set(source, power, duration)
+
+		res := struct {
+			Power    float64 `json:"power"`
+			Source   string  `json:"source"`
+			Duration string  `json:"duration,omitempty"`
+		}{
+			Power:  power,
+			Source: source,
+		}
+
+		if duration > 0 {
+			res.Duration = duration.String()
+		}
+
+		jsonResponse(w, r, res)
+	}
+}
+
+// LoadPointRemotePowerLimitHandler sets the loadpoint's remote power limit
+func LoadPointRemotePowerLimitHandler(loadpoint core.LoadPointAPI) http.HandlerFunc {
+	return RemotePowerLimitHandler(loadpoint.SetRemotePowerLimit)
+}
+
 func timezone() *time.Location {
 	tz := os.Getenv("TZ")
 	if tz == "" {
@@ -273,6 +323,35 @@ func timezone() *time.Location {
 	return loc
 }
 
+// DepartureHandler sets or removes the departure time for vehicle preconditioning
+func DepartureHandler(loadpoint core.LoadPointAPI) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var timeV time.Time
+
+		if r.Method != http.MethodDelete {
+			var err error
+			timeS := mux.Vars(r)["time"]
+			timeV, err = time.ParseInLocation("2006-01-02T15:04:05", timeS, timezone())
+
+			if err != nil || timeV.Before(time.Now()) {
+				log.DEBUG.Printf("parse time: %v", err)
+				w.WriteHeader(http.StatusBadRequest)
+				return
+			}
+		}
+
+		loadpoint.SetDepartureTime(timeV)
+
+		res := struct {
+			Time time.Time `json:"time"`
+		}{
+			Time: timeV,
+		}
+
+		jsonResponse(w, r, res)
+	}
+}
+
 // TargetChargeHandler updates target soc
 func TargetChargeHandler(loadpoint core.LoadPointAPI) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
@@ -309,6 +388,89 @@ func TargetChargeHandler(loadpoint core.LoadPointAPI) http.HandlerFunc {
 	}
 }
 
+// parseDate parses an optional date query parameter in local time
+func parseDate(r *http.Request, key string) (time.Time, error) {
+	if s := r.URL.Query().Get(key); s != "" {
+		return time.ParseInLocation("2006-01-02", s, timezone())
+	}
+	return time.Time{}, nil
+}
+
+// SessionsHandler returns charging sessions as JSON or CSV.
+// Sessions can be filtered by from/to date (to is exclusive), site id and loadpoint title.
+func SessionsHandler(store *db.DB, asCSV bool) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		from, err := parseDate(r, "from")
+		if err != nil {
+			log.DEBUG.Printf("parse from: %v", err)
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+
+		to, err := parseDate(r, "to")
+		if err != nil {
+			log.DEBUG.Printf("parse to: %v", err)
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+
+		res, err := store.Sessions(from, to, r.URL.Query().Get("site"), r.URL.Query().Get("loadpoint"))
+		if err != nil {
+			log.ERROR.Printf("httpd: failed to read sessions: %v", err)
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+
+		if !asCSV {
+			jsonResponse(w, r, res)
+			return
+		}
+
+		w.Header().Set("Content-Type", "text/csv; charset=UTF-8")
+		w.Header().Set("Content-Disposition", `attachment; filename="sessions.csv"`)
+		w.WriteHeader(http.StatusOK)
+
+		if err := db.WriteCSV(w, res); err != nil {
+			log.ERROR.Printf("httpd: failed to encode CSV: %v", err)
+		}
+	}
+}
+
+// LoadPointHandler resolves the loadpoint by id on each request
+func LoadPointHandler(site core.SiteAPI, id int, handler func(core.LoadPointAPI) http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		handler(site.LoadPoints()[id])(w, r)
+	}
+}
+
+// ReloadHandler reloads the configuration
+func ReloadHandler(reload func() error) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json; charset=UTF-8")
+
+		if err := reload(); err != nil {
+			log.ERROR.Printf("httpd: failed to reload config: %v", err)
+
+			w.WriteHeader(http.StatusInternalServerError)
+			if err := json.NewEncoder(w).Encode(struct {
+				Error string `json:"error"`
+			}{
+				Error: err.Error(),
+			}); err != nil {
+				log.ERROR.Printf("httpd: failed to encode JSON: %v", err)
+			}
+
+			return
+		}
+
+		jsonResponse(w, r, struct {
+			Reload bool `json:"reload"`
+		}{
+			Reload: true,
+		})
+	}
+}
+
 // SocketHandler attaches websocket handler to uri
 func SocketHandler(hub *SocketHub) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
@@ -321,14 +483,55 @@ type HTTPd struct {
 	*http.Server
 }
 
-// NewHTTPd creates HTTP server with configured routes for loadpoint
-func NewHTTPd(url string, site core.SiteAPI, hub *SocketHub, cache *util.Cache) *HTTPd {
+// siteRoutes attaches the site's and its loadpoints' api to the router
+func siteRoutes(router *mux.Router, site core.SiteAPI) {
+	// remote power limit
+	router.Methods("POST", "OPTIONS").Path("/remotepowerlimit/{power:[0-9]+}/{source}").Handler(RemotePowerLimitHandler(site.SetRemotePowerLimit))
+
+	// loadpoint api
+	for id := range site.LoadPoints() {
+		lpAPI := router.PathPrefix(fmt.Sprintf("/loadpoints/%d", id)).Subrouter()
+
+		// loadpoints are resolved per request as they may be replaced by config reload
+		lp := func(handler func(core.LoadPointAPI) http.HandlerFunc) http.HandlerFunc {
+			return LoadPointHandler(site, id, handler)
+		}
+
+		routes := map[string]route{
+			"getmode":          {[]string{"GET"}, "/mode", lp(CurrentChargeModeHandler)},
+			"setmode":          {[]string{"POST", "OPTIONS"}, "/mode/{mode:[a-z]+}", lp(ChargeModeHandler)},
+			"gettargetsoc":     {[]string{"GET"}, "/targetsoc", lp(CurrentTargetSoCHandler)},
+			"settargetsoc":     {[]string{"POST", "OPTIONS"}, "/targetsoc/{soc:[0-9]+}", lp(TargetSoCHandler)},
+			"getminsoc":        {[]string{"GET"}, "/minsoc", lp(CurrentMinSoCHandler)},
+			"setminsoc":        {[]string{"POST", "OPTIONS"}, "/minsoc/{soc:[0-9]+}", lp(MinSoCHandler)},
+			"settargetcharge":  {[]string{"POST", "OPTIONS"}, "/targetcharge/{soc:[0-9]+}/{time:[0-9TZ:-]+}", lp(TargetChargeHandler)},
+			"setdeparture":     {[]string{"POST", "OPTIONS"}, "/departure/{time:[0-9TZ:-]+}", lp(DepartureHandler)},
+			"deletedeparture":  {[]string{"DELETE"}, "/departure", lp(DepartureHandler)},
+			"remotedemand":     {[]string{"POST", "OPTIONS"}, "/remotedemand/{demand:[a-z]+}/{source}", lp(RemoteDemandHandler)},
+			"remotepowerlimit": {[]string{"POST", "OPTIONS"}, "/remotepowerlimit/{power:[0-9]+}/{source}", lp(LoadPointRemotePowerLimitHandler)},
+		}
+
+		for _, r := range routes {
+			lpAPI.Methods(r.Methods...).Path(r.Pattern).Handler(r.HandlerFunc)
+		}
+	}
+}
+
+// NewHTTPd creates HTTP server with configured routes for the sites' loadpoints.
+// Routes of sites with id are prefixed by /sites/<id>.
+func NewHTTPd(url string, sites []core.SiteAPI, hub *SocketHub, cache *util.Cache) *HTTPd {
 	routes := map[string]route{
-		"health":    {[]string{"GET"}, "/health", HealthHandler(site)},
+		"health":    {[]string{"GET"}, "/health", HealthHandler(sites)},
 		"state":     {[]string{"GET"}, "/state", StateHandler(cache)},
 		"templates": {[]string{"GET"}, "/config/templates/{class:[a-z]+}", TemplatesHandler()},
 	}
 
+	// session api
+	if db.Instance != nil {
+		routes["sessions"] = route{[]string{"GET"}, "/sessions", SessionsHandler(db.Instance, false)}
+		routes["sessionscsv"] = route{[]string{"GET"}, "/sessions/csv", SessionsHandler(db.Instance, true)}
+	}
+
 	router := mux.NewRouter().StrictSlash(true)
 
 	// websocket
@@ -338,7 +541,7 @@ func NewHTTPd(url string, site core.SiteAPI, hub *SocketHub, cache *util.Cache)
 	static := router.PathPrefix("/").Subrouter()
 	static.Use(handlers.CompressHandler)
 
-	static.HandleFunc("/", indexHandler(site, useLocalAssets))
+	static.HandleFunc("/", indexHandler(sites, useLocalAssets))
 	var distDir = Dir(false, "/dist/")
 	if useLocalAssets {
 		distDir = http.Dir("./dist")
@@ -359,29 +562,19 @@ func NewHTTPd(url string, site core.SiteAPI, hub *SocketHub, cache *util.Cache)
 		}),
 	))
 
-	// site api
+	// global api
 	for _, r := range routes {
 		api.Methods(r.Methods...).Path(r.Pattern).Handler(r.HandlerFunc)
 	}
 
-	// loadpoint api
-	for id, lp := range site.LoadPoints() {
-		lpAPI := api.PathPrefix(fmt.Sprintf("/loadpoints/%d", id)).Subrouter()
-
-		routes := map[string]route{
-			"getmode":         {[]string{"GET"}, "/mode", CurrentChargeModeHandler(lp)},
-			"setmode":         {[]string{"POST", "OPTIONS"}, "/mode/{mode:[a-z]+}", ChargeModeHandler(lp)},
-			"gettargetsoc":    {[]string{"GET"}, "/targetsoc", CurrentTargetSoCHandler(lp)},
-			"settargetsoc":    {[]string{"POST", "OPTIONS"}, "/targetsoc/{soc:[0-9]+}", TargetSoCHandler(lp)},
-			"getminsoc":       {[]string{"GET"}, "/minsoc", CurrentMinSoCHandler(lp)},
-			"setminsoc":       {[]string{"POST", "OPTIONS"}, "/minsoc/{soc:[0-9]+}", MinSoCHandler(lp)},
-			"settargetcharge": {[]string{"POST", "OPTIONS"}, "/targetcharge/{soc:[0-9]+}/{time:[0-9TZ:-]+}", TargetChargeHandler(lp)},
-			"remotedemand":    {[]string{"POST", "OPTIONS"}, "/remotedemand/{demand:[a-z]+}/{source}", RemoteDemandHandler(lp)},
+	// site api
+	for _, site := range sites {
+		siteAPI := api
+		if site.Name() != "" {
+			siteAPI = api.PathPrefix(fmt.Sprintf("/sites/%s", site.Name())).Subrouter()
 		}
 
-		for _, r := range routes {
-			lpAPI.Methods(r.Methods...).Path(r.Pattern).Handler(r.HandlerFunc)
-		}
+		siteRoutes(siteAPI, site)
 	}
 
 	srv := &HTTPd{
@@ -72,8 +72,28 @@ func (m *Influx) supportedType(p util.Param) bool {
 	}
 }
 
-// Run Influx publisher
-func (m *Influx) Run(loadPoints []core.LoadPointAPI, in <-chan util.Param) {
+// loadPointName returns the name of the site's loadpoint
+func loadPointName(sites []core.SiteAPI, site string, id int) string {
+	for _, s := range sites {
+		if lps := s.LoadPoints(); s.Name() == site && id < len(lps) {
+			return lps[id].Name()
+		}
+	}
+	return ""
+}
+
+// consumerName returns the name of the site's consumer
+func consumerName(sites []core.SiteAPI, site string, id int) string {
+	for _, s := range sites {
+		if consumers := s.Consumers(); s.Name() == site && id < len(consumers) {
+			return consumers[id].Name()
+		}
+	}
+	return ""
+}
+
+// Run Influx publisher. Values of sites with id are tagged with the site id.
+func (m *Influx) Run(sites []core.SiteAPI, in <-chan util.Param) {
 	writer := m.client.WriteAPI(m.org, m.database)
 
 	// log errors
@@ -90,8 +110,14 @@ func (m *Influx) Run(loadPoints []core.LoadPointAPI, in <-chan util.Param) {
 		}
 
 		tags := map[string]string{}
+		if param.Site != "" {
+			tags["site"] = param.Site
+		}
 		if param.LoadPoint != nil {
-			tags["loadpoint"] = loadPoints[*param.LoadPoint].Name()
+			tags["loadpoint"] = loadPointName(sites, param.Site, *param.LoadPoint)
+		}
+		if param.Consumer != nil {
+			tags["consumer"] = consumerName(sites, param.Site, *param.Consumer)
 		}
 
 		fields := map[string]interface{}{}
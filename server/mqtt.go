@@ -68,42 +68,77 @@ func (m *MQTT) publish(topic string, retained bool, payload interface{}) {
 	m.publishSingleValue(topic, retained, payload)
 }
 
-func (m *MQTT) listenSetters(topic string, apiHandler core.LoadPointAPI) {
+// listenSetters subscribes to loadpoint setters. The loadpoint is resolved on each
+// message as it may be replaced by config reload.
+func (m *MQTT) listenSetters(topic string, site core.SiteAPI, id int) {
+	apiHandler := func() core.LoadPointAPI {
+		return site.LoadPoints()[id]
+	}
+
 	m.Handler.Listen(topic+"/mode/set", func(payload string) {
-		apiHandler.SetMode(api.ChargeMode(payload))
+		apiHandler().SetMode(api.ChargeMode(payload))
 	})
 	m.Handler.Listen(topic+"/minSoC/set", func(payload string) {
 		soc, err := strconv.Atoi(payload)
 		if err == nil {
-			_ = apiHandler.SetMinSoC(soc)
+			_ = apiHandler().SetMinSoC(soc)
 		}
 	})
 	m.Handler.Listen(topic+"/targetSoC/set", func(payload string) {
 		soc, err := strconv.Atoi(payload)
 		if err == nil {
-			_ = apiHandler.SetTargetSoC(soc)
+			_ = apiHandler().SetTargetSoC(soc)
+		}
+	})
+	m.Handler.Listen(topic+"/remotePowerLimit/set", func(payload string) {
+		power, err := strconv.ParseFloat(payload, 64)
+		if err == nil {
+			apiHandler().SetRemotePowerLimit("mqtt", power, 0)
 		}
 	})
 }
 
-// Run starts the MQTT publisher for the MQTT API
-func (m *MQTT) Run(site core.SiteAPI, in <-chan util.Param) {
+// siteTopic returns the topic root of the site
+func (m *MQTT) siteTopic(site string) string {
+	if site == "" {
+		return m.root
+	}
+	return fmt.Sprintf("%s/sites/%s", m.root, site)
+}
+
+// listenSiteSetters subscribes to site and loadpoint setters
+func (m *MQTT) listenSiteSetters(site core.SiteAPI) {
+	root := m.siteTopic(site.Name())
+
 	// site setters
-	m.Handler.Listen(fmt.Sprintf("%s/site/prioritySoC/set", m.root), func(payload string) {
+	m.Handler.Listen(fmt.Sprintf("%s/site/prioritySoC/set", root), func(payload string) {
 		soc, err := strconv.Atoi(payload)
 		if err == nil {
 			_ = site.SetPrioritySoC(float64(soc))
 		}
 	})
+	m.Handler.Listen(fmt.Sprintf("%s/site/remotePowerLimit/set", root), func(payload string) {
+		power, err := strconv.ParseFloat(payload, 64)
+		if err == nil {
+			site.SetRemotePowerLimit("mqtt", power, 0)
+		}
+	})
 
 	// number of loadpoints
-	topic := fmt.Sprintf("%s/loadpoints", m.root)
+	topic := fmt.Sprintf("%s/loadpoints", root)
 	m.publish(topic, true, len(site.LoadPoints()))
 
 	// loadpoint setters
-	for id, lp := range site.LoadPoints() {
-		topic := fmt.Sprintf("%s/loadpoints/%d", m.root, id+1)
-		m.listenSetters(topic, lp)
+	for id := range site.LoadPoints() {
+		topic := fmt.Sprintf("%s/loadpoints/%d", root, id+1)
+		m.listenSetters(topic, site, id)
+	}
+}
+
+// Run starts the MQTT publisher for the MQTT API. Topics of sites with id are prefixed by sites/<id>.
+func (m *MQTT) Run(sites []core.SiteAPI, in <-chan util.Param) {
+	for _, site := range sites {
+		m.listenSiteSetters(site)
 	}
 
 	// alive indicator
@@ -112,10 +147,14 @@ func (m *MQTT) Run(site core.SiteAPI, in <-chan util.Param) {
 
 	// publish
 	for p := range in {
-		topic := fmt.Sprintf("%s/site", m.root)
+		topic := fmt.Sprintf("%s/site", m.siteTopic(p.Site))
 		if p.LoadPoint != nil {
 			id := *p.LoadPoint + 1
-			topic = fmt.Sprintf("%s/loadpoints/%d", m.root, id)
+			topic = fmt.Sprintf("%s/loadpoints/%d", m.siteTopic(p.Site), id)
+		}
+		if p.Consumer != nil {
+			id := *p.Consumer + 1
+			topic = fmt.Sprintf("%s/consumers/%d", m.siteTopic(p.Site), id)
 		}
 
 		// alive indicator
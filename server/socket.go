@@ -111,9 +111,15 @@ func kv(p util.Param) string {
 
 	var msg strings.Builder
 	msg.WriteString("\"")
+	if p.Site != "" {
+		msg.WriteString(fmt.Sprintf("sites.%s.", p.Site))
+	}
 	if p.LoadPoint != nil {
 		msg.WriteString(fmt.Sprintf("loadpoints.%d.", *p.LoadPoint))
 	}
+	if p.Consumer != nil {
+		msg.WriteString(fmt.Sprintf("consumers.%d.", *p.Consumer))
+	}
 	msg.WriteString(p.Key)
 	msg.WriteString("\":")
 	msg.WriteString(val)
@@ -4,6 +4,8 @@ import (
 	"reflect"
 	"testing"
 	"time"
+
+	"github.com/andig/evcc/util"
 )
 
 func TestEncode(t *testing.T) {
@@ -31,3 +33,23 @@ func TestEncode(t *testing.T) {
 		}
 	}
 }
+
+func TestKV(t *testing.T) {
+	id := 1
+
+	tc := []struct {
+		in  util.Param
+		out string
+	}{
+		{util.Param{Key: "gridPower", Val: 1.0}, `"gridPower":1`},
+		{util.Param{LoadPoint: &id, Key: "mode", Val: "pv"}, `"loadpoints.1.mode":"pv"`},
+		{util.Param{Site: "north", Key: "gridPower", Val: 1.0}, `"sites.north.gridPower":1`},
+		{util.Param{Site: "north", LoadPoint: &id, Key: "mode", Val: "pv"}, `"sites.north.loadpoints.1.mode":"pv"`},
+	}
+
+	for _, tc := range tc {
+		if out := kv(tc.in); out != tc.out {
+			t.Errorf("expected %s, got %s", tc.out, out)
+		}
+	}
+}
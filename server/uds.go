@@ -26,7 +26,7 @@ func remoteIfExists(file string) {
 }
 
 // HealthListener attaches listener to unix domain socket and runs listener
-func HealthListener(site core.SiteAPI) {
+func HealthListener(sites []core.SiteAPI) {
 	remoteIfExists(SocketPath)
 
 	l, err := net.Listen("unix", SocketPath)
@@ -37,7 +37,7 @@ func HealthListener(site core.SiteAPI) {
 
 	mux := http.NewServeMux()
 	httpd := http.Server{Handler: mux}
-	mux.HandleFunc("/health", HealthHandler(site))
+	mux.HandleFunc("/health", HealthHandler(sites))
 
 	_ = httpd.Serve(l)
 }
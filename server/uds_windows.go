@@ -5,6 +5,6 @@ package server
 import "github.com/andig/evcc/core"
 
 // HealthListener attaches listener to unix domain socket
-func HealthListener(site core.SiteAPI) {
+func HealthListener(sites []core.SiteAPI) {
 	// nop
 }
@@ -0,0 +1,236 @@
+package simulator
+
+import (
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Sample is a recorded measurement of the site's power flows in W
+type Sample struct {
+	Time      time.Time
+	Grid      float64 // Grid power, positive values mean import
+	PV        float64 // PV production
+	Battery   float64 // Battery power, positive values mean discharge
+	Charge    float64 // Recorded charge power, replaced by the simulated charger
+	Connected bool    // Vehicle connected
+}
+
+// column names as used by evcc's influx measurements
+var columns = map[string]string{
+	"grid":         "grid",
+	"gridpower":    "grid",
+	"pv":           "pv",
+	"pvpower":      "pv",
+	"battery":      "battery",
+	"batterypower": "battery",
+	"charge":       "charge",
+	"chargepower":  "charge",
+	"connected":    "connected",
+}
+
+// ReadCSV reads samples from a csv file. Two layouts are supported:
+// one row per timestamp with a time column and one column per measurement, or
+// influx exports with name, time and value columns and one row per measurement.
+// Samples are returned in chronological order.
+func ReadCSV(r io.Reader) ([]Sample, error) {
+	reader := csv.NewReader(r)
+	reader.Comment = '#'
+	reader.FieldsPerRecord = -1
+	reader.TrimLeadingSpace = true
+
+	header, err := reader.Read()
+	if err != nil {
+		return nil, fmt.Errorf("header: %w", err)
+	}
+
+	index := make(map[string]int)
+	for i, col := range header {
+		index[strings.ToLower(strings.TrimSpace(col))] = i
+	}
+
+	if _, ok := index["time"]; !ok {
+		return nil, errors.New("missing time column")
+	}
+
+	_, name := index["name"]
+	_, value := index["value"]
+
+	var samples []Sample
+	if name && value {
+		samples, err = readLong(reader, index)
+	} else {
+		samples, err = readWide(reader, index)
+	}
+
+	if err == nil && len(samples) == 0 {
+		err = errors.New("no samples")
+	}
+
+	return samples, err
+}
+
+// readWide reads samples with one row per timestamp
+func readWide(reader *csv.Reader, index map[string]int) ([]Sample, error) {
+	fields := make(map[string]int)
+	for col, i := range index {
+		if field, ok := columns[col]; ok {
+			fields[field] = i
+		}
+	}
+
+	if _, ok := fields["grid"]; !ok {
+		return nil, errors.New("missing grid column")
+	}
+
+	var samples []Sample
+	for {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		ts, err := parseTime(fieldValue(record, index["time"]))
+		if err != nil {
+			return nil, err
+		}
+
+		sample := Sample{Time: ts, Connected: true}
+		for field, i := range fields {
+			if err := sample.set(field, fieldValue(record, i)); err != nil {
+				return nil, err
+			}
+		}
+
+		samples = append(samples, sample)
+	}
+
+	sort.SliceStable(samples, func(i, j int) bool {
+		return samples[i].Time.Before(samples[j].Time)
+	})
+
+	return samples, nil
+}
+
+// readLong reads samples with one row per measurement and timestamp. Measurements are
+// usually recorded at different times, missing values are taken from the previous sample.
+func readLong(reader *csv.Reader, index map[string]int) ([]Sample, error) {
+	type row struct {
+		time         time.Time
+		field, value string
+	}
+
+	var rows []row
+	var hasGrid bool
+
+	for {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		field, ok := columns[strings.ToLower(fieldValue(record, index["name"]))]
+		if !ok {
+			continue
+		}
+		hasGrid = hasGrid || field == "grid"
+
+		ts, err := parseTime(fieldValue(record, index["time"]))
+		if err != nil {
+			return nil, err
+		}
+
+		rows = append(rows, row{ts, field, fieldValue(record, index["value"])})
+	}
+
+	if len(rows) > 0 && !hasGrid {
+		return nil, errors.New("missing grid measurement")
+	}
+
+	sort.SliceStable(rows, func(i, j int) bool {
+		return rows[i].time.Before(rows[j].time)
+	})
+
+	var samples []Sample
+	sample := Sample{Connected: true}
+
+	for i, row := range rows {
+		sample.Time = row.time
+		if err := sample.set(row.field, row.value); err != nil {
+			return nil, err
+		}
+
+		if i == len(rows)-1 || !rows[i+1].time.Equal(row.time) {
+			samples = append(samples, sample)
+		}
+	}
+
+	return samples, nil
+}
+
+// fieldValue returns the record's i-th field or empty string if missing
+func fieldValue(record []string, i int) string {
+	if i < len(record) {
+		return strings.TrimSpace(record[i])
+	}
+	return ""
+}
+
+// set parses and assigns the field's value. Empty values are ignored.
+func (s *Sample) set(field, value string) error {
+	if value == "" {
+		return nil
+	}
+
+	f, err := strconv.ParseFloat(value, 64)
+	if err != nil {
+		return fmt.Errorf("invalid %s value: %s", field, value)
+	}
+
+	switch field {
+	case "grid":
+		s.Grid = f
+	case "pv":
+		s.PV = f
+	case "battery":
+		s.Battery = f
+	case "charge":
+		s.Charge = f
+	case "connected":
+		s.Connected = f != 0
+	}
+
+	return nil
+}
+
+// parseTime parses RFC3339 timestamps or unix timestamps in s, ms or ns
+func parseTime(s string) (time.Time, error) {
+	if ts, err := time.Parse(time.RFC3339Nano, s); err == nil {
+		return ts, nil
+	}
+
+	i, err := strconv.ParseInt(s, 10, 64)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("invalid time: %s", s)
+	}
+
+	switch {
+	case i > 1e15:
+		return time.Unix(0, i), nil
+	case i > 1e11:
+		return time.Unix(0, i*int64(time.Millisecond)), nil
+	default:
+		return time.Unix(i, 0), nil
+	}
+}
@@ -0,0 +1,84 @@
+package simulator
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestReadCSV(t *testing.T) {
+	tc := []struct {
+		name, csv string
+		samples   []Sample
+	}{
+		{
+			"wide",
+			`time,gridPower,pvPower,chargePower
+1600000010,-500,3000,1000
+1600000000,100,2000,0`,
+			[]Sample{
+				{Time: time.Unix(1600000000, 0), Grid: 100, PV: 2000, Connected: true},
+				{Time: time.Unix(1600000010, 0), Grid: -500, PV: 3000, Charge: 1000, Connected: true},
+			},
+		},
+		{
+			"wide rfc3339 with connected",
+			`Time, Grid, Connected
+2020-09-13T12:26:40Z, 100, 0`,
+			[]Sample{
+				{Time: time.Unix(1600000000, 0), Grid: 100},
+			},
+		},
+		{
+			"influx",
+			`name,tags,time,value
+gridPower,,1600000000000000000,100
+pvPower,,1600000000000000000,2000
+gridPower,,1600000010000000000,-500
+socCharge,,1600000010000000000,50`,
+			[]Sample{
+				{Time: time.Unix(1600000000, 0), Grid: 100, PV: 2000, Connected: true},
+				{Time: time.Unix(1600000010, 0), Grid: -500, PV: 2000, Connected: true},
+			},
+		},
+	}
+
+	for _, tc := range tc {
+		t.Log(tc.name)
+
+		samples, err := ReadCSV(strings.NewReader(tc.csv))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if len(samples) != len(tc.samples) {
+			t.Fatalf("expected %d samples, got %d", len(tc.samples), len(samples))
+		}
+
+		for i, s := range samples {
+			if !s.Time.Equal(tc.samples[i].Time) {
+				t.Errorf("sample %d: expected time %v, got %v", i, tc.samples[i].Time, s.Time)
+			}
+
+			s.Time = tc.samples[i].Time
+			if s != tc.samples[i] {
+				t.Errorf("sample %d: expected %+v, got %+v", i, tc.samples[i], s)
+			}
+		}
+	}
+}
+
+func TestReadCSVErrors(t *testing.T) {
+	for _, csv := range []string{
+		"grid\n100",                          // missing time
+		"time,pv\n1600000000,100",            // missing grid
+		"time,grid\n1600000000,foo",          // invalid value
+		"time,grid\nfoo,100",                 // invalid time
+		"name,time,value\npv,1600000000,100", // missing grid measurement
+		"time,grid",                          // no samples
+	} {
+		if _, err := ReadCSV(strings.NewReader(csv)); err == nil {
+			t.Errorf("expected error for %q", csv)
+		}
+	}
+}
@@ -0,0 +1,142 @@
+package simulator
+
+import (
+	"math"
+	"time"
+
+	"github.com/andig/evcc/api"
+)
+
+// meter replays a power value
+type meter func() float64
+
+// CurrentPower implements the api.Meter interface
+func (m meter) CurrentPower() (float64, error) {
+	return m(), nil
+}
+
+// vehicle is a simulated vehicle charged by the simulated charger
+type vehicle struct {
+	capacity float64 // kWh
+	soc      float64 // %
+}
+
+// Title implements the api.Vehicle interface
+func (v *vehicle) Title() string {
+	return "sim"
+}
+
+// Capacity implements the api.Vehicle interface
+func (v *vehicle) Capacity() int64 {
+	return int64(v.capacity)
+}
+
+// Identifiers implements the api.Vehicle interface
+func (v *vehicle) Identifiers() []string {
+	return nil
+}
+
+// Defaults implements the api.Vehicle interface
+func (v *vehicle) Defaults() api.VehicleDefaults {
+	return api.VehicleDefaults{}
+}
+
+// SoC implements the api.Vehicle interface
+func (v *vehicle) SoC() (float64, error) {
+	return v.soc, nil
+}
+
+// charge adds the energy in Wh to the vehicle's battery and returns the energy actually charged
+func (v *vehicle) charge(energy float64) float64 {
+	if v.capacity > 0 {
+		energy = math.Min(energy, (100-v.soc)*v.capacity*10)
+		v.soc = math.Min(100, v.soc+energy/v.capacity/10)
+	}
+	return energy
+}
+
+// charger is a simulated charger drawing the configured current until the vehicle is full
+type charger struct {
+	vehicle   *vehicle
+	phases    int64
+	voltage   float64
+	connected bool
+	enabled   bool
+	current   int64
+	switches  int           // number of enable/disable operations
+	energy    float64       // Wh charged while connected
+	duration  time.Duration // charging time while connected
+}
+
+// connect sets the vehicle's connection state. A new connection starts a new session.
+func (c *charger) connect(connected bool) {
+	if connected && !c.connected {
+		c.energy = 0
+		c.duration = 0
+	}
+	c.connected = connected
+}
+
+// advance charges the vehicle at the current power for the given duration and returns the energy in Wh
+func (c *charger) advance(d time.Duration) float64 {
+	power, _ := c.CurrentPower()
+	energy := c.vehicle.charge(power * d.Hours())
+
+	if power > 0 {
+		c.duration += d
+	}
+
+	c.energy += energy
+
+	return energy
+}
+
+// Status implements the api.Charger interface
+func (c *charger) Status() (api.ChargeStatus, error) {
+	switch {
+	case !c.connected:
+		return api.StatusA, nil
+	case c.enabled && c.current > 0 && c.vehicle.soc < 100:
+		return api.StatusC, nil
+	default:
+		return api.StatusB, nil
+	}
+}
+
+// Enabled implements the api.Charger interface
+func (c *charger) Enabled() (bool, error) {
+	return c.enabled, nil
+}
+
+// Enable implements the api.Charger interface
+func (c *charger) Enable(enable bool) error {
+	if enable != c.enabled {
+		c.switches++
+	}
+	c.enabled = enable
+	return nil
+}
+
+// MaxCurrent implements the api.Charger interface
+func (c *charger) MaxCurrent(current int64) error {
+	c.current = current
+	return nil
+}
+
+// CurrentPower implements the api.Meter interface
+func (c *charger) CurrentPower() (float64, error) {
+	if status, _ := c.Status(); status != api.StatusC {
+		return 0, nil
+	}
+	return float64(c.current) * float64(c.phases) * c.voltage, nil
+}
+
+// ChargedEnergy implements the api.ChargeRater interface
+func (c *charger) ChargedEnergy() (float64, error) {
+	return c.energy / 1e3, nil
+}
+
+// ChargingTime implements the api.ChargeTimer interface
+func (c *charger) ChargingTime() (time.Duration, error) {
+	return c.duration, nil
+}
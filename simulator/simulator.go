@@ -0,0 +1,249 @@
+package simulator
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/andig/evcc/api"
+	"github.com/andig/evcc/core"
+	"github.com/andig/evcc/tariff"
+	"github.com/andig/evcc/util"
+)
+
+// Config is the simulated site. Device references are provided by the simulation.
+type Config struct {
+	Site      map[string]interface{} // Site configuration
+	LoadPoint map[string]interface{} // Loadpoint configuration
+	Interval  time.Duration          // Control loop interval
+	Capacity  float64                // Vehicle capacity in kWh
+	SoC       float64                // Initial vehicle SoC in %
+	Phases    int64                  // Charger phases, loadpoint configuration if zero
+}
+
+// Setting contains the control parameters compared between simulation runs
+type Setting struct {
+	Mode          api.ChargeMode
+	Enable        float64       // PV mode enable threshold in W
+	Disable       float64       // PV mode disable threshold in W
+	GuardDuration time.Duration // Charger enable/disable minimum holding time
+	ResidualPower float64       // Site residual power in W
+}
+
+// Result is the outcome of a simulation run
+type Result struct {
+	Setting
+	ChargedEnergy float64 // kWh
+	GridImport    float64 // kWh
+	Switches      int     // Charger enable/disable operations
+	SoC           float64 // Final vehicle SoC in %
+}
+
+// devices provides the simulated devices to the site and loadpoint
+type devices struct {
+	meters  map[string]api.Meter
+	charger *charger
+	vehicle *vehicle
+}
+
+func (d *devices) Meter(name string) api.Meter {
+	return d.meters[name]
+}
+
+func (d *devices) Charger(name string) api.Charger {
+	return d.charger
+}
+
+func (d *devices) Vehicle(name string) api.Vehicle {
+	return d.vehicle
+}
+
+// override sets the configuration key replacing existing keys regardless of case
+func override(conf map[string]interface{}, key string, val interface{}) {
+	for k := range conf {
+		if strings.EqualFold(k, key) {
+			delete(conf, k)
+		}
+	}
+
+	if val != nil {
+		conf[key] = val
+	}
+}
+
+// lookup returns the configuration key's value regardless of case
+func lookup(conf map[string]interface{}, key string) interface{} {
+	for k, v := range conf {
+		if strings.EqualFold(k, key) {
+			return v
+		}
+	}
+	return nil
+}
+
+// copyConfig returns a shallow copy of the configuration
+func copyConfig(conf map[string]interface{}) map[string]interface{} {
+	res := make(map[string]interface{}, len(conf))
+	for k, v := range conf {
+		res[k] = v
+	}
+	return res
+}
+
+// threshold returns the threshold configuration with the threshold replaced
+func threshold(conf map[string]interface{}, key string, value float64) map[string]interface{} {
+	res := make(map[string]interface{})
+	if existing, ok := lookup(conf, key).(map[string]interface{}); ok {
+		res = copyConfig(existing)
+	}
+	override(res, "threshold", value)
+	return res
+}
+
+// configure creates site and loadpoint from configuration. If setting is nil the configured values are used.
+func configure(conf Config, d *devices, setting *Setting) (*core.Site, *core.LoadPoint, error) {
+	lpc := copyConfig(conf.LoadPoint)
+	override(lpc, "charger", "sim")
+	override(lpc, "vehicle", "sim")
+	override(lpc, "vehicles", nil)
+	override(lpc, "meters", nil)
+	if conf.Phases > 0 {
+		override(lpc, "phases", conf.Phases)
+	}
+
+	sitec := copyConfig(conf.Site)
+	meters := map[string]interface{}{"grid": "grid", "pv": "pv"}
+	if _, ok := d.meters["battery"]; ok {
+		meters["battery"] = "battery"
+	}
+	override(sitec, "meters", meters)
+
+	if setting != nil {
+		override(lpc, "mode", string(setting.Mode))
+		override(lpc, "guardDuration", setting.GuardDuration)
+		override(lpc, "enable", threshold(lpc, "enable", setting.Enable))
+		override(lpc, "disable", threshold(lpc, "disable", setting.Disable))
+		override(sitec, "residualPower", setting.ResidualPower)
+	}
+
+	lp, err := core.NewLoadPointFromConfig(util.NewLogger("lp-1"), d, lpc)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed configuring loadpoint: %w", err)
+	}
+
+	site, err := core.NewSiteFromConfig(util.NewLogger("site"), d, sitec, []*core.LoadPoint{lp}, tariff.Tariffs{}, nil)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed configuring site: %w", err)
+	}
+
+	return site, lp, nil
+}
+
+// newDevices creates the simulated devices replaying the current sample
+func newDevices(conf Config, sample *Sample, battery bool) *devices {
+	v := &vehicle{capacity: conf.Capacity, soc: conf.SoC}
+	c := &charger{vehicle: v}
+
+	d := &devices{
+		charger: c,
+		vehicle: v,
+		meters: map[string]api.Meter{
+			// recorded charge power is replaced by the simulated charger
+			"grid": meter(func() float64 {
+				power, _ := c.CurrentPower()
+				return sample.Grid - sample.Charge + power
+			}),
+			"pv": meter(func() float64 { return sample.PV }),
+		},
+	}
+
+	if battery {
+		d.meters["battery"] = meter(func() float64 { return sample.Battery })
+	}
+
+	return d
+}
+
+// hasBattery returns true if the samples contain battery power
+func hasBattery(samples []Sample) bool {
+	for _, sample := range samples {
+		if sample.Battery != 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// DefaultSetting returns the setting defined by the configuration
+func DefaultSetting(conf Config) (Setting, error) {
+	site, lp, err := configure(conf, newDevices(conf, &Sample{}, false), nil)
+	if err != nil {
+		return Setting{}, err
+	}
+
+	return Setting{
+		Mode:          lp.GetMode(),
+		Enable:        lp.Enable.Threshold,
+		Disable:       lp.Disable.Threshold,
+		GuardDuration: lp.GuardDuration,
+		ResidualPower: site.ResidualPower,
+	}, nil
+}
+
+// Run replays the samples against the simulated site. Each sample is held until the next sample.
+func Run(samples []Sample, conf Config, setting Setting) (Result, error) {
+	if len(samples) == 0 {
+		return Result{}, errors.New("no samples")
+	}
+	if conf.Interval <= 0 {
+		return Result{}, errors.New("invalid interval")
+	}
+
+	sample := samples[0]
+	d := newDevices(conf, &sample, hasBattery(samples))
+
+	site, lp, err := configure(conf, d, &setting)
+	if err != nil {
+		return Result{}, err
+	}
+
+	// simulated charger uses the loadpoint's phases and site's voltage
+	d.charger.phases = lp.Phases
+	d.charger.voltage = site.Voltage
+
+	start, end := samples[0].Time, samples[len(samples)-1].Time
+	sim := core.NewSimulation(site, start)
+	defer sim.Close()
+
+	res := Result{Setting: setting}
+
+	var idx int
+	for ts := start; !ts.After(end); ts = ts.Add(conf.Interval) {
+		for idx+1 < len(samples) && !samples[idx+1].Time.After(ts) {
+			idx++
+		}
+
+		sample = samples[idx]
+		d.charger.connect(sample.Connected)
+
+		sim.Step(ts)
+
+		dt := conf.Interval
+		if ts.Add(dt).After(end) {
+			dt = end.Sub(ts)
+		}
+
+		power, _ := d.charger.CurrentPower()
+		if grid := sample.Grid - sample.Charge + power; grid > 0 {
+			res.GridImport += grid * dt.Hours() / 1e3
+		}
+
+		res.ChargedEnergy += d.charger.advance(dt) / 1e3
+	}
+
+	res.Switches = d.charger.switches
+	res.SoC = d.vehicle.soc
+
+	return res, nil
+}
@@ -0,0 +1,80 @@
+package simulator
+
+import (
+	"math"
+	"runtime"
+	"testing"
+	"time"
+
+	"github.com/andig/evcc/api"
+)
+
+// surplus returns an hour of constant pv surplus
+func surplus(power float64) []Sample {
+	start := time.Unix(1600000000, 0)
+	return []Sample{
+		{Time: start, Grid: -power, PV: power, Connected: true},
+		{Time: start.Add(time.Hour), Grid: -power, PV: power, Connected: true},
+	}
+}
+
+func TestRun(t *testing.T) {
+	conf := Config{
+		Interval: 10 * time.Second,
+		Capacity: 50,
+		SoC:      20,
+		Phases:   1,
+	}
+
+	tc := []struct {
+		mode         api.ChargeMode
+		surplus      float64
+		energy, grid float64
+		switches     int
+	}{
+		{api.ModeOff, 5000, 0, 0, 0},
+		{api.ModeNow, 0, 3.68, 3.68, 1},   // 16A
+		{api.ModePV, 5000, 3.68, 0, 1},    // surplus exceeds max current
+		{api.ModePV, 2300, 2.3, 0, 1},     // 10A
+		{api.ModeMinPV, 0, 1.38, 1.38, 1}, // 6A
+	}
+
+	goroutines := runtime.NumGoroutine()
+
+	for _, tc := range tc {
+		t.Log(tc)
+
+		res, err := Run(surplus(tc.surplus), conf, Setting{
+			Mode:          tc.mode,
+			GuardDuration: time.Minute,
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if math.Abs(res.ChargedEnergy-tc.energy) > 0.05 {
+			t.Errorf("expected charged energy %.2fkWh, got %.2fkWh", tc.energy, res.ChargedEnergy)
+		}
+
+		if math.Abs(res.GridImport-tc.grid) > 0.05 {
+			t.Errorf("expected grid import %.2fkWh, got %.2fkWh", tc.grid, res.GridImport)
+		}
+
+		if res.Switches != tc.switches {
+			t.Errorf("expected %d switches, got %d", tc.switches, res.Switches)
+		}
+
+		if soc := conf.SoC + res.ChargedEnergy/conf.Capacity*100; math.Abs(res.SoC-soc) > 0.01 {
+			t.Errorf("expected soc %.1f%%, got %.1f%%", soc, res.SoC)
+		}
+	}
+
+	// finished simulations don't leave goroutines behind
+	for i := 0; runtime.NumGoroutine() > goroutines && i < 100; i++ {
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	if n := runtime.NumGoroutine(); n > goroutines {
+		t.Errorf("expected %d goroutines, got %d", goroutines, n)
+	}
+}
@@ -0,0 +1,110 @@
+package tariff
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/andig/evcc/api"
+	"github.com/andig/evcc/util"
+	"github.com/benbjohnson/clock"
+)
+
+// horizon is the period for which the fixed tariff provides rates
+const horizon = 48 * time.Hour
+
+type zone struct {
+	from, to int // hours of day, to is exclusive
+	price    float64
+}
+
+// contains checks if the hour of day is covered by the zone. Zones may wrap around midnight.
+func (z zone) contains(hour int) bool {
+	if z.from <= z.to {
+		return hour >= z.from && hour < z.to
+	}
+	return hour >= z.from || hour < z.to
+}
+
+// Fixed is a time-of-use tariff with fixed prices per hour of day
+type Fixed struct {
+	clock clock.Clock
+	price float64
+	zones []zone
+}
+
+func init() {
+	registry.Add("fixed", NewFixedFromConfig)
+}
+
+// NewFixedFromConfig creates a fixed time-of-use tariff from config
+func NewFixedFromConfig(other map[string]interface{}) (api.Tariff, error) {
+	cc := struct {
+		Price float64
+		Zones []struct {
+			Hours string
+			Price float64
+		}
+	}{}
+
+	if err := util.DecodeOther(other, &cc); err != nil {
+		return nil, err
+	}
+
+	t := &Fixed{
+		clock: clock.New(),
+		price: cc.Price,
+	}
+
+	for _, z := range cc.Zones {
+		from, to, err := parseHours(z.Hours)
+		if err != nil {
+			return nil, err
+		}
+
+		t.zones = append(t.zones, zone{from: from, to: to, price: z.Price})
+	}
+
+	return t, nil
+}
+
+// parseHours parses hour ranges like 22-6
+func parseHours(s string) (int, int, error) {
+	segs := strings.SplitN(s, "-", 2)
+	if len(segs) != 2 {
+		return 0, 0, fmt.Errorf("invalid hours: %s", s)
+	}
+
+	var res [2]int
+	for i, seg := range segs {
+		hour, err := strconv.Atoi(strings.TrimSpace(seg))
+		if err != nil || hour < 0 || hour > 24 {
+			return 0, 0, fmt.Errorf("invalid hours: %s", s)
+		}
+		res[i] = hour % 24
+	}
+
+	return res[0], res[1], nil
+}
+
+// Rates implements the api.Tariff interface
+func (t *Fixed) Rates() (api.Rates, error) {
+	now := t.clock.Now()
+	start := time.Date(now.Year(), now.Month(), now.Day(), now.Hour(), 0, 0, 0, now.Location())
+
+	res := make(api.Rates, 0, int(horizon/time.Hour))
+	for ts := start; ts.Before(start.Add(horizon)); ts = ts.Add(time.Hour) {
+		price := t.price
+		for _, z := range t.zones {
+			if z.contains(ts.Hour()) {
+				price = z.price
+				break
+			}
+		}
+
+		res = append(res, api.Rate{Start: ts, End: ts.Add(time.Hour), Price: price})
+	}
+
+	return res, nil
+}
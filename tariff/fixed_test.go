@@ -0,0 +1,78 @@
+package tariff
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/benbjohnson/clock"
+)
+
+func TestFixed(t *testing.T) {
+	tf, err := NewFixedFromConfig(map[string]interface{}{
+		"price": 0.3,
+		"zones": []map[string]interface{}{
+			{"hours": "22-6", "price": 0.2},
+			{"hours": "12-14", "price": 0.1},
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	clck := clock.NewMock()
+	clck.Set(time.Date(2021, 1, 1, 21, 30, 0, 0, time.UTC))
+	tf.(*Fixed).clock = clck
+
+	rates, err := tf.Rates()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(rates) != int(horizon/time.Hour) {
+		t.Errorf("expected %d rates, got %d", int(horizon/time.Hour), len(rates))
+	}
+
+	if start := rates[0].Start; !start.Equal(time.Date(2021, 1, 1, 21, 0, 0, 0, time.UTC)) {
+		t.Errorf("unexpected start %v", start)
+	}
+
+	tc := map[int]float64{
+		21: 0.3, 22: 0.2, 23: 0.2, 0: 0.2, 5: 0.2, 6: 0.3, 11: 0.3, 12: 0.1, 13: 0.1, 14: 0.3,
+	}
+
+	for _, r := range rates[:24] {
+		if price, ok := tc[r.Start.Hour()]; ok && price != r.Price {
+			t.Errorf("hour %d: expected %.1f, got %.1f", r.Start.Hour(), price, r.Price)
+		}
+	}
+
+	if r, err := rates.Current(clck.Now()); err != nil || r.Price != 0.3 {
+		t.Errorf("unexpected current rate %v: %v", r, err)
+	}
+}
+
+func TestFileRates(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "rates.json")
+	if err := ioutil.WriteFile(path, []byte(`{"data":[
+		{"start":"2021-01-01T01:00:00Z","end":"2021-01-01T02:00:00Z","price":0.2},
+		{"start":"2021-01-01T00:00:00Z","end":"2021-01-01T01:00:00Z","price":0.1}
+	]}`), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	tf, err := NewFileFromConfig(map[string]interface{}{"path": path, "jq": ".data"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	rates, err := tf.Rates()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(rates) != 2 || rates[0].Price != 0.1 || rates[1].Price != 0.2 {
+		t.Errorf("unexpected rates %+v", rates)
+	}
+}
@@ -0,0 +1,52 @@
+package tariff
+
+import (
+	"github.com/andig/evcc/api"
+	"github.com/andig/evcc/provider/slots"
+	"github.com/andig/evcc/util"
+)
+
+// Slots is a tariff retrieving rates from a JSON web service or local file
+type Slots struct {
+	*slots.Provider
+}
+
+func init() {
+	registry.Add("http", NewHTTPFromConfig)
+	registry.Add("file", NewFileFromConfig)
+}
+
+// NewHTTPFromConfig creates a HTTP tariff from config
+func NewHTTPFromConfig(other map[string]interface{}) (api.Tariff, error) {
+	p, err := slots.NewHTTPFromConfig(util.NewLogger("tariff"), "price", other)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Slots{p}, nil
+}
+
+// NewFileFromConfig creates a file tariff from config
+func NewFileFromConfig(other map[string]interface{}) (api.Tariff, error) {
+	p, err := slots.NewFileFromConfig(util.NewLogger("tariff"), "price", other)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Slots{p}, nil
+}
+
+// Rates implements the api.Tariff interface
+func (t *Slots) Rates() (api.Rates, error) {
+	s, err := t.Slots()
+	if err != nil {
+		return nil, err
+	}
+
+	res := make(api.Rates, 0, len(s))
+	for _, slot := range s {
+		res = append(res, api.Rate{Start: slot.Start, End: slot.End, Price: slot.Value})
+	}
+
+	return res, nil
+}
@@ -29,37 +29,76 @@ func (c *Cache) Run(in <-chan Param) {
 }
 
 // State provides a structured copy of the cached values
-// Loadpoints are aggregated as loadpoints array
+// Loadpoints are aggregated as loadpoints array, named sites as sites map
 func (c *Cache) State() map[string]interface{} {
 	c.Lock()
 	defer c.Unlock()
 
+	params := make(map[string][]Param)
+	for _, param := range c.val {
+		params[param.Site] = append(params[param.Site], param)
+	}
+
+	res := state(params[""])
+
+	sites := make(map[string]interface{})
+	for site, p := range params {
+		if site != "" {
+			sites[site] = state(p)
+		}
+	}
+
+	if len(sites) > 0 {
+		res["sites"] = sites
+	}
+
+	return res
+}
+
+// state aggregates a site's values
+func state(params []Param) map[string]interface{} {
 	res := map[string]interface{}{}
 	lps := make(map[int]map[string]interface{})
+	consumers := make(map[int]map[string]interface{})
+
+	// values of loadpoints and consumers are grouped by id
+	group := func(groups map[int]map[string]interface{}, id int, param Param) {
+		g, ok := groups[id]
+		if !ok {
+			g = make(map[string]interface{})
+			groups[id] = g
+		}
+		g[param.Key] = param.Val
+	}
 
-	for _, param := range c.val {
-		if param.LoadPoint == nil {
+	for _, param := range params {
+		switch {
+		case param.LoadPoint != nil:
+			group(lps, *param.LoadPoint, param)
+		case param.Consumer != nil:
+			group(consumers, *param.Consumer, param)
+		default:
 			res[param.Key] = param.Val
-		} else {
-			lp, ok := lps[*param.LoadPoint]
-			if !ok {
-				lp = make(map[string]interface{})
-				lps[*param.LoadPoint] = lp
-			}
-			lp[param.Key] = param.Val
 		}
 	}
 
-	// convert map to array
-	loadpoints := make([]map[string]interface{}, len(lps))
-	for id, lp := range lps {
-		loadpoints[id] = lp
+	res["loadpoints"] = toArray(lps)
+	if len(consumers) > 0 {
+		res["consumers"] = toArray(consumers)
 	}
-	res["loadpoints"] = loadpoints
 
 	return res
 }
 
+// toArray converts grouped values to array
+func toArray(groups map[int]map[string]interface{}) []map[string]interface{} {
+	res := make([]map[string]interface{}, len(groups))
+	for id, g := range groups {
+		res[id] = g
+	}
+	return res
+}
+
 // All provides a copy of the cached values
 func (c *Cache) All() []Param {
 	c.Lock()
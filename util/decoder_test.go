@@ -0,0 +1,33 @@
+package util
+
+import (
+	"testing"
+
+	"github.com/andig/evcc/api"
+)
+
+func TestDecodeChargeMode(t *testing.T) {
+	cases := []struct {
+		mode   string
+		expect api.ChargeMode
+		err    bool
+	}{
+		{"", "", false},
+		{"PV", api.ModePV, false},
+		{"minpv", api.ModeMinPV, false},
+		{"fast", "", true},
+	}
+
+	for _, tc := range cases {
+		var cc api.VehicleDefaults
+
+		err := DecodeOther(map[string]interface{}{"mode": tc.mode}, &cc)
+		if tc.err != (err != nil) {
+			t.Errorf("%s: unexpected error %v", tc.mode, err)
+		}
+
+		if cc.Mode != tc.expect {
+			t.Errorf("%s: expected %s, got %s", tc.mode, tc.expect, cc.Mode)
+		}
+	}
+}
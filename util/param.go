@@ -4,16 +4,24 @@ import "strconv"
 
 // Param is the broadcast channel data type
 type Param struct {
+	Site      string // optional site id
 	LoadPoint *int
+	Consumer  *int
 	Key       string
 	Val       interface{}
 }
 
-// UniqueID returns unique identifier for parameter LoadPoint/Key combination
+// UniqueID returns unique identifier for parameter Site/LoadPoint/Consumer/Key combination
 func (p Param) UniqueID() string {
 	key := p.Key
 	if p.LoadPoint != nil {
 		key = strconv.Itoa(*p.LoadPoint) + "." + key
 	}
+	if p.Consumer != nil {
+		key = "consumer" + strconv.Itoa(*p.Consumer) + "." + key
+	}
+	if p.Site != "" {
+		key = p.Site + "." + key
+	}
 	return key
 }
@@ -3,6 +3,7 @@ package bluelink
 import (
 	"errors"
 	"fmt"
+	"math"
 	"net/http"
 	"net/http/cookiejar"
 	"net/url"
@@ -21,6 +22,8 @@ import (
 const (
 	resOK       = "S"
 	resAuthFail = "F"
+
+	defaultClimateTemp = 21.0 // °C, used if no target temperature is given
 )
 
 var (
@@ -33,6 +36,7 @@ var (
 		AccessToken: "/api/v1/user/oauth2/token",
 		Vehicles:    "/api/v1/spa/vehicles",
 		Status:      "/api/v1/spa/vehicles/%s/status",
+		Climate:     "/api/v2/spa/vehicles/%s/control/temperature",
 	}
 )
 
@@ -48,6 +52,7 @@ type Config struct {
 	AccessToken       string
 	Vehicles          string
 	Status            string
+	Climate           string
 }
 
 // API implements the Kia/Hyundai bluelink api.
@@ -291,42 +296,51 @@ func (v *API) authFlow() (err error) {
 	return err
 }
 
-func (v *API) getStatus() (response, error) {
-	var resp response
-
-	if v.auth.accToken == "" {
-		return resp, errAuthFail
-	}
-
-	headers := map[string]string{
+func (v *API) headers() map[string]string {
+	return map[string]string{
 		"Authorization":       v.auth.accToken,
 		"ccsp-device-id":      v.auth.deviceID,
 		"ccsp-application-id": v.config.CCSPApplicationID,
 		"offset":              "1",
 		"User-Agent":          "okhttp/3.10.0",
 	}
+}
+
+func (v *API) getStatus() (response, error) {
+	var resp response
+
+	if v.auth.accToken == "" {
+		return resp, errAuthFail
+	}
 
 	uri := fmt.Sprintf(v.config.URI+v.config.Status, v.auth.vehicleID)
-	req, err := request.New(http.MethodGet, uri, nil, headers)
+	req, err := request.New(http.MethodGet, uri, nil, v.headers())
 	if err == nil {
-		err = v.DoJSON(req, &resp)
+		err = v.do(req, &resp)
+	}
 
-		if err != nil {
-			// handle http 401, 403
-			if se, ok := err.(request.StatusError); ok && se.HasStatus(http.StatusUnauthorized, http.StatusForbidden) {
-				err = errAuthFail
-			}
+	return resp, err
+}
+
+// do executes the request and checks the response code
+func (v *API) do(req *http.Request, resp *response) error {
+	err := v.DoJSON(req, resp)
+
+	if err != nil {
+		// handle http 401, 403
+		if se, ok := err.(request.StatusError); ok && se.HasStatus(http.StatusUnauthorized, http.StatusForbidden) {
+			err = errAuthFail
 		}
+	}
 
-		if err == nil && resp.RetCode != resOK {
-			err = errors.New("unexpected response")
-			if resp.RetCode == resAuthFail {
-				err = errAuthFail
-			}
+	if err == nil && resp.RetCode != resOK {
+		err = errors.New("unexpected response")
+		if resp.RetCode == resAuthFail {
+			err = errAuthFail
 		}
 	}
 
-	return resp, err
+	return err
 }
 
 // status retrieves the bluelink status response
@@ -345,6 +359,64 @@ func (v *API) statusAPI() (interface{}, error) {
 	return res, err
 }
 
+// tempCode converts the temperature to the api's hex code in 0.5°C steps starting at 06H for 14°C
+func tempCode(temp float64) string {
+	temp = math.Max(14, math.Min(temp, 30))
+	return fmt.Sprintf("%02XH", 6+int(math.Round((temp-14)*2)))
+}
+
+// climate sends the climate control action
+func (v *API) climate(action string, temp float64) error {
+	if temp == 0 {
+		temp = defaultClimateTemp
+	}
+
+	data := map[string]interface{}{
+		"action":   action,
+		"hvacType": 0,
+		"options": map[string]interface{}{
+			"defrost":  true,
+			"heating1": 0,
+		},
+		"tempCode": tempCode(temp),
+		"unit":     "C",
+	}
+
+	send := func() error {
+		if v.auth.accToken == "" {
+			return errAuthFail
+		}
+
+		uri := fmt.Sprintf(v.config.URI+v.config.Climate, v.auth.vehicleID)
+		req, err := request.New(http.MethodPost, uri, request.MarshalJSON(data), v.headers(), request.JSONEncoding)
+		if err == nil {
+			var resp response
+			err = v.do(req, &resp)
+		}
+
+		return err
+	}
+
+	err := send()
+	if err != nil && errors.Is(err, errAuthFail) {
+		if err = v.authFlow(); err == nil {
+			err = send()
+		}
+	}
+
+	return err
+}
+
+// StartClimate implements the api.VehicleClimateController interface
+func (v *API) StartClimate(targetTemp float64) error {
+	return v.climate("start", targetTemp)
+}
+
+// StopClimate implements the api.VehicleClimateController interface
+func (v *API) StopClimate() error {
+	return v.climate("stop", 0)
+}
+
 // SoC implements the api.Vehicle interface
 func (v *API) SoC() (float64, error) {
 	res, err := v.apiG()
@@ -49,6 +49,9 @@ func NewBMWFromConfig(other map[string]interface{}) (api.Vehicle, error) {
 	cc := struct {
 		Title               string
 		Capacity            int64
+		Identifiers         []string
+		Defaults            api.VehicleDefaults
+		ChargeCurve         api.ChargeCurve
 		User, Password, VIN string
 		Cache               time.Duration
 	}{
@@ -62,7 +65,7 @@ func NewBMWFromConfig(other map[string]interface{}) (api.Vehicle, error) {
 	log := util.NewLogger("bmw")
 
 	v := &BMW{
-		embed:    &embed{cc.Title, cc.Capacity},
+		embed:    &embed{cc.Title, cc.Capacity, cc.Identifiers, cc.Defaults, cc.ChargeCurve},
 		Helper:   request.NewHelper(log),
 		user:     cc.User,
 		password: cc.Password,
@@ -127,14 +130,14 @@ func (v *BMW) login(user, password string) error {
 	return nil
 }
 
-func (v *BMW) request(uri string) (*http.Request, error) {
+func (v *BMW) request(method, uri string) (*http.Request, error) {
 	if v.token == "" || time.Since(v.tokenValid) > 0 {
 		if err := v.login(v.user, v.password); err != nil {
 			return nil, err
 		}
 	}
 
-	req, err := http.NewRequest(http.MethodGet, uri, nil)
+	req, err := http.NewRequest(method, uri, nil)
 	if err == nil {
 		req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", v.token))
 	}
@@ -149,7 +152,7 @@ func (v *BMW) vehicles() ([]string, error) {
 
 	var vehicles []string
 
-	req, err := v.request(uri)
+	req, err := v.request(http.MethodGet, uri)
 	if err == nil {
 		err = v.DoJSON(req, &resp)
 	}
@@ -168,7 +171,7 @@ func (v *BMW) chargeState() (float64, error) {
 	var resp bmwDynamicResponse
 	uri := fmt.Sprintf("%s/vehicle/dynamic/v1/%s", bmwAPI, v.vin)
 
-	req, err := v.request(uri)
+	req, err := v.request(http.MethodGet, uri)
 	if err != nil {
 		return 0, err
 	}
@@ -181,3 +184,26 @@ func (v *BMW) chargeState() (float64, error) {
 func (v *BMW) SoC() (float64, error) {
 	return v.chargeStateG()
 }
+
+// remoteService executes the remote service on the vehicle
+func (v *BMW) remoteService(service string) error {
+	uri := fmt.Sprintf("%s/vehicle/remoteservices/v1/%s/%s", bmwAPI, v.vin, service)
+
+	req, err := v.request(http.MethodPost, uri)
+	if err == nil {
+		var resp interface{}
+		err = v.DoJSON(req, &resp)
+	}
+
+	return err
+}
+
+// StartCharge implements the api.VehicleStartCharge interface
+func (v *BMW) StartCharge() error {
+	return v.remoteService("CHARGE_NOW")
+}
+
+// StopCharge implements the api.VehicleStopCharge interface
+func (v *BMW) StopCharge() error {
+	return v.remoteService("CHARGING_STOP")
+}
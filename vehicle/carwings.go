@@ -28,6 +28,9 @@ func NewCarWingsFromConfig(other map[string]interface{}) (api.Vehicle, error) {
 	cc := struct {
 		Title                  string
 		Capacity               int64
+		Identifiers            []string
+		Defaults               api.VehicleDefaults
+		ChargeCurve            api.ChargeCurve
 		User, Password, Region string
 		Cache                  time.Duration
 	}{
@@ -40,7 +43,7 @@ func NewCarWingsFromConfig(other map[string]interface{}) (api.Vehicle, error) {
 	}
 
 	v := &CarWings{
-		embed:    &embed{cc.Title, cc.Capacity},
+		embed:    &embed{cc.Title, cc.Capacity, cc.Identifiers, cc.Defaults, cc.ChargeCurve},
 		user:     cc.User,
 		password: cc.Password,
 		region:   cc.Region,
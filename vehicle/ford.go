@@ -37,6 +37,9 @@ func NewFordFromConfig(other map[string]interface{}) (api.Vehicle, error) {
 	cc := struct {
 		Title               string
 		Capacity            int64
+		Identifiers         []string
+		Defaults            api.VehicleDefaults
+		ChargeCurve         api.ChargeCurve
 		User, Password, VIN string
 		Cache               time.Duration
 	}{
@@ -50,7 +53,7 @@ func NewFordFromConfig(other map[string]interface{}) (api.Vehicle, error) {
 	log := util.NewLogger("ford")
 
 	v := &Ford{
-		embed:    &embed{cc.Title, cc.Capacity},
+		embed:    &embed{cc.Title, cc.Capacity, cc.Identifiers, cc.Defaults, cc.ChargeCurve},
 		Helper:   request.NewHelper(log),
 		user:     cc.User,
 		password: cc.Password,
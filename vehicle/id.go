@@ -29,6 +29,9 @@ func NewIDFromConfig(other map[string]interface{}) (api.Vehicle, error) {
 	cc := struct {
 		Title               string
 		Capacity            int64
+		Identifiers         []string
+		Defaults            api.VehicleDefaults
+		ChargeCurve         api.ChargeCurve
 		User, Password, VIN string
 		Cache               time.Duration
 	}{
@@ -40,7 +43,7 @@ func NewIDFromConfig(other map[string]interface{}) (api.Vehicle, error) {
 	}
 
 	v := &ID{
-		embed: &embed{cc.Title, cc.Capacity},
+		embed: &embed{cc.Title, cc.Capacity, cc.Identifiers, cc.Defaults, cc.ChargeCurve},
 	}
 
 	log := util.NewLogger("id")
@@ -177,6 +177,36 @@ func (v *API) Action(vin, action, value string) error {
 	return err
 }
 
+// ChargingSettingsRequest is the /charging/settings request
+type ChargingSettingsRequest struct {
+	TargetSOCPercent int `json:"targetSOC_pct"`
+}
+
+// ClimatisationSettingsRequest is the /climatisation/settings request
+type ClimatisationSettingsRequest struct {
+	TargetTemperature                 float64 `json:"targetTemperature"`
+	TargetTemperatureUnit             string  `json:"targetTemperatureUnit"` // celsius
+	ClimatisationWithoutExternalPower bool    `json:"climatisationWithoutExternalPower"`
+}
+
+// Settings implements vehicle settings updates
+func (v *API) Settings(vin, action string, data interface{}) error {
+	uri := fmt.Sprintf("https://mobileapi.apps.emea.vwapps.io/vehicles/%s/%s/settings", vin, action)
+
+	req, err := request.New(http.MethodPut, uri, request.MarshalJSON(data), map[string]string{
+		"Accept":        "application/json",
+		"Content-Type":  "application/json",
+		"Authorization": "Bearer " + v.identity.Token(),
+	})
+
+	if err == nil {
+		var res interface{}
+		err = v.DoJSON(req, &res)
+	}
+
+	return err
+}
+
 // Any implements any api response
 func (v *API) Any(uri, vin string) (interface{}, error) {
 	if strings.Contains(uri, "%s") {
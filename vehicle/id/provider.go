@@ -10,8 +10,11 @@ import (
 
 // Provider is an api.Vehicle implementation for VW ID cars
 type Provider struct {
-	statusG           func() (interface{}, error)
-	startChargeAction func() error
+	statusG         func() (interface{}, error)
+	chargeAction    func(action string) error
+	chargeSettings  func(soc int) error
+	climateAction   func(action string) error
+	climateSettings func(targetTemp float64) error
 }
 
 // NewProvider creates a new vehicle
@@ -20,8 +23,23 @@ func NewProvider(api *API, vin string, cache time.Duration) *Provider {
 		statusG: provider.NewCached(func() (interface{}, error) {
 			return api.Status(vin)
 		}, cache).InterfaceGetter(),
-		startChargeAction: func() error {
-			return api.Action(vin, ActionCharge, ActionChargeStart)
+		chargeAction: func(action string) error {
+			return api.Action(vin, ActionCharge, action)
+		},
+		chargeSettings: func(soc int) error {
+			return api.Settings(vin, ActionCharge, ChargingSettingsRequest{
+				TargetSOCPercent: soc,
+			})
+		},
+		climateAction: func(action string) error {
+			return api.Action(vin, ActionClimatisation, action)
+		},
+		climateSettings: func(targetTemp float64) error {
+			return api.Settings(vin, ActionClimatisation, ClimatisationSettingsRequest{
+				TargetTemperature:                 targetTemp,
+				TargetTemperatureUnit:             "celsius",
+				ClimatisationWithoutExternalPower: true,
+			})
 		},
 	}
 	return impl
@@ -99,7 +117,33 @@ func (v *Provider) Climater() (active bool, outsideTemp float64, targetTemp floa
 	return active, outsideTemp, targetTemp, err
 }
 
-// StartCharge implements the VehicleStartCharge interface
+// StartCharge implements the api.VehicleStartCharge interface
 func (v *Provider) StartCharge() error {
-	return v.startChargeAction()
+	return v.chargeAction(ActionChargeStart)
+}
+
+// StopCharge implements the api.VehicleStopCharge interface
+func (v *Provider) StopCharge() error {
+	return v.chargeAction(ActionChargeStop)
+}
+
+// SetChargeLimit implements the api.VehicleChargeLimit interface
+func (v *Provider) SetChargeLimit(soc int) error {
+	return v.chargeSettings(soc)
+}
+
+// StartClimate implements the api.VehicleClimateController interface
+func (v *Provider) StartClimate(targetTemp float64) error {
+	if targetTemp != 0 {
+		if err := v.climateSettings(targetTemp); err != nil {
+			return err
+		}
+	}
+
+	return v.climateAction(ActionClimatisationStart)
+}
+
+// StopClimate implements the api.VehicleClimateController interface
+func (v *Provider) StopClimate() error {
+	return v.climateAction(ActionClimatisationStop)
 }
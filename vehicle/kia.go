@@ -23,6 +23,9 @@ func NewKiaFromConfig(other map[string]interface{}) (api.Vehicle, error) {
 	cc := struct {
 		Title          string
 		Capacity       int64
+		Identifiers    []string
+		Defaults       api.VehicleDefaults
+		ChargeCurve    api.ChargeCurve
 		User, Password string
 		Cache          time.Duration
 	}{
@@ -47,7 +50,7 @@ func NewKiaFromConfig(other map[string]interface{}) (api.Vehicle, error) {
 	}
 
 	v := &Kia{
-		embed: &embed{cc.Title, cc.Capacity},
+		embed: &embed{cc.Title, cc.Capacity, cc.Identifiers, cc.Defaults, cc.ChargeCurve},
 		API:   api,
 	}
 
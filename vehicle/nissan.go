@@ -60,6 +60,9 @@ func NewNissanFromConfig(other map[string]interface{}) (api.Vehicle, error) {
 	cc := struct {
 		Title                       string
 		Capacity                    int64
+		Identifiers                 []string
+		Defaults                    api.VehicleDefaults
+		ChargeCurve                 api.ChargeCurve
 		User, Password, Region, VIN string
 		Cache                       time.Duration
 	}{
@@ -74,7 +77,7 @@ func NewNissanFromConfig(other map[string]interface{}) (api.Vehicle, error) {
 	log := util.NewLogger("nissan")
 
 	v := &Nissan{
-		embed:    &embed{cc.Title, cc.Capacity},
+		embed:    &embed{cc.Title, cc.Capacity, cc.Identifiers, cc.Defaults, cc.ChargeCurve},
 		Helper:   request.NewHelper(log),
 		log:      log,
 		user:     cc.User,
@@ -74,6 +74,9 @@ func NewPorscheFromConfig(other map[string]interface{}) (api.Vehicle, error) {
 	cc := struct {
 		Title               string
 		Capacity            int64
+		Identifiers         []string
+		Defaults            api.VehicleDefaults
+		ChargeCurve         api.ChargeCurve
 		User, Password, VIN string
 		Cache               time.Duration
 	}{
@@ -89,7 +92,7 @@ func NewPorscheFromConfig(other map[string]interface{}) (api.Vehicle, error) {
 	}
 
 	v := &Porsche{
-		embed:    &embed{cc.Title, cc.Capacity},
+		embed:    &embed{cc.Title, cc.Capacity, cc.Identifiers, cc.Defaults, cc.ChargeCurve},
 		Helper:   request.NewHelper(util.NewLogger("porsche")),
 		user:     cc.User,
 		password: cc.Password,
@@ -108,6 +108,9 @@ func NewRenaultFromConfig(other map[string]interface{}) (api.Vehicle, error) {
 	cc := struct {
 		Title                       string
 		Capacity                    int64
+		Identifiers                 []string
+		Defaults                    api.VehicleDefaults
+		ChargeCurve                 api.ChargeCurve
 		User, Password, Region, VIN string
 		Cache                       time.Duration
 	}{
@@ -122,7 +125,7 @@ func NewRenaultFromConfig(other map[string]interface{}) (api.Vehicle, error) {
 	log := util.NewLogger("renault")
 
 	v := &Renault{
-		embed:    &embed{cc.Title, cc.Capacity},
+		embed:    &embed{cc.Title, cc.Capacity, cc.Identifiers, cc.Defaults, cc.ChargeCurve},
 		Helper:   request.NewHelper(log),
 		user:     cc.User,
 		password: cc.Password,
@@ -273,6 +276,37 @@ func (v *Renault) kamereonRequest(uri string) (kamereonResponse, error) {
 	return res, err
 }
 
+// kamereonAction sends an action request, repeating auth if required
+func (v *Renault) kamereonAction(action string, data interface{}) error {
+	uri := fmt.Sprintf("%s/commerce/v1/accounts/%s/kamereon/kca/car-adapter/v1/cars/%s/actions/%s", v.kamereon.Target, v.accountID, v.vin, action)
+
+	send := func() error {
+		req, err := request.New(http.MethodPost, uri, request.MarshalJSON(data), map[string]string{
+			"Content-Type":     "application/vnd.api+json",
+			"x-gigya-id_token": v.gigyaJwtToken,
+			"apikey":           v.kamereon.APIKey,
+		})
+
+		if err == nil {
+			req.URL.RawQuery = url.Values{"country": []string{"DE"}}.Encode()
+
+			var res interface{}
+			err = v.DoJSON(req, &res)
+		}
+
+		return err
+	}
+
+	err := send()
+	if err != nil {
+		if err = v.authFlow(); err == nil {
+			err = send()
+		}
+	}
+
+	return err
+}
+
 func (v *Renault) kamereonPerson(personID string) (string, error) {
 	uri := fmt.Sprintf("%s/commerce/v1/persons/%s", v.kamereon.Target, personID)
 	res, err := v.kamereonRequest(uri)
@@ -370,3 +404,27 @@ func (v *Renault) FinishTime() (time.Time, error) {
 
 	return time.Time{}, err
 }
+
+// chargingAction starts or stops charging
+func (v *Renault) chargingAction(action string) error {
+	data := map[string]interface{}{
+		"data": map[string]interface{}{
+			"type": "ChargingStart",
+			"attributes": map[string]interface{}{
+				"action": action,
+			},
+		},
+	}
+
+	return v.kamereonAction("charging-start", data)
+}
+
+// StartCharge implements the api.VehicleStartCharge interface
+func (v *Renault) StartCharge() error {
+	return v.chargingAction("start")
+}
+
+// StopCharge implements the api.VehicleStopCharge interface
+func (v *Renault) StopCharge() error {
+	return v.chargingAction("stop")
+}
@@ -3,6 +3,7 @@ package vehicle
 import (
 	"errors"
 	"fmt"
+	"net/http"
 	"strings"
 	"time"
 
@@ -18,9 +19,12 @@ import (
 // Tesla is an api.Vehicle implementation for Tesla cars
 type Tesla struct {
 	*embed
+	*request.Helper
+	ts             oauth2.TokenSource
 	vehicle        *tesla.Vehicle
 	chargeStateG   func() (float64, error)
 	chargedEnergyG func() (float64, error)
+	statusG        func() (interface{}, error)
 }
 
 // teslaTokens contains access and refresh tokens
@@ -37,6 +41,9 @@ func NewTeslaFromConfig(other map[string]interface{}) (api.Vehicle, error) {
 	cc := struct {
 		Title                  string
 		Capacity               int64
+		Identifiers            []string
+		Defaults               api.VehicleDefaults
+		ChargeCurve            api.ChargeCurve
 		ClientID, ClientSecret string
 		User, Password         string
 		Tokens                 teslaTokens
@@ -51,7 +58,7 @@ func NewTeslaFromConfig(other map[string]interface{}) (api.Vehicle, error) {
 	}
 
 	v := &Tesla{
-		embed: &embed{cc.Title, cc.Capacity},
+		embed: &embed{cc.Title, cc.Capacity, cc.Identifiers, cc.Defaults, cc.ChargeCurve},
 	}
 
 	log := util.NewLogger("tesla")
@@ -65,9 +72,12 @@ func NewTeslaFromConfig(other map[string]interface{}) (api.Vehicle, error) {
 		return nil, fmt.Errorf("login failed: %w", err)
 	}
 
+	v.Helper = request.NewHelper(log)
+	v.ts = ts
+
 	client, err := tesla.NewClient(&tesla.Auth{
 		TokenSource: ts,
-		HTTPClient:  request.NewHelper(log),
+		HTTPClient:  v.Helper,
 	})
 	if err != nil {
 		return nil, err
@@ -94,6 +104,7 @@ func NewTeslaFromConfig(other map[string]interface{}) (api.Vehicle, error) {
 
 	v.chargeStateG = provider.NewCached(v.chargeState, cc.Cache).FloatGetter()
 	v.chargedEnergyG = provider.NewCached(v.chargedEnergy, cc.Cache).FloatGetter()
+	v.statusG = provider.NewCached(v.status, cc.Cache).InterfaceGetter()
 
 	return v, nil
 }
@@ -152,3 +163,83 @@ func (v *Tesla) chargedEnergy() (float64, error) {
 func (v *Tesla) ChargedEnergy() (float64, error) {
 	return v.chargedEnergyG()
 }
+
+// StartClimate implements the api.VehicleClimateController interface
+func (v *Tesla) StartClimate(targetTemp float64) error {
+	if targetTemp != 0 {
+		if err := v.vehicle.SetTemprature(targetTemp, targetTemp); err != nil {
+			return err
+		}
+	}
+
+	return v.vehicle.StartAirConditioning()
+}
+
+// StopClimate implements the api.VehicleClimateController interface
+func (v *Tesla) StopClimate() error {
+	return v.vehicle.StopAirConditioning()
+}
+
+// status implements the api.VehicleStatus interface
+func (v *Tesla) status() (interface{}, error) {
+	return v.vehicle.ChargeState()
+}
+
+// Status implements the api.VehicleStatus interface
+func (v *Tesla) Status() (api.ChargeStatus, error) {
+	status := api.StatusA // disconnected
+
+	res, err := v.statusG()
+	if res, ok := res.(*tesla.ChargeState); err == nil && ok {
+		switch res.ChargingState {
+		case "Disconnected", "":
+		case "Charging":
+			status = api.StatusC
+		default: // Stopped, Complete, NoPower
+			status = api.StatusB
+		}
+	}
+
+	return status, err
+}
+
+// StartCharge implements the api.VehicleStartCharge interface
+func (v *Tesla) StartCharge() error {
+	return v.vehicle.StartCharging()
+}
+
+// StopCharge implements the api.VehicleStopCharge interface
+func (v *Tesla) StopCharge() error {
+	return v.vehicle.StopCharging()
+}
+
+// SetChargeLimit implements the api.VehicleChargeLimit interface
+func (v *Tesla) SetChargeLimit(soc int) error {
+	return v.vehicle.SetChargeLimit(soc)
+}
+
+// SetChargeCurrent implements the api.VehicleChargeCurrent interface
+func (v *Tesla) SetChargeCurrent(current int64) error {
+	token, err := v.ts.Token()
+	if err != nil {
+		return err
+	}
+
+	uri := fmt.Sprintf("%s/vehicles/%d/command/set_charging_amps", tesla.BaseURL, v.vehicle.ID)
+	data := map[string]int64{"charging_amps": current}
+
+	req, err := request.New(http.MethodPost, uri, request.MarshalJSON(data), request.JSONEncoding, map[string]string{
+		"Authorization": "Bearer " + token.AccessToken,
+	})
+
+	var res tesla.CommandResponse
+	if err == nil {
+		err = v.DoJSON(req, &res)
+	}
+
+	if err == nil && !res.Response.Result {
+		err = fmt.Errorf("set charging amps: %s", res.Response.Reason)
+	}
+
+	return err
+}
@@ -10,8 +10,11 @@ import (
 )
 
 type embed struct {
-	title    string
-	capacity int64
+	title       string
+	capacity    int64
+	identifiers []string
+	defaults    api.VehicleDefaults
+	chargeCurve api.ChargeCurve
 }
 
 // Title implements the Vehicle.Title interface
@@ -24,6 +27,21 @@ func (m *embed) Capacity() int64 {
 	return m.capacity
 }
 
+// Identifiers implements the Vehicle.Identifiers interface
+func (m *embed) Identifiers() []string {
+	return m.identifiers
+}
+
+// Defaults implements the Vehicle.Defaults interface
+func (m *embed) Defaults() api.VehicleDefaults {
+	return m.defaults
+}
+
+// ChargeCurve implements the api.VehicleChargeCurve interface
+func (m *embed) ChargeCurve() api.ChargeCurve {
+	return m.chargeCurve
+}
+
 //go:generate go run ../cmd/tools/decorate.go -p vehicle -f decorateVehicle -b api.Vehicle -o vehicle_decorators -t "api.VehicleStatus,Status,func() (api.ChargeStatus, error)" -t "api.VehicleRange,Range,func() (int64, error)"
 
 // Vehicle is an api.Vehicle implementation with configurable getters and setters.
@@ -41,12 +59,15 @@ func init() {
 // NewConfigurableFromConfig creates a new Vehicle
 func NewConfigurableFromConfig(other map[string]interface{}) (api.Vehicle, error) {
 	cc := struct {
-		Title    string
-		Capacity int64
-		Charge   provider.Config
-		Status   *provider.Config
-		Range    *provider.Config
-		Cache    time.Duration
+		Title       string
+		Capacity    int64
+		Identifiers []string
+		Defaults    api.VehicleDefaults
+		ChargeCurve api.ChargeCurve
+		Charge      provider.Config
+		Status      *provider.Config
+		Range       *provider.Config
+		Cache       time.Duration
 	}{
 		Cache: interval,
 	}
@@ -71,7 +92,7 @@ func NewConfigurableFromConfig(other map[string]interface{}) (api.Vehicle, error
 	}
 
 	v := &Vehicle{
-		embed:   &embed{cc.Title, cc.Capacity},
+		embed:   &embed{cc.Title, cc.Capacity, cc.Identifiers, cc.Defaults, cc.ChargeCurve},
 		chargeG: getter,
 	}
 
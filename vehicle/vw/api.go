@@ -2,6 +2,7 @@ package vw
 
 import (
 	"fmt"
+	"math"
 	"net/http"
 	"strings"
 
@@ -83,11 +84,57 @@ type ClimaterResponse struct {
 	}
 }
 
+// Charger action types
+const (
+	ChargerActionStart       = "start"
+	ChargerActionStop        = "stop"
+	ChargerActionSetSettings = "setSettings"
+)
+
+// ChargerActionSettings are the settings of the charger settings action
+type ChargerActionSettings struct {
+	MaxChargeCurrent int64 `json:"maxChargeCurrent"`
+}
+
+// ChargerActionRequest is the /bs/batterycharge/v1/%s/%s/vehicles/%s/charger/actions api
+type ChargerActionRequest struct {
+	Action struct {
+		Type     string                 `json:"type"`
+		Settings *ChargerActionSettings `json:"settings,omitempty"`
+	} `json:"action"`
+}
+
+// Climater action types
+const (
+	ClimaterActionStart = "startClimatisation"
+	ClimaterActionStop  = "stopClimatisation"
+)
+
+// ClimaterActionSettings are the settings of the climater start action
+type ClimaterActionSettings struct {
+	TargetTemperature           int    `json:"targetTemperature,omitempty"` // dK
+	ClimatisationWithoutHVpower bool   `json:"climatisationWithoutHVpower"`
+	HeaterSource                string `json:"heaterSource"` // electric
+}
+
+// ClimaterActionRequest is the /bs/climatisation/v1/%s/%s/vehicles/%s/climater/actions api
+type ClimaterActionRequest struct {
+	Action struct {
+		Type     string                  `json:"type"`
+		Settings *ClimaterActionSettings `json:"settings,omitempty"`
+	} `json:"action"`
+}
+
 // Temp2Float converts api temp to float value
 func Temp2Float(val int) float64 {
 	return float64(val)/10 - 273
 }
 
+// Float2Temp converts float value to api temp
+func Float2Temp(val float64) int {
+	return int(math.Round((val + 273) * 10))
+}
+
 // API is the VW api client
 type API struct {
 	*request.Helper
@@ -119,6 +166,21 @@ func (v *API) getJSON(uri string, res interface{}) error {
 	return err
 }
 
+func (v *API) postJSON(uri, contentType string, data interface{}) error {
+	req, err := request.New(http.MethodPost, uri, request.MarshalJSON(data), map[string]string{
+		"Accept":        "application/json",
+		"Content-Type":  contentType,
+		"Authorization": "Bearer " + v.identity.Token(),
+	})
+
+	if err == nil {
+		var res interface{}
+		err = v.DoJSON(req, &res)
+	}
+
+	return err
+}
+
 // Vehicles implements the /vehicles response
 func (v *API) Vehicles() ([]string, error) {
 	var res VehiclesResponse
@@ -143,6 +205,41 @@ func (v *API) Climater(vin string) (ClimaterResponse, error) {
 	return res, err
 }
 
+// ChargerAction implements the /charger/actions request. Current is only used for the settings action.
+func (v *API) ChargerAction(vin, action string, current int64) error {
+	var data ChargerActionRequest
+	data.Action.Type = action
+
+	if action == ChargerActionSetSettings {
+		data.Action.Settings = &ChargerActionSettings{
+			MaxChargeCurrent: current,
+		}
+	}
+
+	uri := fmt.Sprintf("%s/bs/batterycharge/v1/%s/%s/vehicles/%s/charger/actions", BaseURI, v.brand, v.country, vin)
+	return v.postJSON(uri, "application/vnd.vwg.mbb.ChargerAction_v1_0_0+json", data)
+}
+
+// ClimaterAction implements the /climater/actions request. Zero target temperature keeps the vehicle's setting.
+func (v *API) ClimaterAction(vin, action string, targetTemp float64) error {
+	var data ClimaterActionRequest
+	data.Action.Type = action
+
+	if action == ClimaterActionStart {
+		data.Action.Settings = &ClimaterActionSettings{
+			ClimatisationWithoutHVpower: true,
+			HeaterSource:                "electric",
+		}
+
+		if targetTemp != 0 {
+			data.Action.Settings.TargetTemperature = Float2Temp(targetTemp)
+		}
+	}
+
+	uri := fmt.Sprintf("%s/bs/climatisation/v1/%s/%s/vehicles/%s/climater/actions", BaseURI, v.brand, v.country, vin)
+	return v.postJSON(uri, "application/vnd.vwg.mbb.ClimaterAction_v1_0_0+json", data)
+}
+
 // Any implements any api response
 func (v *API) Any(base, vin string) (interface{}, error) {
 	var res interface{}
@@ -10,8 +10,10 @@ import (
 
 // Provider implements the evcc vehicle api
 type Provider struct {
-	chargerG func() (interface{}, error)
-	climateG func() (interface{}, error)
+	chargerG      func() (interface{}, error)
+	climateG      func() (interface{}, error)
+	climateAction func(action string, targetTemp float64) error
+	chargerAction func(action string, current int64) error
 }
 
 // NewProvider provides the evcc vehicle api provider
@@ -23,6 +25,12 @@ func NewProvider(api *API, vin string, cache time.Duration) *Provider {
 		climateG: provider.NewCached(func() (interface{}, error) {
 			return api.Climater(vin)
 		}, cache).InterfaceGetter(),
+		climateAction: func(action string, targetTemp float64) error {
+			return api.ClimaterAction(vin, action, targetTemp)
+		},
+		chargerAction: func(action string, current int64) error {
+			return api.ChargerAction(vin, action, current)
+		},
 	}
 	return impl
 }
@@ -102,3 +110,28 @@ func (v *Provider) Climater() (active bool, outsideTemp float64, targetTemp floa
 
 	return active, outsideTemp, targetTemp, err
 }
+
+// StartClimate implements the api.VehicleClimateController interface
+func (v *Provider) StartClimate(targetTemp float64) error {
+	return v.climateAction(ClimaterActionStart, targetTemp)
+}
+
+// StopClimate implements the api.VehicleClimateController interface
+func (v *Provider) StopClimate() error {
+	return v.climateAction(ClimaterActionStop, 0)
+}
+
+// StartCharge implements the api.VehicleStartCharge interface
+func (v *Provider) StartCharge() error {
+	return v.chargerAction(ChargerActionStart, 0)
+}
+
+// StopCharge implements the api.VehicleStopCharge interface
+func (v *Provider) StopCharge() error {
+	return v.chargerAction(ChargerActionStop, 0)
+}
+
+// SetChargeCurrent implements the api.VehicleChargeCurrent interface
+func (v *Provider) SetChargeCurrent(current int64) error {
+	return v.chargerAction(ChargerActionSetSettings, current)
+}